@@ -21,6 +21,7 @@ Examples:
 
   force active
   force active -a user@example.org
+  force active -a <alias>
 `,
 }
 var (
@@ -31,24 +32,24 @@ var (
 func init() {
 	cmdActive.Flag.BoolVar(&tojson, "j", false, "output to json")
 	cmdActive.Flag.BoolVar(&tojson, "json", false, "output to json")
-	cmdActive.Flag.StringVar(&account, "a", "", "output to json")
-	cmdActive.Flag.StringVar(&account, "account", "", "output to json")
+	cmdActive.Flag.StringVar(&account, "a", "", "username or alias to make active")
+	cmdActive.Flag.StringVar(&account, "account", "", "username or alias to make active")
 	cmdActive.Run = runActive
 }
 
 func runActive(cmd *Command, args []string) {
 	if account == "" {
-		account, _ := util.Config.Load("current", "account")
+		account, _ := ActiveLogin()
 		data, _ := util.Config.Load("accounts", account)
 		var creds salesforce.ForceCredentials
 		json.Unmarshal([]byte(data), &creds)
 		if tojson {
-			fmt.Printf(fmt.Sprintf("{ \"login\": \"%s\", \"instanceUrl\": \"%s\", \"namespace\":\"%s\" }", account, creds.InstanceUrl, creds.Namespace))
+			fmt.Printf("{ \"login\": \"%s\", \"instanceUrl\": \"%s\", \"namespace\":\"%s\" }", account, creds.InstanceUrl, creds.Namespace)
 		} else {
 			fmt.Println(fmt.Sprintf("%s - %s - ns:%s", account, creds.InstanceUrl, creds.Namespace))
 		}
 	} else {
-		//account := args[0]
+		account = ResolveAccount(account)
 		accounts, _ := util.Config.List("accounts")
 		i := sort.SearchStrings(accounts, account)
 		if i < len(accounts) && accounts[i] == account {
@@ -57,7 +58,7 @@ func runActive(cmd *Command, args []string) {
 				cmd.Run()
 			} else {
 				title := fmt.Sprintf("\033];%s\007", account)
-				fmt.Printf(title)
+				fmt.Print(title)
 			}
 			fmt.Printf("%s now active\n", account)
 			util.Config.Save("current", "account", account)
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joist-engineering/force/util"
+)
+
+var cmdAlias = &Command{
+	Run:   runAlias,
+	Usage: "alias <command> [<args>]",
+	Short: "Manage short names for logins",
+	Long: `
+Manage short names for logins.  An alias can be used anywhere a username
+is expected: force active -a, force logout -u and force -u.
+
+Usage:
+
+  force alias set <alias> <username>
+
+  force alias list
+
+  force alias rm <alias>
+
+Examples:
+
+  force alias set uat2 deploy.bot@example.com.uat2
+
+  force active -a uat2
+
+  force -u uat2 query "SELECT Id FROM Account"
+`,
+}
+
+func runAlias(cmd *Command, args []string) {
+	if len(args) == 0 {
+		cmd.printUsage()
+	} else {
+		switch args[0] {
+		case "set":
+			runAliasSet(args[1:])
+		case "list":
+			runAliasList()
+		case "rm", "remove":
+			runAliasRemove(args[1:])
+		default:
+			util.ErrorAndExit("no such command: %s", args[0])
+		}
+	}
+}
+
+func runAliasSet(args []string) {
+	if len(args) != 2 {
+		util.ErrorAndExit("must specify alias and username")
+	}
+	alias, username := args[0], ResolveAccount(args[1])
+	if alias == "" || strings.ContainsAny(alias, `/\`) || strings.HasPrefix(alias, ".") {
+		util.ErrorAndExit("invalid alias: %s", alias)
+	}
+	if !hasLogin(username) {
+		util.ErrorAndExit("no login for %s, please run `force login`", username)
+	}
+	if hasLogin(alias) {
+		util.ErrorAndExit("%s is already a username", alias)
+	}
+	if err := util.Config.Save("aliases", alias, username); err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("%s is now an alias for %s\n", alias, username)
+}
+
+func runAliasList() {
+	aliases := Aliases()
+	if len(aliases) == 0 {
+		fmt.Println("no aliases")
+		return
+	}
+	names := make([]string, 0, len(aliases))
+	for alias := range aliases {
+		names = append(names, alias)
+	}
+	sort.Strings(names)
+	w := tabwriter.NewWriter(os.Stdout, 1, 0, 2, ' ', 0)
+	for _, alias := range names {
+		fmt.Fprintf(w, "%s\t%s\n", alias, aliases[alias])
+	}
+	w.Flush()
+}
+
+func runAliasRemove(args []string) {
+	if len(args) != 1 {
+		util.ErrorAndExit("must specify alias")
+	}
+	if _, ok := Aliases()[args[0]]; !ok {
+		util.ErrorAndExit("no such alias %s", args[0])
+	}
+	util.Config.Delete("aliases", args[0])
+}
+
+// Aliases returns the username of every alias.
+func Aliases() (aliases map[string]string) {
+	aliases = make(map[string]string)
+	names, _ := util.Config.List("aliases")
+	for _, alias := range names {
+		if username, err := util.Config.Load("aliases", alias); err == nil {
+			aliases[alias] = strings.TrimSpace(username)
+		}
+	}
+	return
+}
+
+// AliasesFor returns the aliases of a login, in order.
+func AliasesFor(username string) (aliases []string) {
+	for alias, target := range Aliases() {
+		if target == username {
+			aliases = append(aliases, alias)
+		}
+	}
+	sort.Strings(aliases)
+	return
+}
+
+// ResolveAccount returns the username for an alias, or the name as it is if it
+// isn't an alias.
+func ResolveAccount(name string) string {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return name
+	}
+	if username, err := util.Config.Load("aliases", name); err == nil {
+		return strings.TrimSpace(username)
+	}
+	return name
+}
+
+// removeAliasesFor removes the aliases of a login, such as when logging out.
+func removeAliasesFor(username string) {
+	for _, alias := range AliasesFor(username) {
+		util.Config.Delete("aliases", alias)
+	}
+}
+
+func hasLogin(username string) bool {
+	accounts, _ := util.Config.List("accounts")
+	for _, account := range accounts {
+		if account == username {
+			return true
+		}
+	}
+	return false
+}
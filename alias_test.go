@@ -0,0 +1,91 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/joist-engineering/force/salesforce"
+	"github.com/joist-engineering/force/util"
+)
+
+func withTempHome(t *testing.T) func() {
+	home := os.Getenv("HOME")
+	dir, err := ioutil.TempDir("", "force-alias-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("HOME", dir)
+	return func() {
+		os.Setenv("HOME", home)
+		os.RemoveAll(dir)
+		selectedAccount = ""
+		accountSelected = false
+	}
+}
+
+// serveOrg serves handler as an org, returning a Force logged in to it that
+// doesn't retry failed requests and a func to stop the server.
+func serveOrg(handler http.Handler) (*salesforce.Force, func()) {
+	server := httptest.NewServer(handler)
+	force := salesforce.NewForce(salesforce.ForceCredentials{AccessToken: "token", InstanceUrl: server.URL, ApiVersion: "v45.0"})
+	force.Retry = salesforce.RetryPolicy{}
+	return force, server.Close
+}
+
+func TestResolveAccount(t *testing.T) {
+	defer withTempHome(t)()
+	util.Config.Save("accounts", "deploy.bot@example.com.uat2", "{}")
+	util.Config.Save("aliases", "uat2", "deploy.bot@example.com.uat2")
+	util.Config.Save("aliases", "uat", "deploy.bot@example.com.uat2")
+
+	assert.Equal(t, "deploy.bot@example.com.uat2", ResolveAccount("uat2"))
+	assert.Equal(t, "other@example.com", ResolveAccount("other@example.com"))
+	assert.Equal(t, []string{"uat", "uat2"}, AliasesFor("deploy.bot@example.com.uat2"))
+}
+
+func TestSelectAccountByAlias(t *testing.T) {
+	defer withTempHome(t)()
+	util.Config.Save("accounts", "deploy.bot@example.com.uat2", "{}")
+	util.Config.Save("aliases", "uat2", "deploy.bot@example.com.uat2")
+	util.Config.Save("current", "account", "someone@example.com")
+
+	assert.Equal(t, nil, SelectAccount("uat2"))
+	account, _ := ActiveLogin()
+	assert.Equal(t, "deploy.bot@example.com.uat2", account)
+	saved, _ := util.Config.Load("current", "account")
+	assert.Equal(t, "someone@example.com", saved)
+
+	assert.NotEqual(t, nil, SelectAccount("nobody"))
+}
+
+func TestSelectAccountForLoginCommands(t *testing.T) {
+	defer withTempHome(t)()
+
+	assert.NotEqual(t, nil, selectAccount("query", "ci@example.com"))
+	for _, command := range []string{"login", "logout", "logins", "alias", "help"} {
+		assert.Equal(t, nil, selectAccount(command, "ci@example.com"))
+	}
+	assert.Equal(t, "", selectedAccount)
+
+	util.Config.Save("accounts", "ci@example.com", "{}")
+	assert.Equal(t, nil, selectAccount("login", "ci@example.com"))
+	assert.Equal(t, "ci@example.com", selectedAccount)
+	assert.Equal(t, nil, selectAccount("query", ""))
+}
+
+func TestSaveLoginKeepsTheActiveLoginForASelectedAccount(t *testing.T) {
+	defer withTempHome(t)()
+	assert.Equal(t, nil, saveLogin("dev@example.com", salesforce.ForceCredentials{}))
+	current, _ := util.Config.Load("current", "account")
+	assert.Equal(t, "dev@example.com", current)
+
+	assert.Equal(t, nil, selectAccount("login", "ci@example.com"))
+	assert.Equal(t, nil, saveLogin("ci@example.com", salesforce.ForceCredentials{}))
+	current, _ = util.Config.Load("current", "account")
+	assert.Equal(t, "dev@example.com", current)
+	assert.T(t, hasLogin("ci@example.com"))
+}
@@ -4,8 +4,6 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-
-	"github.com/joist-engineering/force/util"
 )
 
 var cmdApex = &Command{
@@ -41,13 +39,13 @@ func runApex(cmd *Command, args []string) {
 		fmt.Println("\n\n>> Executing code...")
 	}
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	force, _ := ActiveForce()
 	if len(args) <= 1 {
 		output, err := force.Partner.ExecuteAnonymous(string(code))
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		fmt.Println(output)
 	} else {
@@ -55,7 +53,7 @@ func runApex(cmd *Command, args []string) {
 		fmt.Println(apexclass)
 		err := force.GetCodeCoverage("", apexclass)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	}
 }
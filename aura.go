@@ -82,12 +82,12 @@ func runAura(cmd *Command, args []string) {
 	if strings.HasPrefix(subcommand, "delete ") || strings.HasPrefix(subcommand, "push ") {
 		what := strings.Split(subcommand, " ")
 		if err := cmd.Flag.Parse(what[1:]); err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		subcommand = what[0]
 	} else {
 		if err := cmd.Flag.Parse(args[1:]); err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	}
 
@@ -104,7 +104,7 @@ func runAura(cmd *Command, args []string) {
 	case "list":
 		bundles, err := force.GetAuraBundlesList()
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		for _, bundle := range bundles.Records {
 			fmt.Println(bundle["DeveloperName"])
@@ -122,7 +122,7 @@ func runDeleteAura() {
 	if InAuraBundlesFolder(absPath) {
 		info, err := os.Stat(absPath)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		manifest, err := GetManifest(absPath)
 		isBundle := false
@@ -134,7 +134,7 @@ func runDeleteAura() {
 				// Try to look up the bundle by name
 				b, err := force.GetAuraBundleByName(filepath.Base(absPath))
 				if err != nil {
-					util.ErrorAndExit(err.Error())
+					exitWithError(err)
 				} else {
 					if len(b.Records) == 0 {
 						util.ErrorAndExit(fmt.Sprintf("No bundle definition named %q", filepath.Base(absPath)))
@@ -148,7 +148,7 @@ func runDeleteAura() {
 
 			err = force.DeleteToolingRecord("AuraDefinitionBundle", bid)
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			// Now walk the bundle and remove all the atrifacts
 			filepath.Walk(absPath, func(path string, inf os.FileInfo, err error) error {
@@ -190,7 +190,7 @@ func deleteAuraDefinitionBundle(manifest salesforce.BundleManifest) {
 	force, err := ActiveForce()
 	err = force.DeleteToolingRecord("AuraDefinitionBundle", manifest.Id)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	os.Remove(filepath.Join(resourcepath[0], ".manifest"))
 	os.Remove(resourcepath[0])
@@ -200,7 +200,7 @@ func deleteAuraDefinition(manifest salesforce.BundleManifest, key int) {
 	force, err := ActiveForce()
 	err = force.DeleteToolingRecord("AuraDefinition", manifest.Files[key].ComponentId)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fname := manifest.Files[key].FileName
 	os.Remove(fname)
@@ -112,7 +112,7 @@ func parseField(fielddata string) (result salesforce.BigObjectField) {
 			var lval int64
 			lval, err := strconv.ParseInt(pair[1], 10, 0)
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			result.Length = int(lval)
 		}
@@ -187,7 +187,7 @@ func runBigObjectCreate(args []string) {
 	}
 	force, _ := ActiveForce()
 	if err := force.Metadata.CreateBigObject(object); err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Println("Big object created")
 
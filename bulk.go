@@ -74,6 +74,8 @@ Examples:
 
   force bulk update Account [csv file]
 
+  force bulk upsert Account [external id field] [csv file]
+
   force bulk job [job id]
 
   force bulk batches [job id]
@@ -91,6 +93,14 @@ Examples:
 func runBulk(cmd *Command, args []string) {
 	if len(args) == 0 {
 		cmd.printUsage()
+	} else if args[0] == "upsert" {
+		if len(args) == 4 {
+			createBulkUpsertJob(args[3], args[1], args[2], "CSV")
+		} else if len(args) == 5 {
+			createBulkUpsertJob(args[3], args[1], args[2], args[4])
+		} else {
+			util.ErrorAndExit("Upsert requires an object, an external id field and a file")
+		}
 	} else if len(args) == 1 {
 		util.ErrorAndExit("Invalid command")
 	} else if len(args) == 2 {
@@ -139,13 +149,13 @@ func runBulk(cmd *Command, args []string) {
 }
 
 func doBulkQuery(objectType string, soql string, contenttype string) {
-	jobInfo, err := createBulkJob(objectType, "query", contenttype)
+	jobInfo, err := createBulkJob(objectType, "query", contenttype, "")
 	force, _ := ActiveForce()
 
 	result, err := force.BulkQuery(soql, jobInfo.Id, contenttype)
 	if err != nil {
 		closeBulkJob(jobInfo.Id)
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Println("Query Submitted")
 	fmt.Printf("To retrieve query status use\nforce bulk query status %s %s\n\n", jobInfo.Id, result.Id)
@@ -177,7 +187,7 @@ func retrieveBulkQuery(jobId string, batchId string) (resultIds []string) {
 
 	jobInfo, err := force.RetrieveBulkQuery(jobId, batchId)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	var resultList struct {
@@ -194,7 +204,7 @@ func retrieveBulkQueryResults(jobId string, batchId string, resultId string) (da
 
 	data, err := force.RetrieveBulkQueryResults(jobId, batchId, resultId)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	return
 }
@@ -218,10 +228,10 @@ func getBatchResults(jobId string, batchId string) {
 	force, _ := ActiveForce()
 
 	data, err := force.RetrieveBulkBatchResults(jobId, batchId)
-	fmt.Println(data)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
+	DisplayBatchResults(data)
 	return
 }
 
@@ -231,7 +241,7 @@ func getJobDetails(jobId string) (jobInfo salesforce.JobInfo) {
 	jobInfo, err := force.GetJobInfo(jobId)
 
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	return
 }
@@ -242,7 +252,7 @@ func getBatches(jobId string) (batchInfos []salesforce.BatchInfo) {
 	batchInfos, err := force.GetBatches(jobId)
 
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	return
 }
@@ -253,39 +263,38 @@ func getBatchDetails(jobId string, batchId string) (batchInfo salesforce.BatchIn
 	batchInfo, err := force.GetBatchInfo(jobId, batchId)
 
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	return
 }
 
 func createBulkInsertJob(csvFilePath string, objectType string, format string) {
-	jobInfo, err := createBulkJob(objectType, "insert", format)
-	if err != nil {
-		util.ErrorAndExit(err.Error())
-	} else {
-		batchInfo, err := addBatchToJob(csvFilePath, jobInfo.Id)
-		if err != nil {
-			closeBulkJob(jobInfo.Id)
-			util.ErrorAndExit(err.Error())
-		} else {
-			closeBulkJob(jobInfo.Id)
-			fmt.Printf("Job created ( %s ) - for job status use\n force bulk batch %s %s\n", jobInfo.Id, jobInfo.Id, batchInfo.Id)
-		}
-	}
+	createBulkLoadJob(csvFilePath, objectType, "insert", format, "")
 }
 
 func createBulkUpdateJob(csvFilePath string, objectType string, format string) {
-	jobInfo, err := createBulkJob(objectType, "update", format)
+	createBulkLoadJob(csvFilePath, objectType, "update", format, "")
+}
+
+// createBulkUpsertJob loads rows that are matched to records by an external
+// id field, updating those that exist and creating the rest.
+func createBulkUpsertJob(csvFilePath string, objectType string, externalIdField string, format string) {
+	createBulkLoadJob(csvFilePath, objectType, "upsert", format, externalIdField)
+}
+
+func createBulkLoadJob(csvFilePath string, objectType string, operation string, format string, externalIdField string) {
+	jobInfo, err := createBulkJob(objectType, operation, format, externalIdField)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	} else {
 		batchInfo, err := addBatchToJob(csvFilePath, jobInfo.Id)
 		if err != nil {
 			closeBulkJob(jobInfo.Id)
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		} else {
 			closeBulkJob(jobInfo.Id)
 			fmt.Printf("Job created ( %s ) - for job status use\n force bulk batch %s %s\n", jobInfo.Id, jobInfo.Id, batchInfo.Id)
+			fmt.Printf("and once it's done, for what happened to each row use\n force bulk batch retrieve %s %s\n", jobInfo.Id, batchInfo.Id)
 		}
 	}
 }
@@ -329,17 +338,21 @@ func getBatchInfo(jobId string, batchId string) (batchInfo salesforce.BatchInfo,
 	return
 }
 
-func createBulkJob(objectType string, operation string, fileFormat string) (jobInfo salesforce.JobInfo, err error) {
+func createBulkJob(objectType string, operation string, fileFormat string, externalIdField string) (jobInfo salesforce.JobInfo, err error) {
 	force, _ := ActiveForce()
 
 	xml := `
 	<jobInfo xmlns="http://www.force.com/2009/06/asyncapi/dataload">
  		<operation>%s</operation>
- 		<object>%s</object>
+ 		<object>%s</object>%s
  		<contentType>%s</contentType>
 	</jobInfo>
 	`
-	data := fmt.Sprintf(xml, operation, objectType, fileFormat)
+	var externalId string
+	if externalIdField != "" {
+		externalId = fmt.Sprintf("\n \t\t<externalIdFieldName>%s</externalIdFieldName>", externalIdField)
+	}
+	data := fmt.Sprintf(xml, operation, objectType, externalId, fileFormat)
 	jobInfo, err = force.CreateBulkJob(data)
 	return
 }
@@ -354,7 +367,7 @@ func closeBulkJob(jobId string) (jobInfo salesforce.JobInfo, err error) {
 	`
 	jobInfo, err = force.CloseBulkJob(jobId, xml)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	return
 }
@@ -129,7 +129,7 @@ func runDataPipelineJob() {
 	id := GetDataPipelineId(dpname)
 	_, err, _ := force.CreateDataPipelineJob(id)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Printf("Successfully created DataPipeline job for %s\n", dpname)
 }
@@ -140,7 +140,7 @@ func runDataPipelineListJobs() {
 	force, _ := ActiveForce()
 	result, err := force.QueryDataPipelineJob(query)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	DisplayForceRecordsf(result.Records, "csv")
@@ -152,7 +152,7 @@ func runDataPipelineQueryJob() {
 	force, _ := ActiveForce()
 	result, err := force.QueryDataPipelineJob(query)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	DisplayForceRecordsf(result.Records, "csv")
@@ -165,7 +165,7 @@ func runDataPipelineQuery() {
 	force, _ := ActiveForce()
 	result, err := force.QueryDataPipeline(query)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	fmt.Println("Result: \n", result)
@@ -182,7 +182,7 @@ func runDataPipelineCreate() {
 	force, _ := ActiveForce()
 	_, err, _ := force.CreateDataPipeline(dpname, masterlabel, apiversion, scriptcontent, scripttype)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Printf("DataPipeline %s successfully created.\n", dpname)
 }
@@ -199,7 +199,7 @@ func runDataPipelineUpdate() {
 
 	result, err := force.GetDataPipeline(dpname)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	if len(result.Records) == 0 {
@@ -218,12 +218,12 @@ func runDataPipelineUpdate() {
 			fmt.Printf("file exists; processing...")
 			scriptcontent, err = readScriptFile(scriptcontent)
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 		}
 		err = force.UpdateDataPipeline(id, masterlabel, scriptcontent)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		fmt.Printf("%s successfully updated.\n", dpname)
 	}
@@ -240,7 +240,7 @@ func GetDataPipelineId(name string) (id string) {
 
 	result, err := force.GetDataPipeline(dpname)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	if len(result.Records) == 0 {
@@ -258,7 +258,7 @@ func runDataPipelineDelete() {
 	id := GetDataPipelineId(dpname)
 	err := force.DeleteDataPipeline(id)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Printf("%s successfully deleted.\n", dpname)
 }
@@ -268,7 +268,7 @@ func runDataPipelineList() {
 	query = "SELECT Id, MasterLabel, DeveloperName, ScriptType FROM DataPipeline"
 	result, err := force.QueryDataPipeline(query)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	DisplayForceRecordsf(result.Records, format)
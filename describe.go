@@ -54,7 +54,7 @@ func runDescribe(cmd *Command, args []string) {
 			// List all metadata
 			describe, err := force.Metadata.DescribeMetadata()
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			if jsonout {
 				DisplayMetadataListJson(describe.MetadataObjects)
@@ -65,13 +65,13 @@ func runDescribe(cmd *Command, args []string) {
 			// List all metdata object of metaItem type
 			body, err := force.Metadata.ListMetadata(metaItem)
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			var res struct {
 				Response salesforce.ListMetadataResponse `xml:"Body>listMetadataResponse"`
 			}
 			if err = xml.Unmarshal(body, &res); err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			if jsonout {
 				DisplayListMetadataResponseJson(res.Response)
@@ -92,7 +92,7 @@ func runDescribe(cmd *Command, args []string) {
 			// describe sobject
 			desc, err := force.DescribeSObject(metaItem)
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			DisplayForceSobjectDescribe(desc)
 		}
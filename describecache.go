@@ -0,0 +1,182 @@
+package main
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/joist-engineering/force/salesforce"
+	"github.com/joist-engineering/force/util"
+)
+
+// describer is the part of a Force that describes sObjects.
+type describer interface {
+	ListSobjects() ([]salesforce.ForceSobject, error)
+	GetSobject(name string) (salesforce.ForceSobject, error)
+}
+
+// sobjectDescribe is the part of an sObject's describe that is cached.
+type sobjectDescribe struct {
+	Name               string
+	Fields             []describeField
+	ChildRelationships []childRelationship
+}
+
+type describeField struct {
+	Name             string
+	Type             string
+	RelationshipName string
+	ReferenceTo      []string
+	PicklistValues   []struct {
+		Value string
+	}
+}
+
+type childRelationship struct {
+	ChildSObject     string
+	RelationshipName string
+	// Field is the child's lookup to the parent.
+	Field string
+}
+
+// Field returns the field with a name, whichever way it is spelt.
+func (d sobjectDescribe) Field(name string) (field describeField, ok bool) {
+	for _, field = range d.Fields {
+		if strings.EqualFold(field.Name, name) {
+			return field, true
+		}
+	}
+	return
+}
+
+// Relationship returns the reference field with a relationship name, such
+// as Account for AccountId.
+func (d sobjectDescribe) Relationship(name string) (field describeField, ok bool) {
+	for _, field = range d.Fields {
+		if field.RelationshipName != "" && strings.EqualFold(field.RelationshipName, name) {
+			return field, true
+		}
+	}
+	return
+}
+
+// ChildRelationship returns the child relationship with a name, such as
+// Contacts, whose child records are Contacts with an AccountId.
+func (d sobjectDescribe) ChildRelationship(name string) (child childRelationship, ok bool) {
+	for _, child = range d.ChildRelationships {
+		if strings.EqualFold(child.RelationshipName, name) {
+			return child, true
+		}
+	}
+	return
+}
+
+// describeCache keeps the describes of an org's sObjects in the config dir,
+// so that each is only fetched from Salesforce once.  Refresh drops them,
+// for after the org's schema has changed.
+type describeCache struct {
+	force     describer
+	name      string
+	sobjects  []string
+	describes map[string]sobjectDescribe
+}
+
+func newDescribeCache(force *salesforce.Force) *describeCache {
+	return &describeCache{
+		force:     force,
+		name:      "describe/" + orgKey(force.Credentials),
+		describes: make(map[string]sobjectDescribe),
+	}
+}
+
+// orgKey names an org: by its id, from the end of the identity URL
+// (.../id/<org id>/<user id>), or failing that, by its instance's host.
+func orgKey(creds salesforce.ForceCredentials) string {
+	parts := strings.Split(strings.TrimSuffix(creds.Id, "/"), "/")
+	if n := len(parts); n >= 3 && parts[n-3] == "id" {
+		return parts[n-2]
+	}
+	if u, err := url.Parse(creds.InstanceUrl); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "default"
+}
+
+// SobjectNames returns the names of the org's sObjects.
+func (c *describeCache) SobjectNames() (names []string, err error) {
+	if c.sobjects != nil {
+		return c.sobjects, nil
+	}
+	if c.load("sobjects", &names) {
+		c.sobjects = names
+		return
+	}
+	sobjects, err := c.force.ListSobjects()
+	if err != nil {
+		return
+	}
+	for _, sobject := range sobjects {
+		if name, ok := sobject["name"].(string); ok {
+			names = append(names, name)
+		}
+	}
+	c.sobjects = names
+	c.save("sobjects", names)
+	return
+}
+
+// Describe returns the describe of an sObject.
+func (c *describeCache) Describe(sobject string) (describe sobjectDescribe, err error) {
+	key := strings.ToLower(sobject)
+	if describe, ok := c.describes[key]; ok {
+		return describe, nil
+	}
+	if c.load(key, &describe) {
+		c.describes[key] = describe
+		return
+	}
+	result, err := c.force.GetSobject(sobject)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err = json.Unmarshal(data, &describe); err != nil {
+		return
+	}
+	c.describes[key] = describe
+	c.save(key, describe)
+	return
+}
+
+// Refresh forgets every describe, so that they are fetched again.
+func (c *describeCache) Refresh() {
+	c.sobjects = nil
+	c.describes = make(map[string]sobjectDescribe)
+	keys, _ := util.Config.List(c.name)
+	for _, key := range keys {
+		util.Config.Delete(c.name, key)
+	}
+}
+
+// Forget drops the describe of one sObject, so that it is fetched again.
+func (c *describeCache) Forget(sobject string) {
+	key := strings.ToLower(sobject)
+	delete(c.describes, key)
+	util.Config.Delete(c.name, key)
+}
+
+func (c *describeCache) load(key string, v interface{}) bool {
+	data, err := util.Config.Load(c.name, key)
+	return err == nil && json.Unmarshal([]byte(data), v) == nil
+}
+
+// save is best effort: a describe that can't be cached is fetched again
+// next time.
+func (c *describeCache) save(key string, v interface{}) {
+	if data, err := json.Marshal(v); err == nil {
+		util.Config.Save(c.name, key, string(data))
+	}
+}
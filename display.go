@@ -4,11 +4,13 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"sort"
 	"strings"
+	"text/tabwriter"
 
 	"github.com/joist-engineering/force/salesforce"
-	"github.com/joist-engineering/force/util"
 )
 
 var BatchInfoTemplate = `
@@ -43,11 +45,29 @@ func DisplayListMetadataResponseJson(resp salesforce.ListMetadataResponse) {
 	sort.Sort(ByFullName(resp.Result))
 	b, err := json.MarshalIndent(resp.Result, "", "   ")
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Printf("%s\n", string(b))
 }
 
+type ByLastModifiedDate []salesforce.MDFileProperties
+
+func (a ByLastModifiedDate) Len() int      { return len(a) }
+func (a ByLastModifiedDate) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByLastModifiedDate) Less(i, j int) bool {
+	return a[i].LastModifiedDate.Before(a[j].LastModifiedDate)
+}
+
+func DisplayChangedMetadata(properties []salesforce.MDFileProperties) {
+	sort.Stable(ByLastModifiedDate(properties))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "TYPE\tNAME\tLAST MODIFIED BY\tLAST MODIFIED")
+	for _, property := range properties {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", property.Type, property.FullName, property.LastModifiedByName, property.LastModifiedDate.Local().Format("2006-01-02 15:04:05"))
+	}
+	w.Flush()
+}
+
 func DisplayMetadataList(metadataObjects []salesforce.DescribeMetadataObject) {
 
 	sort.Sort(ByXmlName(metadataObjects))
@@ -75,7 +95,7 @@ func DisplayMetadataListJson(metadataObjects []salesforce.DescribeMetadataObject
 
 	b, err := json.MarshalIndent(metadataObjects, "", "   ")
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Printf("%s\n", string(b))
 }
@@ -89,6 +109,31 @@ func DisplayBatchList(batchInfos []salesforce.BatchInfo) {
 	}
 }
 
+func DisplayBatchResults(result salesforce.BatchResult) {
+	writeBatchResults(os.Stdout, result)
+}
+
+// writeBatchResults writes whether each row of a batch created or updated a
+// record, or why it failed.
+func writeBatchResults(w io.Writer, result salesforce.BatchResult) {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "Row\tId\tResult\tError")
+	for i, row := range result.Results {
+		outcome := "updated"
+		if !row.Success {
+			outcome = "failed"
+		} else if row.Created {
+			outcome = "created"
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s", i+1, row.Id, outcome)
+		if row.Message != "" {
+			fmt.Fprintf(table, "\t%s", row.Message)
+		}
+		fmt.Fprintln(table)
+	}
+	table.Flush()
+}
+
 func DisplayBatchInfo(batchInfo salesforce.BatchInfo) {
 
 	fmt.Printf(BatchInfoTemplate, batchInfo.Id, batchInfo.JobId, batchInfo.State,
@@ -139,7 +184,7 @@ func DisplayForceSobjectDescribe(sobject string) {
 	b := []byte(sobject)
 	err := json.Unmarshal(b, &d)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	out, err := json.MarshalIndent(d, "", "    ")
 	fmt.Println(string(out))
@@ -163,24 +208,19 @@ func DisplayForceSobjectsJson(sobjects []salesforce.ForceSobject) {
 	}
 	b, err := json.MarshalIndent(names, "", "   ")
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Printf("%s\n", string(b))
 }
 
 func DisplayForceRecordsf(records []salesforce.ForceRecord, format string) {
-	switch format {
-	case "csv":
-		fmt.Println(RenderForceRecordsCSV(records, format))
-	case "json":
-		recs, _ := json.Marshal(records)
-		fmt.Println(string(recs))
-	case "json-pretty":
-		recs, _ := json.MarshalIndent(records, "", "  ")
-		fmt.Println(string(recs))
-	default:
-		fmt.Printf("Format %s not supported\n\n", format)
+	out, err := newRecordWriter(os.Stdout, format, nil, false)
+	if err != nil {
+		fmt.Printf("%s\n\n", err.Error())
+		return
 	}
+	out.WritePage(records)
+	out.Close()
 }
 
 func DisplayForceRecords(result salesforce.ForceQueryResult) {
@@ -335,33 +375,9 @@ func StringSliceContains(slice []string, value string) bool {
 
 func RenderForceRecordsCSV(records []salesforce.ForceRecord, format string) string {
 	var out bytes.Buffer
-
-	var keys []string
-	var flattenedRecords []map[string]interface{}
-	for _, record := range records {
-		flattenedRecord := flattenForceRecord(record)
-		flattenedRecords = append(flattenedRecords, flattenedRecord)
-		for key, _ := range flattenedRecord {
-			if !StringSliceContains(keys, key) {
-				keys = append(keys, key)
-			}
-		}
-	}
-	sort.Strings(keys)
-
-	if len(records) > 0 {
-		// Write out header
-		out.WriteString(fmt.Sprintf(`"%s"%s`, strings.Join(keys, `","`), "\n"))
-
-		// Write out each record
-		for _, record := range flattenedRecords {
-			myvalues := make([]string, len(keys))
-			for i, key := range keys {
-				myvalues[i] = strings.Replace(fmt.Sprintf(`%v`, record[key]), "<nil>", "", -1)
-			}
-			out.WriteString(fmt.Sprintf(`"%s"%s`, strings.Join(myvalues, `","`), "\n"))
-		}
-	}
+	csv, _ := newRecordWriter(&out, "csv", nil, false)
+	csv.WritePage(records)
+	csv.Close()
 	return out.String()
 }
 
@@ -568,7 +584,7 @@ func DisplayFieldDetails(fieldType string) {
   Sorry, that is not a valid field type.
 `
 	}
-	fmt.Printf(msg + "\n")
+	fmt.Println(msg)
 }
 
 func DisplayTextFieldDetails() (message string) {
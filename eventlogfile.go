@@ -2,8 +2,6 @@ package main
 
 import (
 	"fmt"
-
-	"github.com/joist-engineering/force/util"
 )
 
 var cmdEventLogFile = &Command{
@@ -24,14 +22,14 @@ func getEventLogFile(cmd *Command, args []string) {
 	if len(args) == 0 {
 		records, err := force.QueryEventLogFiles()
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		DisplayForceRecords(records)
 	} else {
 		logId := args[0]
 		log, err := force.RetrieveEventLogFile(logId)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		fmt.Println(log)
 	}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/joist-engineering/force/salesforce"
+)
+
+// Exit statuses for failures that scripts may want to tell apart.  Anything
+// else exits with ExitError.
+const (
+	ExitError         = 1
+	ExitAuthorization = 3
+	ExitPermission    = 4
+	ExitNotFound      = 5
+	ExitDuplicate     = 6
+	ExitInvalid       = 7
+	ExitLimit         = 8
+)
+
+type errorCodeExit struct {
+	status int
+	hint   string
+}
+
+var (
+	loginHint      = "Your session has expired; please run `force login`."
+	permissionHint = "Your user doesn't have access to this; check its profile and permission sets."
+	fieldHint      = "Check the field names with `force field list <object>`."
+)
+
+// errorCodeExits are the exit statuses and hints for Salesforce error codes.
+var errorCodeExits = map[string]errorCodeExit{
+	"INVALID_SESSION_ID":  {ExitAuthorization, loginHint},
+	"InvalidSessionId":    {ExitAuthorization, loginHint},
+	"INVALID_AUTH_HEADER": {ExitAuthorization, loginHint},
+	"invalid_grant":       {ExitAuthorization, "The login was refused; please run `force login`."},
+
+	"INSUFFICIENT_ACCESS":                           {ExitPermission, permissionHint},
+	"INSUFFICIENT_ACCESS_OR_READONLY":               {ExitPermission, permissionHint},
+	"INSUFFICIENT_ACCESS_ON_CROSS_REFERENCE_ENTITY": {ExitPermission, permissionHint},
+	"API_DISABLED_FOR_ORG":                          {ExitPermission, "The API isn't enabled for this org, or for your user's profile."},
+
+	"NOT_FOUND":         {ExitNotFound, ""},
+	"ENTITY_IS_DELETED": {ExitNotFound, ""},
+	"INVALID_TYPE":      {ExitNotFound, "Check the object name with `force sobject list`."},
+	"INVALID_FIELD":     {ExitNotFound, fieldHint},
+
+	"DUPLICATE_VALUE": {ExitDuplicate, "A record with this value already exists; use `force record update` to change it."},
+
+	"REQUIRED_FIELD_MISSING":                  {ExitInvalid, fieldHint},
+	"INVALID_FIELD_FOR_INSERT_UPDATE":         {ExitInvalid, fieldHint},
+	"FIELD_CUSTOM_VALIDATION_EXCEPTION":       {ExitInvalid, ""},
+	"FIELD_INTEGRITY_EXCEPTION":               {ExitInvalid, ""},
+	"INVALID_OR_NULL_FOR_RESTRICTED_PICKLIST": {ExitInvalid, ""},
+	"STRING_TOO_LONG":                         {ExitInvalid, ""},
+	"MALFORMED_ID":                            {ExitInvalid, ""},
+	"MALFORMED_QUERY":                         {ExitInvalid, ""},
+	"INVALID_QUERY_LOCATOR":                   {ExitInvalid, "The query's cursor has expired; run it again without -resume."},
+	"JSON_PARSER_ERROR":                       {ExitInvalid, ""},
+
+	"REQUEST_LIMIT_EXCEEDED": {ExitLimit, "The org is over its API request limits; see `force limits`."},
+}
+
+// errorExit returns the exit status for an error, and a hint about what to do
+// about it if there is one.
+func errorExit(err error) (status int, hint string) {
+	var apiError *salesforce.APIError
+	var refreshError *salesforce.RefreshError
+	switch {
+	case errors.As(err, &apiError):
+		if exit, ok := errorCodeExits[apiError.ErrorCode]; ok {
+			return exit.status, exit.hint
+		}
+		switch apiError.StatusCode {
+		case 401:
+			return ExitAuthorization, loginHint
+		case 403:
+			return ExitPermission, "Your session may have expired, or you don't have access; try `force login`."
+		case 404:
+			return ExitNotFound, ""
+		case 429:
+			return ExitLimit, ""
+		}
+	case errors.As(err, &refreshError):
+		return ExitAuthorization, ""
+	}
+	return ExitError, ""
+}
+
+// exitWithError prints an error, and a hint if there is one, and exits with a
+// status that says what kind of error it was.
+func exitWithError(err error) {
+	os.Exit(printError(err))
+}
+
+// printError prints an error, and a hint if there is one, and returns the
+// status to exit with.
+func printError(err error) (status int) {
+	status, hint := errorExit(err)
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+	if hint != "" {
+		fmt.Fprintln(os.Stderr, hint)
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/joist-engineering/force/salesforce"
+)
+
+func TestErrorExit(t *testing.T) {
+	status, hint := errorExit(&salesforce.APIError{StatusCode: 400, ErrorCode: "DUPLICATE_VALUE"})
+	assert.Equal(t, ExitDuplicate, status)
+	assert.NotEqual(t, "", hint)
+
+	status, hint = errorExit(fmt.Errorf("creating record: %w", &salesforce.APIError{StatusCode: 401, ErrorCode: "INVALID_SESSION_ID"}))
+	assert.Equal(t, ExitAuthorization, status)
+	assert.Equal(t, loginHint, hint)
+
+	status, _ = errorExit(&salesforce.APIError{StatusCode: 404})
+	assert.Equal(t, ExitNotFound, status)
+
+	status, _ = errorExit(&salesforce.APIError{StatusCode: 500, ErrorCode: "UNKNOWN_EXCEPTION"})
+	assert.Equal(t, ExitError, status)
+
+	status, _ = errorExit(&salesforce.RefreshError{Reason: "expired access/refresh token"})
+	assert.Equal(t, ExitAuthorization, status)
+
+	status, hint = errorExit(errors.New("no such file"))
+	assert.Equal(t, ExitError, status)
+	assert.Equal(t, "", hint)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joist-engineering/force/salesforce"
+)
+
+// explainFormats are the formats query plans can be written in.
+var explainFormats = []string{"console", "json", "json-pretty"}
+
+// writeQueryExplanation writes the plans for a query as a table, cheapest
+// first, followed by their notes, or as JSON.
+func writeQueryExplanation(w io.Writer, explanation salesforce.ForceQueryExplanation, format string) (err error) {
+	switch format {
+	case "json", "json-pretty":
+		var b []byte
+		if format == "json" {
+			b, err = json.Marshal(explanation)
+		} else {
+			b, err = json.MarshalIndent(explanation, "", "  ")
+		}
+		if err != nil {
+			return
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return
+	case "console":
+	default:
+		return fmt.Errorf("-explain can only be written as %s", strings.Join(explainFormats, ", "))
+	}
+
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "Leading Operation\tCardinality\tsObject Cardinality\tRelative Cost\tsObject\tFields")
+	for _, plan := range explanation.Plans {
+		fmt.Fprintf(table, "%s\t%d\t%d\t%s\t%s\t%s\n",
+			plan.LeadingOperationType,
+			plan.Cardinality,
+			plan.SobjectCardinality,
+			strconv.FormatFloat(plan.RelativeCost, 'f', -1, 64),
+			plan.SobjectType,
+			strings.Join(plan.Fields, ", "))
+	}
+	if err = table.Flush(); err != nil {
+		return
+	}
+
+	for i, plan := range explanation.Plans {
+		if len(plan.Notes) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\nNotes on plan %d (%s):\n", i+1, plan.LeadingOperationType)
+		for _, note := range plan.Notes {
+			fmt.Fprintf(w, "  %s: %s", note.TableEnumOrId, note.Description)
+			if len(note.Fields) > 0 {
+				fmt.Fprintf(w, " (%s)", strings.Join(note.Fields, ", "))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return
+}
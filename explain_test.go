@@ -0,0 +1,36 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/joist-engineering/force/salesforce"
+)
+
+func TestWriteQueryExplanation(t *testing.T) {
+	explanation := salesforce.ForceQueryExplanation{
+		Plans: []salesforce.ForceQueryPlan{
+			{Cardinality: 12, Fields: []string{"Name"}, LeadingOperationType: "Index", RelativeCost: 0.0005, SobjectCardinality: 24873, SobjectType: "Account"},
+			{Cardinality: 2487, LeadingOperationType: "TableScan", RelativeCost: 1.65, SobjectCardinality: 24873, SobjectType: "Account",
+				Notes: []salesforce.ForceQueryPlanNote{{Description: "Not considering filter for optimization because unindexed", Fields: []string{"Industry"}, TableEnumOrId: "Account"}}},
+		},
+		SourceQuery: "SELECT Id FROM Account WHERE Name = 'Acme' AND Industry = 'Energy'",
+	}
+
+	var out strings.Builder
+	assert.Equal(t, nil, writeQueryExplanation(&out, explanation, "console"))
+	assert.Equal(t, `Leading Operation  Cardinality  sObject Cardinality  Relative Cost  sObject  Fields
+Index              12           24873                0.0005         Account  Name
+TableScan          2487         24873                1.65           Account  
+
+Notes on plan 2 (TableScan):
+  Account: Not considering filter for optimization because unindexed (Industry)
+`, out.String())
+
+	out.Reset()
+	assert.Equal(t, nil, writeQueryExplanation(&out, explanation, "json"))
+	assert.T(t, strings.HasPrefix(out.String(), `{"plans":[{"cardinality":12,"fields":["Name"],"leadingOperationType":"Index"`), out.String())
+
+	assert.NotEqual(t, nil, writeQueryExplanation(&out, explanation, "csv"))
+}
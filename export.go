@@ -9,7 +9,6 @@ import (
 
 	"github.com/joist-engineering/force/project"
 	"github.com/joist-engineering/force/salesforce"
-	"github.com/joist-engineering/force/util"
 )
 
 var cmdExport = &Command{
@@ -37,17 +36,17 @@ func runExport(cmd *Command, args []string) {
 		root, err = project.GetSourceDir()
 		if err != nil {
 			fmt.Printf("Error obtaining root directory\n")
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	}
 	if err != nil {
 		fmt.Printf("Error obtaining file path\n")
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	force, _ := ActiveForce()
 	sobjects, err := force.ListSobjects()
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	stdObjects := make([]string, 2, len(sobjects)+2)
 	stdObjects[0] = "*"
@@ -153,17 +152,17 @@ func runExport(cmd *Command, args []string) {
 	})
 	if err != nil {
 		fmt.Printf("Encountered and error with retrieve...\n")
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	for name, data := range files {
 		file := filepath.Join(root, name)
 		dir := filepath.Dir(file)
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		if err := ioutil.WriteFile(filepath.Join(root, name), data, 0644); err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	}
 	fmt.Printf("Exported to %s\n", root)
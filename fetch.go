@@ -11,6 +11,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/joist-engineering/force/project"
 	"github.com/joist-engineering/force/salesforce"
@@ -26,6 +27,10 @@ var cmdFetch = &Command{
   -d, -directory  # override the default target directory
   -u, -unpack     # unpack any zipped static resources (ignored if type is not StaticResource)
   -p, -preserve   # preserve the zip file
+  -decompose      # split objects and profiles into one file per field, list view, permission, etc.
+  -changed-since  # fetch everything changed since a date (2006-01-02) or time (2006-01-02T15:04:05Z)
+  -by             # with -changed-since, only fetch changes made by this user (name or username)
+  -list           # with -changed-since, list the changes rather than fetching them
 
 Export specified artifact(s) to a local directory. Use "package" type to retrieve an unmanaged package.
 
@@ -33,6 +38,8 @@ Examples
 
   force fetch -t=CustomObject n=Book__c n=Author__c
   force fetch -t Aura -n MyComponent -d /Users/me/Documents/Project/home
+  force fetch -t CustomObject -n Account -decompose
+  force fetch -changed-since 2026-10-15 -by jane@example.com -list
 
 `,
 }
@@ -59,6 +66,10 @@ var (
 	metadataName    metaName
 	makefile        bool
 	preserveZip     bool
+	decompose       bool
+	changedSince    string
+	changedBy       string
+	listChanged     bool
 	mdbase          string
 )
 
@@ -73,6 +84,10 @@ func init() {
 	cmdFetch.Flag.BoolVar(&unpack, "unpack", false, "Unpack any static resources")
 	cmdFetch.Flag.BoolVar(&preserveZip, "p", false, "keep zip file on disk")
 	cmdFetch.Flag.BoolVar(&preserveZip, "preserve", false, "keep zip file on disk")
+	cmdFetch.Flag.BoolVar(&decompose, "decompose", false, "split objects and profiles into one file per component")
+	cmdFetch.Flag.StringVar(&changedSince, "changed-since", "", "fetch metadata changed since this date")
+	cmdFetch.Flag.StringVar(&changedBy, "by", "", "only fetch metadata changed by this user")
+	cmdFetch.Flag.BoolVar(&listChanged, "list", false, "list changed metadata without fetching it")
 	cmdFetch.Run = runFetch
 	makefile = true
 }
@@ -87,12 +102,12 @@ func runFetchAura2(cmd *Command, entityname string) {
 	if entityname == "" {
 		bundles, definitions, err = force.GetAuraBundles()
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	} else {
 		bundles, definitions, err = force.GetAuraBundle(entityname)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	}
 	_, err = persistBundles(bundles, definitions)
@@ -109,12 +124,12 @@ func FetchManifest(entityname string) (manifest salesforce.BundleManifest) {
 	if entityname == "" {
 		bundles, definitions, err = force.GetAuraBundles()
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	} else {
 		bundles, definitions, err = force.GetAuraBundle(entityname)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	}
 	makefile = false
@@ -139,12 +154,12 @@ func persistBundles(bundles salesforce.AuraDefinitionBundleResult, definitions s
 		root = filepath.Join(targetDirectory, root, mdbase, "aura")
 	}
 	if err := os.MkdirAll(root, 0755); err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	for key, value := range bundleMap {
 		if err := os.MkdirAll(filepath.Join(root, value), 0755); err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 
 		bundleManifest = salesforce.BundleManifest{}
@@ -175,7 +190,7 @@ func persistBundles(bundles salesforce.AuraDefinitionBundleResult, definitions s
 				default:
 					entity += fmt.Sprintf("%s.js", naming)
 				}
-				var componentFile = salesforce.ComponentFile{filepath.Join(root, value, entity), fmt.Sprintf("%s", def["Id"])}
+				var componentFile = salesforce.ComponentFile{FileName: filepath.Join(root, value, entity), ComponentId: fmt.Sprintf("%s", def["Id"])}
 				bundleManifest.Files = append(bundleManifest.Files, componentFile)
 				if makefile {
 					ioutil.WriteFile(filepath.Join(root, value, entity), []byte(fmt.Sprintf("%s", def["Source"])), 0644)
@@ -188,17 +203,103 @@ func persistBundles(bundles salesforce.AuraDefinitionBundleResult, definitions s
 	return
 }
 
+// decomposeFetchedFiles converts the retrieved files to the decomposed source
+// format, clearing out whatever was on disk for those components before so
+// that deleted fields and the like don't linger.
+func decomposeFetchedFiles(root string, files salesforce.ForceMetadataFiles) salesforce.ForceMetadataFiles {
+	decomposed := salesforce.DecomposeMetadata(files)
+	for name := range files {
+		if _, ok := decomposed[name]; ok {
+			continue
+		}
+		file := filepath.Join(root, name)
+		if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+			exitWithError(err)
+		}
+		if err := os.RemoveAll(strings.TrimSuffix(file, filepath.Ext(file))); err != nil {
+			exitWithError(err)
+		}
+	}
+	return decomposed
+}
+
+// parseChangedSince accepts either a date, taken as midnight local time, or a
+// full RFC 3339 timestamp.
+func parseChangedSince(value string) (since time.Time, err error) {
+	if since, err = time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return
+	}
+	if since, err = time.Parse(time.RFC3339, value); err != nil {
+		err = fmt.Errorf("Could not parse %q as a date (2006-01-02) or time (2006-01-02T15:04:05Z)", value)
+	}
+	return
+}
+
+// listChangedMetadata lists every component changed since -changed-since,
+// optionally only those changed by the -by user.
+func listChangedMetadata(force *salesforce.Force) []salesforce.MDFileProperties {
+	since, err := parseChangedSince(changedSince)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	// usernames are resolved to an Id, since listMetadata only gives us
+	// the user's name otherwise.
+	by := changedBy
+	if strings.Contains(by, "@") {
+		soql := fmt.Sprintf("SELECT Id FROM User WHERE Username = '%s'", soqlLiteralEscaper.Replace(by))
+		users, err := force.Query(soql, false)
+		if err != nil {
+			exitWithError(err)
+		}
+		if len(users.Records) == 0 {
+			util.ErrorAndExit("No user with the username %s", by)
+		}
+		by = users.Records[0]["Id"].(string)
+	}
+
+	describe, err := force.Metadata.DescribeMetadata()
+	if err != nil {
+		exitWithError(err)
+	}
+	properties, err := force.Metadata.ListAllMetadataProperties(describe)
+	if err != nil {
+		exitWithError(err)
+	}
+	changed := salesforce.FilterChangedMetadata(properties, since, by)
+	if len(changed) == 0 {
+		fmt.Printf("No metadata changed since %s\n", since.Format(time.RFC3339))
+		os.Exit(0)
+	}
+	return changed
+}
+
 func runFetch(cmd *Command, args []string) {
-	if metadataType == "" {
+	if metadataType == "" && changedSince == "" {
 		util.ErrorAndExit("must specify object type and/or object name")
 	}
+	if changedSince == "" && (changedBy != "" || listChanged) {
+		util.ErrorAndExit("-by and -list can only be used with -changed-since")
+	}
 
 	force, _ := ActiveForce()
 	var files salesforce.ForceMetadataFiles
 	var err error
 	var expandResources bool = unpack
 
-	if strings.ToLower(metadataType) == "aura" {
+	if changedSince != "" {
+		changed := listChangedMetadata(force)
+		if listChanged {
+			DisplayChangedMetadata(changed)
+			return
+		}
+		files, err = force.Metadata.Retrieve(salesforce.RetrieveQueryForProperties(changed), salesforce.ForceRetrieveOptions{
+			PreserveZip: preserveZip,
+		})
+		if err != nil {
+			exitWithError(err)
+		}
+	} else if strings.ToLower(metadataType) == "aura" {
 		if len(metadataName) > 0 {
 			for names := range metadataName {
 				runFetchAura2(cmd, metadataName[names])
@@ -213,7 +314,7 @@ func runFetch(cmd *Command, args []string) {
 					PreserveZip: preserveZip,
 				})
 				if err != nil {
-					util.ErrorAndExit(err.Error())
+					exitWithError(err)
 				}
 				if preserveZip == true {
 					os.Rename("inbound.zip", fmt.Sprintf("%s.zip", metadataName[names]))
@@ -223,17 +324,17 @@ func runFetch(cmd *Command, args []string) {
 	} else {
 		query := salesforce.ForceMetadataQuery{}
 		if len(metadataName) > 0 {
-			mq := salesforce.ForceMetadataQueryElement{metadataType, metadataName}
+			mq := salesforce.ForceMetadataQueryElement{Name: metadataType, Members: metadataName}
 			query = append(query, mq)
 		} else {
-			mq := salesforce.ForceMetadataQueryElement{metadataType, []string{"*"}}
+			mq := salesforce.ForceMetadataQueryElement{Name: metadataType, Members: []string{"*"}}
 			query = append(query, mq)
 		}
 		files, err = force.Metadata.Retrieve(query, salesforce.ForceRetrieveOptions{
 			PreserveZip: preserveZip,
 		})
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	}
 
@@ -243,22 +344,25 @@ func runFetch(cmd *Command, args []string) {
 	root, err := project.GetSourceDir()
 	if err != nil {
 		fmt.Printf("Error obtaining root directory\n")
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	existingPackage, _ := pathExists(filepath.Join(root, "package.xml"))
 
 	if len(files) == 1 {
 		util.ErrorAndExit("Could not find any objects for " + metadataType + ". (Is the metadata type correct?)")
 	}
+	if decompose {
+		files = decomposeFetchedFiles(root, files)
+	}
 	for name, data := range files {
 		if !existingPackage || name != "package.xml" {
 			file := filepath.Join(root, name)
 			dir := filepath.Dir(file)
 			if err := os.MkdirAll(dir, 0755); err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			if err := ioutil.WriteFile(filepath.Join(root, name), data, 0644); err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			var isResource = false
 			if strings.ToLower(metadataType) == "staticresource" {
@@ -302,7 +406,7 @@ func runFetch(cmd *Command, args []string) {
 			resourcefile := value
 			dest := strings.Split(value, ".")[0]
 			if err := os.MkdirAll(dest, 0755); err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			r, err := zip.OpenReader(resourcefile)
 			if err != nil {
@@ -64,7 +64,7 @@ func runFieldList(args []string) {
 	force, _ := ActiveForce()
 	sobject, err := force.GetSobject(args[0])
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	DisplayForceSobject(sobject)
 }
@@ -95,10 +95,10 @@ func runFieldCreate(args []string) {
 	// Validate the options for this field type
 	newOptions, err := force.Metadata.ValidateFieldOptions(parts[1], optionMap)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	if err := force.Metadata.CreateCustomField(args[0], parts[0], parts[1], newOptions); err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Println("Custom field created")
 }
@@ -109,7 +109,7 @@ func runFieldDelete(args []string) {
 	}
 	force, _ := ActiveForce()
 	if err := force.Metadata.DeleteCustomField(args[0], args[1]); err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Println("Custom field deleted")
 }
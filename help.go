@@ -39,12 +39,15 @@ func runHelp(cmd *Command, args []string) {
 }
 
 var usageTemplate = template.Must(template.New("usage").Parse(`
-Usage: force <command> [<args>]
+Usage: force [-u <username or alias>] <command> [<args>]
 
 Available commands:{{range .Commands}}{{if .Runnable}}{{if .List}}
    {{.Name | printf "%-8s"}}  {{.Short}}{{end}}{{end}}{{end}}
 
 Run 'force help [command]' for details.
+
+-u, or the FORCE_ACCOUNT environment variable, runs the command with that
+login instead of the active one, without changing the active login.
 `[1:]))
 
 func printUsage() {
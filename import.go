@@ -1,6 +1,7 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"os"
 
@@ -25,7 +26,7 @@ Deployment Options
   -test                   Run tests in class (implies -l RunSpecifiedTests)
   -testlevel, -l          Set test level (NoTestRun, RunSpecifiedTests, RunLocalTests, RunAllTestsInOrg)
   -ignorewarnings, -i     Indicates if warnings should fail deployment or not
-  -directory, -d 		  Path to the package.xml file to import
+  -directory, -d 		  Path to the package.xml file, or sfdx-project.json, to import
   -verbose, -v 			  Provide detailed feedback on operation
 
 Examples:
@@ -35,6 +36,9 @@ Examples:
   force import -directory=my_metadata -c -r -v
 
   force import -checkonly -runalltests
+
+When run from the root of a Salesforce DX project (one with an sfdx-project.json),
+the package directories are converted from the source format and imported.
 `,
 }
 
@@ -67,29 +71,55 @@ func init() {
 	cmdImport.Flag.Var(&testsToRun, "test", "Test(s) to run")
 }
 
+// isFlagSet reports whether any of the given flags were passed on the command
+// line.
+func isFlagSet(cmd *Command, names ...string) (set bool) {
+	cmd.Flag.Visit(func(f *flag.Flag) {
+		for _, name := range names {
+			if f.Name == name {
+				set = true
+			}
+		}
+	})
+	return
+}
+
 func runImport(cmd *Command, args []string) {
 	if len(args) > 0 {
 		util.ErrorAndExit("Unrecognized argument: " + args[0])
 	}
 
-	loadedProject := project.LoadProject(*directory)
+	// a Salesforce DX project is imported from its root, rather than from
+	// the usual metadata directory.
+	projectDirectory := *directory
+	if !isFlagSet(cmd, "directory", "d") {
+		if _, err := os.Stat(salesforce.SourceProjectFile); err == nil {
+			projectDirectory = "."
+		}
+	}
+	loadedProject := project.LoadProject(projectDirectory)
 
 	force, err := ActiveForce()
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
+	}
+	// source format projects need the org's metadata types to be converted,
+	// but the static table of them will do if the org can't be asked.
+	if loadedProject.IsSourceFormat() {
+		force.Metadata.LoadMetapaths()
 	}
 	files := loadedProject.EnumerateContents()
 
 	loginUsername, err := ActiveLogin()
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	if projectEnvironmentConfig, err := loadedProject.GetEnvironmentConfigForActiveEnvironment(loginUsername, force.Credentials.InstanceUrl); projectEnvironmentConfig != nil {
 		fmt.Printf("About to deploy to: %s at %s\n", projectEnvironmentConfig.Name, force.Credentials.InstanceUrl)
 		files = loadedProject.ContentsWithInternalTransformsApplied(projectEnvironmentConfig)
 	} else if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	// Now to handle the metadata types that Salesforce has implemented their
@@ -102,15 +132,21 @@ func runImport(cmd *Command, args []string) {
 
 	// if we have any flows to deploy, run a remote query to see if we actually
 	// have any non-replaceable metadata that requires it!
-	if project.IsNewFlowVersionsOnlyTransformRequired(files) {
+	transformRequired, err := project.IsNewFlowVersionsOnlyTransformRequired(files)
+	if err != nil {
+		exitWithError(err)
+	}
+	if transformRequired {
 		fmt.Print("Flows are present, checking for active flows in target to skip...\n")
 		targetFlowsAndDefinitions, err := force.Metadata.Retrieve(query, salesforce.ForceRetrieveOptions{})
 		if err != nil {
 			fmt.Printf("Encountered an error with retrieve...\n")
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 
-		files = project.TransformDeployToIncludeNewFlowVersionsOnly(files, targetFlowsAndDefinitions)
+		if files, err = project.TransformDeployToIncludeNewFlowVersionsOnly(files, targetFlowsAndDefinitions); err != nil {
+			exitWithError(err)
+		}
 	}
 
 	var DeploymentOptions salesforce.ForceDeployOptions
@@ -130,7 +166,7 @@ func runImport(cmd *Command, args []string) {
 	problems := result.Details.ComponentFailures
 	successes := result.Details.ComponentSuccesses
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	fmt.Printf("\nFailures - %d\n", len(problems))
@@ -5,7 +5,6 @@ import (
 	"sort"
 
 	"github.com/joist-engineering/force/salesforce"
-	"github.com/joist-engineering/force/util"
 )
 
 var cmdLimits = &Command{
@@ -31,7 +30,7 @@ func runLimits(cmd *Command, args []string) {
 	result, err := force.GetLimits()
 
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	} else {
 		printLimits(result)
 	}
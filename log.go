@@ -2,8 +2,6 @@ package main
 
 import (
 	"fmt"
-
-	"github.com/joist-engineering/force/util"
 )
 
 var cmdLog = &Command{
@@ -29,14 +27,14 @@ func getLog(cmd *Command, args []string) {
 	if len(args) == 0 || args[0] == "list" {
 		records, err := force.QueryLogs()
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		DisplayForceRecords(records)
 	} else {
 		logId := args[0]
 		log, err := force.RetrieveLog(logId)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		fmt.Println(log)
 	}
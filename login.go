@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 
 	"github.com/bgentry/speakeasy"
@@ -16,16 +17,33 @@ var cmdLogin = &Command{
 	Long: `
   force login [-i=<instance>] [<-u=username> <-p=password> <-v=apiversion]
 
+  force login -jwt -client-id=<consumer key> -key=<private key file> -u=username [-i=<instance>] [-audience=<url>]
+
+  force login -token=<session id> -instance=<instance url>
+
+  force login -auth-url=force://<client id>:<client secret>:<refresh token>@<instance>
+
+  The JWT bearer flow needs no browser or password, which suits CI servers.
+  The connected app must have the certificate for the private key, and the
+  user must be pre-authorized for the app.  The assertion's audience is
+  test.salesforce.com for sandboxes, including sandbox My Domains given
+  with -i, and login.salesforce.com otherwise; give -audience for a
+  community.
+
   Examples:
     force login
     force login -i=test
     force login -u=un -p=pw
     force login -i=test -u=un -p=pw
     force login -i=na1-blitz01.soma.salesforce.com -u=un -p=pw
+    force login -jwt -client-id=3MVG9... -key=server.key -u=un -i=test
+    force login -token=00D... -instance=https://example.my.salesforce.com
+    force login -auth-url=force://PlatformCLI::5Aep...@example.my.salesforce.com
 `,
 }
 
 func init() {
+	cmdLogin.Flag.StringVar(instance, "instance", "", "Instance url, same as -i")
 	cmdLogin.Run = runLogin
 }
 
@@ -36,9 +54,39 @@ var (
 	userName    = cmdLogin.Flag.String("u", "", "Username for Soap Login")
 	password    = cmdLogin.Flag.String("p", "", "Password for Soap Login")
 	api_version = cmdLogin.Flag.String("v", "", "API Version to use")
+	jwtLogin    = cmdLogin.Flag.Bool("jwt", false, "Log in with the OAuth JWT bearer flow")
+	clientId    = cmdLogin.Flag.String("client-id", "", "Consumer key of the connected app for JWT Login")
+	keyFile     = cmdLogin.Flag.String("key", "", "Private key file for JWT Login")
+	audience    = cmdLogin.Flag.String("audience", "", "Audience of the assertion for JWT Login, if not login.salesforce.com or test.salesforce.com")
+	token       = cmdLogin.Flag.String("token", "", "Existing session id or access token to use")
+	authUrl     = cmdLogin.Flag.String("auth-url", "", "Salesforce DX auth url to log in with")
 )
 
 func runLogin(cmd *Command, args []string) {
+	if *token != "" {
+		if *instance == "" {
+			util.ErrorAndExit("Logging in with a token needs the -instance url")
+		}
+		creds, err := salesforce.ForceTokenLogin(*instance, *token)
+		if err != nil {
+			util.ErrorAndExit("Unable to use the token: %s", err.Error())
+		}
+		if _, err = ForceSaveLogin(creds); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+	if *authUrl != "" {
+		creds, err := salesforce.ForceAuthUrlLogin(*authUrl)
+		if err != nil {
+			util.ErrorAndExit("Unable to log in with the auth url: %s", err.Error())
+		}
+		if _, err = ForceSaveLogin(creds); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+
 	var endpoint salesforce.ForceEndpoint = salesforce.EndpointProduction
 
 	// If no instance specified, try to get last endpoint used
@@ -91,22 +139,30 @@ func runLogin(cmd *Command, args []string) {
 		}
 	}
 
-	if len(*userName) != 0 { // Do SOAP login
+	if *jwtLogin {
+		if *clientId == "" || *keyFile == "" || *userName == "" {
+			util.ErrorAndExit("JWT login needs -client-id, -key and -u")
+		}
+		_, err := ForceLoginAndSaveJWT(endpoint, *clientId, *userName, *audience, *keyFile)
+		if err != nil {
+			exitWithError(err)
+		}
+	} else if len(*userName) != 0 { // Do SOAP login
 		if len(*password) == 0 {
 			var err error
 			*password, err = speakeasy.Ask("Password: ")
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 		}
 		_, err := ForceLoginAndSaveSoap(endpoint, *userName, *password, apiVersion)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	} else { // Do OAuth login
 		_, err := ForceLoginAndSave(endpoint)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 	}
 }
@@ -122,10 +178,19 @@ func CurrentEndpoint() (endpoint salesforce.ForceEndpoint, customUrl string, err
 }
 
 func ForceSaveLogin(creds salesforce.ForceCredentials) (username string, err error) {
-	// TODO find existing creds to rescue existing apiVersion:
 	creds.ApiVersion = salesforce.DefaultApiVersion
 
-	if existingCredsJSON, err := util.Config.Load("accounts", account); err == nil {
+	force := salesforce.NewForce(creds)
+	login, err := force.Get(creds.Id)
+	if err != nil {
+		return
+	}
+
+	userId := login["user_id"].(string)
+	creds.UserId = userId
+	username = login["username"].(string)
+
+	if existingCredsJSON, err := util.Config.Load("accounts", username); err == nil {
 		// there's an existing account!  Copy over its api version (and any other
 		// settings we want to persist across re-logins:)
 		var existingCreds salesforce.ForceCredentials
@@ -133,25 +198,11 @@ func ForceSaveLogin(creds salesforce.ForceCredentials) (username string, err err
 			if existingCreds.ApiVersion != "" {
 				fmt.Printf("We already have settings for a previous login of this account, carrying them over\n")
 				creds.ApiVersion = existingCreds.ApiVersion
+				force.Credentials.ApiVersion = existingCreds.ApiVersion
 			}
 		}
 	}
 
-	force := salesforce.NewForce(creds)
-	login, err := force.Get(creds.Id)
-	if err != nil {
-		return
-	}
-
-	body, err := json.Marshal(creds)
-	if err != nil {
-		return
-	}
-
-	userId := login["user_id"].(string)
-	creds.UserId = userId
-	username = login["username"].(string)
-
 	me, err := force.Whoami()
 	if err != nil {
 		fmt.Println("Problem getting user data, continuing...")
@@ -159,7 +210,7 @@ func ForceSaveLogin(creds salesforce.ForceCredentials) (username string, err err
 	}
 	fmt.Printf("Logged in as '%s' (API %s)\n", me["Username"], creds.ApiVersion)
 	title := fmt.Sprintf("\033];%s\007", me["Username"])
-	fmt.Printf(title)
+	fmt.Print(title)
 
 	describe, err := force.Metadata.DescribeMetadata()
 
@@ -170,12 +221,24 @@ func ForceSaveLogin(creds salesforce.ForceCredentials) (username string, err err
 		err = nil
 	}
 
-	body, err = json.Marshal(creds)
+	err = saveLogin(username, creds)
+	return
+}
+
+// saveLogin stores a login's credentials and makes it the active login,
+// unless a login was chosen for this run with -u or FORCE_ACCOUNT, which
+// leaves the active login as it was.
+func saveLogin(username string, creds salesforce.ForceCredentials) (err error) {
+	body, err := json.Marshal(creds)
 	if err != nil {
 		return
 	}
-	util.Config.Save("accounts", username, string(body))
-	util.Config.Save("current", "account", username)
+	if err = util.Config.Save("accounts", username, string(body)); err != nil {
+		return
+	}
+	if !accountSelected {
+		util.Config.Save("current", "account", username)
+	}
 	return
 }
 
@@ -192,6 +255,20 @@ func ForceLoginAndSaveSoap(endpoint salesforce.ForceEndpoint, user_name string,
 	return
 }
 
+func ForceLoginAndSaveJWT(endpoint salesforce.ForceEndpoint, clientId string, user_name string, audience string, keyFile string) (username string, err error) {
+	key, err := ioutil.ReadFile(keyFile)
+	if err != nil {
+		return
+	}
+	creds, err := salesforce.ForceJWTLogin(endpoint, clientId, user_name, audience, key)
+	if err != nil {
+		return
+	}
+
+	username, err = ForceSaveLogin(creds)
+	return
+}
+
 func ForceLoginAndSave(endpoint salesforce.ForceEndpoint) (username string, err error) {
 	creds, err := salesforce.ForceLogin(endpoint)
 	if err != nil {
@@ -2,9 +2,12 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"strings"
+	"sync"
 	"text/tabwriter"
 
 	"github.com/joist-engineering/force/salesforce"
@@ -13,52 +16,304 @@ import (
 
 var cmdLogins = &Command{
 	Run:   runLogins,
-	Usage: "logins",
+	Usage: "logins [-check] [-json] [prune | migrate-store <store>]",
 	Short: "List force.com logins used",
 	Long: `
 List force.com accounts
 
+  -check   check that every login still works, showing whether it's valid,
+           expired or unreachable, and the type of org
+  -json    output as JSON
+
+prune removes the logins that have expired.
+
+Logins are saved in plain files by default.  migrate-store moves them to
+another credential store, which is then used for new logins too:
+
+  plaintext  plain files in ~/.force (the default)
+  encrypted  files encrypted with a key derived from the passphrase in
+             FORCE_CONFIG_PASSPHRASE, which must be set whenever force runs
+  keyring    the Secret Service keyring (GNOME Keyring or KWallet), using
+             secret-tool from libsecret
+
 Examples:
 
   force logins
+
+  force logins -check -json
+
+  force logins prune
+
+  FORCE_CONFIG_PASSPHRASE=... force logins migrate-store encrypted
 `,
 }
 
+var (
+	checkLogins bool
+	loginsJson  bool
+)
+
+func init() {
+	cmdLogins.Flag.BoolVar(&checkLogins, "check", false, "check that each login still works")
+	cmdLogins.Flag.BoolVar(&loginsJson, "json", false, "output to json")
+}
+
+// The states of a checked login.
+const (
+	LoginValid       = "valid"
+	LoginExpired     = "expired"
+	LoginUnreachable = "unreachable"
+	LoginUnreadable  = "unreadable"
+	LoginError       = "error"
+)
+
+// LoginStatus describes a saved login, and if it was checked, whether it
+// still works.
+type LoginStatus struct {
+	Username    string   `json:"username"`
+	Aliases     []string `json:"aliases,omitempty"`
+	Active      bool     `json:"active"`
+	InstanceUrl string   `json:"instanceUrl,omitempty"`
+	ApiVersion  string   `json:"apiVersion,omitempty"`
+	Namespace   string   `json:"namespace,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	OrgType     string   `json:"orgType,omitempty"`
+	Error       string   `json:"error,omitempty"`
+}
+
 func runLogins(cmd *Command, args []string) {
+	if len(args) > 0 {
+		switch args[0] {
+		case "prune":
+			runPruneLogins()
+		case "migrate-store":
+			runMigrateStore(args[1:])
+		default:
+			util.ErrorAndExit("no such command: %s", args[0])
+		}
+		return
+	}
+
+	logins := loadLogins()
+	if checkLogins {
+		checkLoginStatuses(logins)
+	}
+	if loginsJson {
+		if logins == nil {
+			logins = []LoginStatus{}
+		}
+		out, _ := json.MarshalIndent(logins, "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	if len(logins) == 0 {
+		fmt.Println("no logins")
+		return
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 1, 0, 1, ' ', 0)
+	for _, login := range logins {
+		account := login.Username
+		var banner = fmt.Sprintf("\t%s\t%s", login.InstanceUrl, strings.Join(login.Aliases, ", "))
+		if login.Active {
+			account = fmt.Sprintf("\x1b[31;1m%s (active)\x1b[0m", account)
+		} else {
+			account = fmt.Sprintf("%s \x1b[31;1m\x1b[0m", account)
+		}
+		if login.Status == LoginUnreadable {
+			banner = fmt.Sprintf("\t(unreadable: %s)", login.Error)
+		} else if checkLogins {
+			banner += fmt.Sprintf("\t%s\t%s\t%s\t%s", login.Status, login.OrgType, login.ApiVersion, login.Namespace)
+			if login.Error != "" {
+				banner += fmt.Sprintf("\t%s", login.Error)
+			}
+		}
+		fmt.Fprintln(w, account+banner)
+	}
+	fmt.Fprintln(w)
+	w.Flush()
+}
+
+// loadLogins reads every saved login.  Logins that can't be read are included
+// with the reason.
+func loadLogins() (logins []LoginStatus) {
 	active, _ := ActiveLogin()
 	accounts, _ := util.Config.List("accounts")
-	if len(accounts) == 0 {
-		fmt.Println("no logins")
-	} else {
-		w := new(tabwriter.Writer)
-		w.Init(os.Stdout, 1, 0, 1, ' ', 0)
-
-		for _, account := range accounts {
-			if !strings.HasPrefix(account, ".") {
-				var creds salesforce.ForceCredentials
-				data, err := util.Config.Load("accounts", account)
-				json.Unmarshal([]byte(data), &creds)
-
-				if err != nil {
-					return
-				}
-
-				var banner = fmt.Sprintf("\t%s", creds.InstanceUrl)
-				if account == active {
-					account = fmt.Sprintf("\x1b[31;1m%s (active)\x1b[0m", account)
-				} else {
-					account = fmt.Sprintf("%s \x1b[31;1m\x1b[0m", account)
-				}
-				fmt.Fprintln(w, fmt.Sprintf("%s%s", account, banner))
+	for _, account := range accounts {
+		if strings.HasPrefix(account, ".") {
+			continue
+		}
+		login := LoginStatus{
+			Username: account,
+			Aliases:  AliasesFor(account),
+			Active:   account == active,
+		}
+		creds, err := loadCredentials(account)
+		if err != nil {
+			login.Status = LoginUnreadable
+			login.Error = err.Error()
+		} else {
+			login.InstanceUrl = creds.InstanceUrl
+			login.ApiVersion = creds.ApiVersion
+			login.Namespace = creds.Namespace
+		}
+		logins = append(logins, login)
+	}
+	return
+}
+
+func loadCredentials(account string) (creds salesforce.ForceCredentials, err error) {
+	data, err := util.Config.Load("accounts", account)
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal([]byte(data), &creds)
+	return
+}
+
+// checkLoginStatuses checks every readable login at the same time.
+func checkLoginStatuses(logins []LoginStatus) {
+	var wg sync.WaitGroup
+	for i := range logins {
+		if logins[i].Status == LoginUnreadable {
+			continue
+		}
+		creds, err := loadCredentials(logins[i].Username)
+		if err != nil {
+			continue
+		}
+		wg.Add(1)
+		go func(login *LoginStatus) {
+			defer wg.Done()
+			force := salesforce.NewForce(creds)
+			force.CredentialsRefreshed = func(creds salesforce.ForceCredentials) error {
+				return SaveCredentials(login.Username, creds)
 			}
+			checkLogin(force, login)
+		}(&logins[i])
+	}
+	wg.Wait()
+}
+
+// organizationQuery finds out the type of a login's org.
+const organizationQuery = "SELECT IsSandbox, NamespacePrefix FROM Organization"
+
+// checkLogin looks up the identity of a login to see if it still works, and
+// if it does, the type of its org.
+func checkLogin(force *salesforce.Force, login *LoginStatus) {
+	_, err := force.Get(force.Credentials.Id)
+	if errors.Is(err, salesforce.ErrForbidden) {
+		// The identity service answers an expired token with a 403 rather
+		// than a 401, so refresh it here, or without a refresh token, ask
+		// the REST API, which says whether the session has expired.
+		if force.Credentials.RefreshToken != "" {
+			if err = force.RefreshSession(force.Credentials.AccessToken); err == nil {
+				_, err = force.Get(force.Credentials.Id)
+			}
+		} else if _, restErr := force.Query(organizationQuery, false); restErr == nil || errors.Is(restErr, salesforce.ErrAuthorizationExpired) {
+			err = restErr
 		}
-		fmt.Fprintln(w)
-		w.Flush()
 	}
+	if err != nil {
+		login.Status = loginErrorStatus(err, force.Credentials.RefreshToken != "")
+		login.Error = err.Error()
+		return
+	}
+	login.Status = LoginValid
 
+	result, err := force.Query(organizationQuery, false)
+	if err != nil || len(result.Records) == 0 {
+		return
+	}
+	org := result.Records[0]
+	if isSandbox, _ := org["IsSandbox"].(bool); isSandbox {
+		login.OrgType = "sandbox"
+	} else {
+		login.OrgType = "production"
+	}
+	if namespace, ok := org["NamespacePrefix"].(string); ok {
+		login.Namespace = namespace
+	}
+}
+
+// loginErrorStatus says what a failed check means.  A login has expired when
+// its refresh token has been refused, or if it has none, when its session
+// has; a 403 that may be for want of a permission, or a failure to refresh
+// for any other reason, could be anything, so such logins are reported as
+// errors and never pruned.
+func loginErrorStatus(err error, refreshable bool) string {
+	var urlError *url.Error
+	var refreshError *salesforce.RefreshError
+	switch {
+	case errors.As(err, &urlError):
+		return LoginUnreachable
+	case errors.As(err, &refreshError):
+		return LoginExpired
+	case !refreshable && errors.Is(err, salesforce.ErrAuthorizationExpired):
+		return LoginExpired
+	}
+	return LoginError
+}
+
+func runPruneLogins() {
+	logins := loadLogins()
+	checkLoginStatuses(logins)
+	pruned := 0
+	for _, login := range logins {
+		if login.Status != LoginExpired {
+			continue
+		}
+		util.Config.Delete("accounts", login.Username)
+		removeAliasesFor(login.Username)
+		if active, _ := util.Config.Load("current", "account"); active == login.Username {
+			util.Config.Delete("current", "account")
+			SetActiveLoginDefault()
+		}
+		fmt.Printf("Removed %s\n", login.Username)
+		pruned++
+	}
+	if pruned == 0 {
+		fmt.Println("No expired logins")
+	}
+}
+
+func runMigrateStore(args []string) {
+	if len(args) != 1 {
+		util.ErrorAndExit("must specify one of: %s", strings.Join(util.CredentialStores, ", "))
+	}
+	from := util.Config.CredentialStoreName()
+	migrated, err := util.Config.MigrateCredentials(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	fmt.Printf("Moved %d logins from the %s store to the %s store\n", len(migrated), from, args[0])
+}
+
+// selectedAccount is the login chosen with -u or FORCE_ACCOUNT.  When it is
+// set, commands use it for the whole run instead of the active login, which
+// is left alone.
+var selectedAccount string
+
+// accountSelected is whether -u or FORCE_ACCOUNT was given, even for a login
+// that doesn't exist yet.
+var accountSelected bool
+
+// SelectAccount makes this run use the given login, or alias, rather than the
+// active one.
+func SelectAccount(account string) (err error) {
+	username := ResolveAccount(account)
+	if !hasLogin(username) {
+		return fmt.Errorf("no login for %s, please run `force login`", account)
+	}
+	selectedAccount = username
+	return
 }
 
 func ActiveLogin() (account string, err error) {
+	if selectedAccount != "" {
+		return selectedAccount, nil
+	}
 	account, err = util.Config.Load("current", "account")
 	if err != nil {
 		accounts, _ := util.Config.List("accounts")
@@ -74,21 +329,35 @@ func ActiveCredentials() (creds salesforce.ForceCredentials, err error) {
 	if err != nil {
 		return
 	}
-	data, err := util.Config.Load("accounts", account)
-	json.Unmarshal([]byte(data), &creds)
-
-	return
+	return loadCredentials(account)
 }
 
 func ActiveForce() (force *salesforce.Force, err error) {
+	account, err := ActiveLogin()
+	if err != nil {
+		return
+	}
 	creds, err := ActiveCredentials()
 	if err != nil {
 		return
 	}
 	force = salesforce.NewForce(creds)
+	force.CredentialsRefreshed = func(creds salesforce.ForceCredentials) error {
+		return SaveCredentials(account, creds)
+	}
 	return
 }
 
+// SaveCredentials stores the credentials of an account, such as after its
+// access token has been refreshed.
+func SaveCredentials(account string, creds salesforce.ForceCredentials) (err error) {
+	body, err := json.Marshal(creds)
+	if err != nil {
+		return
+	}
+	return util.Config.Save("accounts", account, string(body))
+}
+
 func SetActiveLoginDefault() (account string) {
 	accounts, _ := util.Config.List("accounts")
 	if len(accounts) > 0 {
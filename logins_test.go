@@ -1,10 +1,14 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/bmizerany/assert"
 	"github.com/devangel/config"
+	"github.com/joist-engineering/force/salesforce"
 )
 
 var TestConfig = config.NewConfig("force")
@@ -17,3 +21,85 @@ func TestSetActiveLogin(t *testing.T) {
 	account, _ := TestConfig.Load("current", "account")
 	assert.Equal(t, account, "clint")
 }
+
+func TestCheckLogin(t *testing.T) {
+	tokenStatus, tokenResponse := 0, ""
+	restStatus := 401
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/services/oauth2/token" {
+			w.WriteHeader(tokenStatus)
+			w.Write([]byte(tokenResponse))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer valid" {
+			// The identity service refuses a bad token with a 403.
+			if strings.HasPrefix(r.URL.Path, "/id/") {
+				w.WriteHeader(403)
+				return
+			}
+			w.WriteHeader(restStatus)
+			if restStatus == 401 {
+				w.Write([]byte(`[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`))
+			} else {
+				w.Write([]byte(`[{"message":"API is disabled for this User","errorCode":"API_DISABLED_FOR_ORG"}]`))
+			}
+			return
+		}
+		switch r.URL.Path {
+		case "/id/00D000000000001/005000000000001":
+			w.Write([]byte(`{"user_id":"005000000000001","username":"user@example.org"}`))
+		case "/services/data/v45.0/query":
+			w.Write([]byte(`{"done":true,"totalSize":1,"records":[{"IsSandbox":true,"NamespacePrefix":"acme"}]}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer server.Close()
+
+	creds := salesforce.ForceCredentials{
+		AccessToken: "valid",
+		Id:          server.URL + "/id/00D000000000001/005000000000001",
+		InstanceUrl: server.URL,
+		ApiVersion:  "v45.0",
+	}
+	var login LoginStatus
+	checkLogin(salesforce.NewForce(creds), &login)
+	assert.Equal(t, LoginValid, login.Status)
+	assert.Equal(t, "sandbox", login.OrgType)
+	assert.Equal(t, "acme", login.Namespace)
+
+	// Without a refresh token to try, a session the REST API refuses has
+	// expired, but a 403 may be for want of a permission.
+	creds.AccessToken = "expired"
+	login = LoginStatus{}
+	checkLogin(salesforce.NewForce(creds), &login)
+	assert.Equal(t, LoginExpired, login.Status)
+	assert.Equal(t, "", login.OrgType)
+
+	restStatus = 403
+	login = LoginStatus{}
+	checkLogin(salesforce.NewForce(creds), &login)
+	assert.Equal(t, LoginError, login.Status)
+	restStatus = 401
+
+	creds.RefreshToken = "refresh"
+	tokenStatus, tokenResponse = 400, `{"error":"invalid_grant","error_description":"expired access/refresh token"}`
+	login = LoginStatus{}
+	checkLogin(salesforce.NewForce(creds), &login)
+	assert.Equal(t, LoginExpired, login.Status)
+
+	tokenStatus, tokenResponse = 503, "Service Unavailable"
+	login = LoginStatus{}
+	force := salesforce.NewForce(creds)
+	force.Retry = salesforce.RetryPolicy{}
+	checkLogin(force, &login)
+	assert.Equal(t, LoginError, login.Status)
+	creds.RefreshToken = ""
+
+	server.Close()
+	login = LoginStatus{}
+	force = salesforce.NewForce(creds)
+	force.Retry = salesforce.RetryPolicy{}
+	checkLogin(force, &login)
+	assert.Equal(t, LoginUnreachable, login.Status)
+}
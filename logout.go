@@ -12,7 +12,7 @@ var cmdLogout = &Command{
 	Usage: "logout",
 	Short: "Log out from force.com",
 	Long: `
-  force logout -u=username
+  force logout -u=<username or alias>
 
   Example:
 
@@ -34,7 +34,9 @@ func runLogout(cmd *Command, args []string) {
 		cmd.Flag.Usage()
 		return
 	}
+	*userName1 = ResolveAccount(*userName1)
 	util.Config.Delete("accounts", *userName1)
+	removeAliasesFor(*userName1)
 	if active, _ := util.Config.Load("current", "account"); active == *userName1 {
 		util.Config.Delete("current", "account")
 		SetActiveLoginDefault()
@@ -44,6 +46,6 @@ func runLogout(cmd *Command, args []string) {
 		cmd.Run()
 	} else {
 		title := fmt.Sprintf("\033];%s\007", "")
-		fmt.Printf(title)
+		fmt.Print(title)
 	}
 }
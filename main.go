@@ -10,6 +10,7 @@ var commands = []*Command{
 	cmdLogout,
 	cmdLogins,
 	cmdActive,
+	cmdAlias,
 	cmdWhoami,
 	cmdDescribe,
 	cmdSobject,
@@ -20,7 +21,9 @@ var commands = []*Command{
 	cmdFetch,
 	cmdImport,
 	cmdExport,
+	cmdPackage,
 	cmdQuery,
+	cmdSoql,
 	cmdApex,
 	cmdTrace,
 	cmdLog,
@@ -40,10 +43,16 @@ var commands = []*Command{
 }
 
 func main() {
-	args := os.Args[1:]
+	args, account, err := parseGlobalOptions(os.Args[1:])
+	if err != nil {
+		exitWithError(err)
+	}
 	if len(args) < 1 {
 		usage()
 	}
+	if err = selectAccount(args[0], account); err != nil {
+		exitWithError(err)
+	}
 
 	for _, cmd := range commands {
 		if cmd.Name() == args[0] && cmd.Run != nil {
@@ -43,11 +43,11 @@ func runOauthCreate(cmd *Command, args []string) {
 	force, _ := ActiveForce()
 	err := force.Metadata.CreateConnectedApp(args[0], args[1])
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	apps, err := force.Metadata.ListConnectedApps()
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	runFetch(cmd, []string{"ConnectedApp", args[0]})
 	for _, app := range apps {
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// AccountVariable is the environment variable that selects the login to use,
+// like the global -u option.
+const AccountVariable = "FORCE_ACCOUNT"
+
+// parseGlobalOptions reads the options given before the command name, and
+// returns the remaining arguments along with the login selected by -u or
+// FORCE_ACCOUNT, if any.  -u takes precedence over FORCE_ACCOUNT.
+func parseGlobalOptions(args []string) (rest []string, account string, err error) {
+	account = os.Getenv(AccountVariable)
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		option := strings.TrimLeft(args[0], "-")
+		switch {
+		case option == "u":
+			if len(args) < 2 {
+				return nil, "", errors.New("-u needs a username")
+			}
+			account = args[1]
+			args = args[2:]
+		case strings.HasPrefix(option, "u="):
+			account = strings.TrimPrefix(option, "u=")
+			args = args[1:]
+		default:
+			return nil, "", errors.New("unknown option: " + args[0])
+		}
+	}
+	return args, account, nil
+}
+
+// loginCommands manage logins, so they run whether or not the login selected
+// with -u or FORCE_ACCOUNT exists yet; otherwise it couldn't be created.
+var loginCommands = []string{"login", "logout", "logins", "alias", "help"}
+
+// selectAccount makes a run of a command use the login selected with -u or
+// FORCE_ACCOUNT, if any.
+func selectAccount(command, account string) error {
+	if account == "" {
+		return nil
+	}
+	accountSelected = true
+	err := SelectAccount(account)
+	if err != nil && StringSliceContains(loginCommands, command) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestParseGlobalOptions(t *testing.T) {
+	os.Unsetenv(AccountVariable)
+
+	rest, account, err := parseGlobalOptions([]string{"-u", "user@example.org", "query", "SELECT Id FROM Account"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "user@example.org", account)
+	assert.Equal(t, []string{"query", "SELECT Id FROM Account"}, rest)
+
+	rest, account, err = parseGlobalOptions([]string{"--u=user@example.org", "whoami"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "user@example.org", account)
+	assert.Equal(t, []string{"whoami"}, rest)
+
+	rest, account, err = parseGlobalOptions([]string{"logout", "-u", "user@example.org"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "", account)
+	assert.Equal(t, []string{"logout", "-u", "user@example.org"}, rest)
+
+	_, _, err = parseGlobalOptions([]string{"-x", "whoami"})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestParseGlobalOptionsEnvironment(t *testing.T) {
+	os.Setenv(AccountVariable, "env@example.org")
+	defer os.Unsetenv(AccountVariable)
+
+	_, account, _ := parseGlobalOptions([]string{"whoami"})
+	assert.Equal(t, "env@example.org", account)
+
+	_, account, _ = parseGlobalOptions([]string{"-u", "flag@example.org", "whoami"})
+	assert.Equal(t, "flag@example.org", account)
+}
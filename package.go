@@ -0,0 +1,181 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joist-engineering/force/project"
+	"github.com/joist-engineering/force/salesforce"
+	"github.com/joist-engineering/force/util"
+)
+
+var cmdPackage = &Command{
+	Run:   runPackage,
+	Usage: "package <command> [<args>]",
+	Short: "Generate, validate and merge package.xml manifests",
+	Long: `
+Generate, validate and merge package.xml manifests.  None of these need a login.
+
+Usage:
+
+  force package generate [-v <api version>] [<directory>]
+
+  force package validate [-m <package.xml>] [<directory>]
+
+  force package merge <package.xml> <package.xml>...
+
+  force package diff <package.xml> <package.xml>
+
+The directory defaults to "metadata", or the current directory in a
+Salesforce DX project.  Manifests are written to standard output.
+
+  generate  list every component in the directory
+  validate  report members with no file, and files not in the manifest
+            (the manifest defaults to package.xml in the directory, or
+            manifest/package.xml in a Salesforce DX project)
+  merge     combine manifests, with sorted and deduplicated members
+  diff      show members only in the first (-) or second (+) manifest
+
+Examples:
+
+  force package generate > metadata/package.xml
+
+  force package validate -m manifest/package.xml
+
+  force package merge package.xml other/package.xml > merged.xml
+
+  force package diff package.xml other/package.xml
+`,
+}
+
+func runPackage(cmd *Command, args []string) {
+	if len(args) == 0 {
+		cmd.printUsage()
+	} else {
+		switch args[0] {
+		case "generate":
+			runPackageGenerate(args[1:])
+		case "validate":
+			runPackageValidate(args[1:])
+		case "merge":
+			runPackageMerge(args[1:])
+		case "diff":
+			runPackageDiff(args[1:])
+		default:
+			util.ErrorAndExit("no such command: %s", args[0])
+		}
+	}
+}
+
+// readPackageDirectory reads in a project directory in the Metadata API
+// format, converting Salesforce DX projects as needed.
+func readPackageDirectory(args []string) (dir string, files salesforce.ForceMetadataFiles, isSourceFormat bool) {
+	dir = "metadata"
+	if len(args) > 0 {
+		dir = args[0]
+	} else if _, err := os.Stat(salesforce.SourceProjectFile); err == nil {
+		dir = "."
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, salesforce.SourceProjectFile)); err == nil {
+		files = project.LoadProject(dir).EnumerateContents()
+		return dir, files, true
+	}
+	if _, err := os.Stat(dir); err != nil {
+		exitWithError(err)
+	}
+	return dir, project.ReadDirectoryContents(dir), false
+}
+
+func readPackageFile(path string) salesforce.Package {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		exitWithError(err)
+	}
+	p, err := salesforce.ParsePackage(data)
+	if err != nil {
+		util.ErrorAndExit("Could not parse %s: %s", path, err.Error())
+	}
+	return p
+}
+
+func runPackageGenerate(args []string) {
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	apiVersion := flags.String("v", strings.TrimPrefix(salesforce.DefaultApiVersion, "v"), "API version of the manifest")
+	flags.Parse(args)
+
+	_, files, _ := readPackageDirectory(flags.Args())
+	delete(files, "package.xml")
+	p, err := salesforce.GeneratePackage(files, *apiVersion)
+	if err != nil {
+		exitWithError(err)
+	}
+	fmt.Print(string(p.Xml()))
+}
+
+func runPackageValidate(args []string) {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	manifest := flags.String("m", "", "path to the package.xml")
+	flags.Parse(args)
+
+	dir, files, isSourceFormat := readPackageDirectory(flags.Args())
+	if *manifest == "" {
+		*manifest = filepath.Join(dir, "package.xml")
+		if isSourceFormat {
+			*manifest = filepath.Join(dir, "manifest", "package.xml")
+		}
+	}
+	delete(files, "package.xml")
+	delete(files, "environments.json")
+
+	missing, uncovered, err := salesforce.ValidatePackage(readPackageFile(*manifest), files)
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(missing) > 0 {
+		fmt.Printf("Members of %s without a file:\n", *manifest)
+		for _, member := range missing {
+			fmt.Printf("  %s: %s\n", member.Type, member.Member)
+		}
+	}
+	if len(uncovered) > 0 {
+		fmt.Printf("Files not in %s:\n", *manifest)
+		for _, name := range uncovered {
+			fmt.Printf("  %s\n", name)
+		}
+	}
+	if len(missing) > 0 || len(uncovered) > 0 {
+		os.Exit(1)
+	}
+}
+
+func runPackageMerge(args []string) {
+	if len(args) < 2 {
+		util.ErrorAndExit("must specify at least two manifests to merge")
+	}
+	var packages []salesforce.Package
+	for _, path := range args {
+		packages = append(packages, readPackageFile(path))
+	}
+	fmt.Print(string(salesforce.MergePackages(packages...).Xml()))
+}
+
+func runPackageDiff(args []string) {
+	if len(args) != 2 {
+		util.ErrorAndExit("must specify two manifests to compare")
+	}
+	onlyInA, onlyInB := salesforce.DiffPackages(readPackageFile(args[0]), readPackageFile(args[1]))
+	for _, member := range onlyInA {
+		fmt.Printf("- %s: %s\n", member.Type, member.Member)
+	}
+	for _, member := range onlyInB {
+		fmt.Printf("+ %s: %s\n", member.Type, member.Member)
+	}
+	if len(onlyInA) > 0 || len(onlyInB) > 0 {
+		os.Exit(1)
+	}
+}
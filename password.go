@@ -51,11 +51,11 @@ func runPasswordStatus(args []string) {
 	force, _ := ActiveForce()
 	records, err := force.Query(fmt.Sprintf("select Id From User Where UserName = '%s'", args[0]), false)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	} else {
 		object, err := force.GetPasswordStatus(records.Records[0]["Id"].(string))
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		} else {
 			fmt.Printf("\nPassword is expired: %t\n\n", object.IsExpired)
 		}
@@ -70,7 +70,7 @@ func runPasswordReset(args []string) {
 	records, err := force.Query(fmt.Sprintf("select Id From User Where UserName = '%s'", args[0]), false)
 	object, err := force.ResetPassword(records.Records[0]["Id"].(string))
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	} else {
 		fmt.Printf("\nNew password is: %s\n\n", object.NewPassword)
 	}
@@ -83,14 +83,14 @@ func runPasswordChange(args []string) {
 	force, _ := ActiveForce()
 	records, err := force.Query(fmt.Sprintf("select Id From User Where UserName = '%s'", args[0]), false)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	} else {
 		fmt.Println(args[1:])
 		newPass := make(map[string]string)
 		newPass["NewPassword"] = args[1]
-		_, err, emessages := force.ChangePassword(records.Records[0]["Id"].(string), newPass)
+		_, err, _ := force.ChangePassword(records.Records[0]["Id"].(string), newPass)
 		if err != nil {
-			util.ErrorAndExit(err.Error(), emessages[0].ErrorCode)
+			exitWithError(err)
 		} else {
 			fmt.Println("\nPassword changed\n ")
 		}
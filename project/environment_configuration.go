@@ -1,9 +1,14 @@
 package project
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"os"
+	"os/exec"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 // EnvironmentMatch can be specified as the `match` value in an environment stanza in
@@ -63,9 +68,8 @@ type EnvironmentsConfigJSON struct {
 func (project *project) GetEnvironmentConfigForActiveEnvironment(activeUsername string, activeInstanceURI string) (foundEnvironment *EnvironmentConfigJSON, err error) {
 	if environmentJSON, present := project.EnumerateContents()["environments.json"]; present {
 		// now, we want to implement our interpolation regime!
-		environmentConfig := EnvironmentsConfigJSON{}
-		if err = json.Unmarshal(environmentJSON, &environmentConfig); err != nil {
-			err = fmt.Errorf("Problem parsing environments.json at offset %v: %s", err.(*json.SyntaxError).Offset, err.Error())
+		var environmentConfig EnvironmentsConfigJSON
+		if environmentConfig, err = parseEnvironmentsConfig(environmentJSON); err != nil {
 			return
 		}
 
@@ -106,3 +110,82 @@ func (project *project) GetEnvironmentConfigForActiveEnvironment(activeUsername
 	}
 	return
 }
+
+// GetEnvironmentConfig retrieves an environment configuration by its name in
+// environments.json, rather than by matching the active login.
+func (project *project) GetEnvironmentConfig(name string) (*EnvironmentConfigJSON, error) {
+	environmentJSON, present := project.EnumerateContents()["environments.json"]
+	if !present {
+		return nil, fmt.Errorf("There is no environments.json in %s", project.LoadedFromPath())
+	}
+	environmentConfig, err := parseEnvironmentsConfig(environmentJSON)
+	if err != nil {
+		return nil, err
+	}
+	env, ok := environmentConfig.Environments[name]
+	if !ok {
+		var names []string
+		for envName := range environmentConfig.Environments {
+			names = append(names, envName)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("No environment named '%s' in your environments.json, it has: %s", name, strings.Join(names, ", "))
+	}
+	env.Name = name
+	return &env, nil
+}
+
+func parseEnvironmentsConfig(environmentJSON []byte) (environmentConfig EnvironmentsConfigJSON, err error) {
+	if err = json.Unmarshal(environmentJSON, &environmentConfig); err != nil {
+		if syntaxError, ok := err.(*json.SyntaxError); ok {
+			err = fmt.Errorf("Problem parsing environments.json at offset %v: %s", syntaxError.Offset, err.Error())
+		} else {
+			err = fmt.Errorf("Problem parsing environments.json: %s", err.Error())
+		}
+	}
+	return
+}
+
+// VariableValues returns the values of the environment's vars, running the
+// `exec` commands of those that have them.
+func (env *EnvironmentConfigJSON) VariableValues() (values map[string]string, err error) {
+	values = make(map[string]string)
+	for placeholder, jsonValue := range env.Variables {
+		if values[placeholder], err = variableValue(placeholder, jsonValue); err != nil {
+			return nil, err
+		}
+	}
+	return
+}
+
+// variableValue gives the value of a var, which is either a string or an object
+// with an `exec` command whose output is the value.
+func variableValue(placeholder string, jsonValue json.RawMessage) (replacementValue string, err error) {
+	// now, we need to handle the json.RawMessage:
+	replacementCommand := ReplacementValueAsCommand{}
+	if err = json.Unmarshal(jsonValue, &replacementCommand); err != nil {
+		// wasn't valid as a ReplacementValueAsCommand, so either there's a JSON syntax
+		// error (or is syntax guaranteed clean by this point?) or the user did not specify
+		// the exec object and just wants a regular string replacement.
+		if err = json.Unmarshal(jsonValue, &replacementValue); err != nil {
+			err = fmt.Errorf("Unable to grok replacement argument specified to `args` in your environment. %s", err.Error())
+		}
+		return
+	}
+	if len(replacementCommand.CommandToExecute) <= 1 {
+		err = fmt.Errorf("Invalid configuration: if you want to specify a command to execute with `exec`, you must actually specify a command!")
+		return
+	}
+	// user specified a replacment command.  time to execute it!
+	command := exec.Command(replacementCommand.CommandToExecute[0], replacementCommand.CommandToExecute[1:]...)
+	var out bytes.Buffer
+	command.Stdout = &out
+	if err = command.Run(); err != nil {
+		commandStyledAsShell := strings.Join(replacementCommand.CommandToExecute, " ")
+		err = fmt.Errorf("Unable to run the command `%s`, because: %s", commandStyledAsShell, err.Error())
+		return
+	}
+	replacementValue = strings.TrimSpace(out.String())
+	fmt.Fprintf(os.Stderr, "Dynamic arg: $%s -> `%s`\n", placeholder, replacementValue)
+	return
+}
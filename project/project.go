@@ -1,36 +1,64 @@
 package project
 
 import (
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"os/user"
 	"path/filepath"
 	"strings"
 
+	"github.com/joist-engineering/force/salesforce"
 	"github.com/joist-engineering/force/util"
 )
 
 type project struct {
 	path string
 
+	// Set when the project is in the Salesforce DX source format rather than the
+	// Metadata API format.
+	sourceProject *sourceProjectJSON
+
 	// Lazily loaded project contents.
 	// file path -> file contents
 	lazyProjectContents *map[string][]byte
 }
 
+// sourceProjectJSON is the subset of sfdx-project.json we care about.
+type sourceProjectJSON struct {
+	PackageDirectories []struct {
+		Path string `json:"path"`
+	} `json:"packageDirectories"`
+	SourceApiVersion string `json:"sourceApiVersion"`
+}
+
 // LoadProject loads the entire project and its config data in from the filesystem,
-// but note that it does so lazily.
+// but note that it does so lazily.  The directory may either contain a package.xml,
+// or be the root of a Salesforce DX project with an sfdx-project.json.
 func LoadProject(directory string) *project {
 	newProject := project{
 		path: determineProjectPath(directory),
 	}
+
+	if data, err := ioutil.ReadFile(filepath.Join(newProject.path, salesforce.SourceProjectFile)); err == nil {
+		newProject.sourceProject = &sourceProjectJSON{}
+		if err := json.Unmarshal(data, newProject.sourceProject); err != nil {
+			util.ErrorAndExit("Problem parsing %s: %s", salesforce.SourceProjectFile, err.Error())
+		}
+		if len(newProject.sourceProject.PackageDirectories) == 0 {
+			util.ErrorAndExit("No packageDirectories specified in %s", salesforce.SourceProjectFile)
+		}
+	}
 	return &newProject
 }
 
+// IsSourceFormat reports whether the project is laid out in the Salesforce DX
+// source format.
+func (project *project) IsSourceFormat() bool {
+	return project.sourceProject != nil
+}
+
 func determineProjectPath(directory string) string {
 	wd, _ := os.Getwd()
 	usr, err := user.Current()
@@ -58,6 +86,9 @@ func determineProjectPath(directory string) string {
 		root = dir
 	}
 
+	if _, err := os.Stat(filepath.Join(root, salesforce.SourceProjectFile)); err == nil {
+		return root
+	}
 	if _, err := os.Stat(filepath.Join(root, "package.xml")); os.IsNotExist(err) {
 		util.ErrorAndExit(" \n" + filepath.Join(root, "package.xml") + "\ndoes not exist")
 	}
@@ -75,53 +106,16 @@ func (project *project) LoadedFromPath() string {
 func (project *project) ContentsWithInternalTransformsApplied(environmentConfig *EnvironmentConfigJSON) map[string][]byte {
 	transformedContents := project.EnumerateContents()
 
-	// in order to prevent unnecessary re-execution of any external `exec` command vars, we'll
-	// memorize them in this map: (var name -> replacementValue computed with the `exec` callout)
-	commandReplacementValues := make(map[string]string)
+	replacementValues, err := environmentConfig.VariableValues()
+	if err != nil {
+		util.ErrorAndExit(err.Error())
+	}
 
 	// first transform: string interpolation of the vars in the config:
 	for name, contents := range transformedContents {
 		contentsUnderProcessing := string(contents)
-		for placeholder, jsonValue := range environmentConfig.Variables {
+		for placeholder, replacementValue := range replacementValues {
 			token := fmt.Sprintf("$%s", placeholder)
-
-			var replacementValue string
-
-			// now, we need to handle the json.RawMessage:
-			replacementCommand := ReplacementValueAsCommand{}
-			err := json.Unmarshal(jsonValue, &replacementCommand)
-			if err != nil {
-				// wasn't valid as a ReplacementValueAsCommand, so either there's a JSON syntax
-				// error (or is syntax guaranteed clean by this point?) or the user did not specify
-				// the exec object and just wants a regular string replacement.
-				err := json.Unmarshal(jsonValue, &replacementValue)
-				if err != nil {
-					util.ErrorAndExit("Unable to grok replacement argument specified to `args` in your environment.", err.Error())
-				}
-			} else {
-				if len(replacementCommand.CommandToExecute) > 1 {
-					// user specified a replacment command.  time to execute it!
-
-					if alreadyComputedReplacementValue, ok := commandReplacementValues[placeholder]; ok {
-						replacementValue = alreadyComputedReplacementValue
-					} else {
-						command := exec.Command(replacementCommand.CommandToExecute[0], replacementCommand.CommandToExecute[1:]...)
-						var out bytes.Buffer
-						command.Stdout = &out
-						err := command.Run()
-						if err != nil {
-							commandStyledAsShell := strings.Join(replacementCommand.CommandToExecute, " ")
-							util.ErrorAndExit("Unable to run the command `%s`, because: %s", commandStyledAsShell, err.Error())
-						}
-						replacementValue = strings.TrimSpace(out.String())
-						commandReplacementValues[placeholder] = replacementValue
-						fmt.Printf("Dynamic arg: %s -> `%s`\n", token, replacementValue)
-					}
-				} else {
-					util.ErrorAndExit("Invalid configuration: if you want to specify a command to execute with `exec`, you must actually specify a command!")
-				}
-			}
-
 			contentsUnderProcessing = strings.Replace(contentsUnderProcessing, token, replacementValue, -1)
 		}
 		// it's safe to replace the value in the map!
@@ -142,22 +136,11 @@ func (project *project) EnumerateContents() (enumeratedContents map[string][]byt
 
 	// compute and memoize as needed:
 	if project.lazyProjectContents == nil {
-		files := make(map[string][]byte)
-
-		err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
-			if f.Mode().IsRegular() {
-				if f.Name() != ".DS_Store" {
-					data, err := ioutil.ReadFile(path)
-					if err != nil {
-						util.ErrorAndExit(err.Error())
-					}
-					files[strings.Replace(path, fmt.Sprintf("%s%s", root, string(os.PathSeparator)), "", -1)] = data
-				}
-			}
-			return nil
-		})
-		if err != nil {
-			util.ErrorAndExit(err.Error())
+		var files map[string][]byte
+		if project.IsSourceFormat() {
+			files = project.convertSourceContents()
+		} else {
+			files = ReadDirectoryContents(root)
 		}
 
 		project.lazyProjectContents = &files
@@ -171,3 +154,56 @@ func (project *project) EnumerateContents() (enumeratedContents map[string][]byt
 	}
 	return
 }
+
+// convertSourceContents reads in every package directory of a source format
+// project and converts them to the Metadata API format.
+func (project *project) convertSourceContents() map[string][]byte {
+	apiVersion := project.sourceProject.SourceApiVersion
+	if apiVersion == "" {
+		apiVersion = salesforce.DefaultApiVersion
+	}
+
+	sourceFiles := make(salesforce.ForceMetadataFiles)
+	for _, packageDirectory := range project.sourceProject.PackageDirectories {
+		for name, data := range ReadDirectoryContents(filepath.Join(project.path, packageDirectory.Path)) {
+			sourceFiles[filepath.Join(packageDirectory.Path, name)] = data
+		}
+	}
+
+	files, err := salesforce.ConvertSourceToMetadata(sourceFiles, apiVersion)
+	if err != nil {
+		util.ErrorAndExit(err.Error())
+	}
+
+	// the per-project configuration lives alongside sfdx-project.json.
+	if data, err := ioutil.ReadFile(filepath.Join(project.path, "environments.json")); err == nil {
+		files["environments.json"] = data
+	}
+	return files
+}
+
+// ReadDirectoryContents reads every file below a directory into memory, keyed
+// by path relative to the directory.
+func ReadDirectoryContents(root string) map[string][]byte {
+	files := make(map[string][]byte)
+
+	err := filepath.Walk(root, func(path string, f os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if f.Mode().IsRegular() {
+			if f.Name() != ".DS_Store" {
+				data, err := ioutil.ReadFile(path)
+				if err != nil {
+					util.ErrorAndExit(err.Error())
+				}
+				files[strings.Replace(path, fmt.Sprintf("%s%s", root, string(os.PathSeparator)), "", -1)] = data
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		util.ErrorAndExit(err.Error())
+	}
+	return files
+}
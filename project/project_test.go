@@ -1,6 +1,12 @@
 package project_test
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joist-engineering/force/project"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -11,3 +17,88 @@ func TestProject(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Project Module Suite")
 }
+
+var _ = Describe("Project", func() {
+	Describe("LoadProject", func() {
+		var tempDir string
+
+		BeforeEach(func() {
+			tempDir, _ = ioutil.TempDir("", "project-test")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tempDir)
+		})
+
+		Context("with a source format project", func() {
+			BeforeEach(func() {
+				classes := filepath.Join(tempDir, "force-app", "main", "default", "classes")
+				os.MkdirAll(classes, 0755)
+				ioutil.WriteFile(filepath.Join(tempDir, "sfdx-project.json"), []byte(`{"packageDirectories": [{"path": "force-app", "default": true}], "sourceApiVersion": "45.0"}`), 0644)
+				ioutil.WriteFile(filepath.Join(tempDir, "environments.json"), []byte(`{"environments": {}}`), 0644)
+				ioutil.WriteFile(filepath.Join(classes, "Foo.cls"), []byte("public class Foo {}"), 0644)
+				ioutil.WriteFile(filepath.Join(classes, "Foo.cls-meta.xml"), []byte("<ApexClass/>"), 0644)
+			})
+
+			It("should be loaded as source format", func() {
+				Expect(project.LoadProject(tempDir).IsSourceFormat()).To(BeTrue())
+			})
+
+			It("should convert the contents to the Metadata API format", func() {
+				contents := project.LoadProject(tempDir).EnumerateContents()
+				Expect(contents).To(HaveKey("classes/Foo.cls"))
+				Expect(contents).To(HaveKey("classes/Foo.cls-meta.xml"))
+				Expect(string(contents["package.xml"])).To(ContainSubstring("<version>45.0</version>"))
+			})
+
+			It("should pick up the project configuration from the project root", func() {
+				contents := project.LoadProject(tempDir).EnumerateContents()
+				Expect(contents).To(HaveKey("environments.json"))
+			})
+		})
+
+		Context("with a metadata project", func() {
+			BeforeEach(func() {
+				os.MkdirAll(filepath.Join(tempDir, "classes"), 0755)
+				ioutil.WriteFile(filepath.Join(tempDir, "package.xml"), []byte("<Package/>"), 0644)
+				ioutil.WriteFile(filepath.Join(tempDir, "classes", "Foo.cls"), []byte("public class Foo {}"), 0644)
+			})
+
+			It("should load the contents as they are", func() {
+				loaded := project.LoadProject(tempDir)
+				Expect(loaded.IsSourceFormat()).To(BeFalse())
+				Expect(loaded.EnumerateContents()).To(Equal(map[string][]byte{
+					"package.xml":     []byte("<Package/>"),
+					"classes/Foo.cls": []byte("public class Foo {}"),
+				}))
+			})
+		})
+
+		Context("with environments", func() {
+			BeforeEach(func() {
+				ioutil.WriteFile(filepath.Join(tempDir, "package.xml"), []byte("<Package/>"), 0644)
+				ioutil.WriteFile(filepath.Join(tempDir, "environments.json"), []byte(`{"environments": {
+					"uat": {"match": {"login": "@example.com.uat$"}, "vars": {
+						"OWNER_ID": "005000000000001",
+						"GIT_VERSION": {"exec": ["echo", "abc123"]}
+					}},
+					"production": {"match": {"login": "@example.com$"}}
+				}}`), 0644)
+			})
+
+			It("should find an environment by name, with the values of its vars", func() {
+				env, err := project.LoadProject(tempDir).GetEnvironmentConfig("uat")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(env.Name).To(Equal("uat"))
+				values, err := env.VariableValues()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(values).To(Equal(map[string]string{"OWNER_ID": "005000000000001", "GIT_VERSION": "abc123"}))
+			})
+
+			It("should list the environments there are when one isn't found", func() {
+				_, err := project.LoadProject(tempDir).GetEnvironmentConfig("staging")
+				Expect(err).To(MatchError("No environment named 'staging' in your environments.json, it has: production, uat"))
+			})
+		})
+	})
+})
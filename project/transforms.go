@@ -7,16 +7,21 @@ import (
 	"strings"
 
 	"github.com/joist-engineering/force/salesforce"
-	"github.com/joist-engineering/force/util"
 )
 
 // IsNewFlowVersionsOnlyTransformRequired lets consumer code (`import` command)
 // know if use of the TransformDeployToIncludeNewFlowVersionsOnly is indicated.
 // This is useful because the transform is expensive because it requires
 // fetching metadata from the target environment beforehand.
-func IsNewFlowVersionsOnlyTransformRequired(sourceMetadata map[string][]byte) (transformRequired bool) {
-	flowDefinitions := salesforce.EnumerateMetadataByType(sourceMetadata, "FlowDefinition", "flowDefinitions", "flowDefinition", "")
-	flowVersions := salesforce.EnumerateMetadataByType(sourceMetadata, "Flow", "flows", "flow", "")
+func IsNewFlowVersionsOnlyTransformRequired(sourceMetadata map[string][]byte) (transformRequired bool, err error) {
+	flowDefinitions, err := salesforce.EnumerateMetadataByType(sourceMetadata, "FlowDefinition", "flowDefinitions", "flowDefinition", "")
+	if err != nil {
+		return
+	}
+	flowVersions, err := salesforce.EnumerateMetadataByType(sourceMetadata, "Flow", "flows", "flow", "")
+	if err != nil {
+		return
+	}
 	transformRequired = len(flowDefinitions.Members) > 0 || len(flowVersions.Members) > 0
 	return
 }
@@ -30,7 +35,7 @@ func IsNewFlowVersionsOnlyTransformRequired(sourceMetadata map[string][]byte) (t
 // using external change management tools.  This transform works around this by
 // determining which versions have already been deployed and removes them from
 // the package.
-func TransformDeployToIncludeNewFlowVersionsOnly(sourceMetadata map[string][]byte, targetCurrentMetadata map[string][]byte) (transformedSourceMetadata map[string][]byte) {
+func TransformDeployToIncludeNewFlowVersionsOnly(sourceMetadata map[string][]byte, targetCurrentMetadata map[string][]byte) (transformedSourceMetadata map[string][]byte, err error) {
 	// make a copy of the sourceMetadata so that we can return it without
 	// modifying the source at all.
 	transformedSourceMetadata = make(map[string][]byte)
@@ -55,10 +60,13 @@ func TransformDeployToIncludeNewFlowVersionsOnly(sourceMetadata map[string][]byt
 		InactiveFlows   map[string]MetadataFlowDefinitionState
 	}
 
-	determineEnvironmentState := func(metadataFiles salesforce.ForceMetadataFiles, environmentName string) EnvironmentFlowState {
-		flowDefinitions := salesforce.EnumerateMetadataByType(metadataFiles, "FlowDefinition", "flowDefinitions", "flowDefinition", "")
+	determineEnvironmentState := func(metadataFiles salesforce.ForceMetadataFiles, environmentName string) (state EnvironmentFlowState, err error) {
+		flowDefinitions, err := salesforce.EnumerateMetadataByType(metadataFiles, "FlowDefinition", "flowDefinitions", "flowDefinition", "")
+		if err != nil {
+			return
+		}
 
-		state := EnvironmentFlowState{
+		state = EnvironmentFlowState{
 			EnvironmentName: environmentName,
 			ActiveFlows:     make(map[string]MetadataFlowDefinitionState),
 			InactiveFlows:   make(map[string]MetadataFlowDefinitionState),
@@ -67,8 +75,8 @@ func TransformDeployToIncludeNewFlowVersionsOnly(sourceMetadata map[string][]byt
 		for _, item := range flowDefinitions.Members {
 			var res salesforce.FlowDefinition
 
-			if err := xml.Unmarshal(item.Content, &res); err != nil {
-				util.ErrorAndExit(err.Error())
+			if err = xml.Unmarshal(item.Content, &res); err != nil {
+				return state, fmt.Errorf("%s: %w", item.CompletePath, err)
 			}
 
 			if res.ActiveVersionNumber != 0 {
@@ -87,7 +95,10 @@ func TransformDeployToIncludeNewFlowVersionsOnly(sourceMetadata map[string][]byt
 		}
 
 		// now, enumerate the flows themselves and index them in:
-		flowVersions := salesforce.EnumerateMetadataByType(metadataFiles, "Flow", "flows", "flow", "")
+		flowVersions, err := salesforce.EnumerateMetadataByType(metadataFiles, "Flow", "flows", "flow", "")
+		if err != nil {
+			return
+		}
 		for _, version := range flowVersions.Members {
 
 			// the version number is indicated by a normalized naming convention in the entries rendered by the
@@ -98,7 +109,7 @@ func TransformDeployToIncludeNewFlowVersionsOnly(sourceMetadata map[string][]byt
 			name := nameFragments[0]
 			versionNumber, err := strconv.ParseUint(nameFragments[len(nameFragments)-1], 10, 64)
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				return state, fmt.Errorf("%s: %w", version.CompletePath, err)
 			}
 
 			if flowDefinition, present := state.InactiveFlows[name]; present {
@@ -118,12 +129,18 @@ func TransformDeployToIncludeNewFlowVersionsOnly(sourceMetadata map[string][]byt
 			}
 		}
 
-		return state
+		return
 	}
 
-	targetState := determineEnvironmentState(targetCurrentMetadata, "target")
+	targetState, err := determineEnvironmentState(targetCurrentMetadata, "target")
+	if err != nil {
+		return nil, err
+	}
 
-	sourceState := determineEnvironmentState(sourceMetadata, "source")
+	sourceState, err := determineEnvironmentState(sourceMetadata, "source")
+	if err != nil {
+		return nil, err
+	}
 
 	// now, index the state of the flows we just determined, by using their full path names.
 	// this allows us to use them to filter the transformedSourceMetadata itself.
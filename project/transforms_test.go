@@ -35,10 +35,11 @@ var _ = Describe("Project transforms", func() {
 				"flows/MyAwesomeFlow-1.flow": []byte(""),
 			}
 
-			transformedMetadata := project.TransformDeployToIncludeNewFlowVersionsOnly(
+			transformedMetadata, err := project.TransformDeployToIncludeNewFlowVersionsOnly(
 				sourceMetadata,
 				targetExistingMetadata,
 			)
+			Ω(err).ShouldNot(HaveOccurred())
 
 			Ω(transformedMetadata).Should(HaveKey("flowDefinitions/MyAwesomeFlow.flowDefinition"))
 			Ω(transformedMetadata).ShouldNot(HaveKey("flows/MyAwesomeFlow-2.flow"))
@@ -59,10 +60,11 @@ var _ = Describe("Project transforms", func() {
 
 		targetExistingMetadata := map[string][]byte{}
 
-		transformedMetadata := project.TransformDeployToIncludeNewFlowVersionsOnly(
+		transformedMetadata, err := project.TransformDeployToIncludeNewFlowVersionsOnly(
 			sourceMetadata,
 			targetExistingMetadata,
 		)
+		Ω(err).ShouldNot(HaveOccurred())
 
 		Ω(transformedMetadata).Should(HaveKey("flowDefinitions/MyAwesomeFlow.flowDefinition"))
 		Ω(transformedMetadata).Should(HaveKey("flows/MyAwesomeFlow-2.flow"))
@@ -32,6 +32,7 @@ Examples:
   force push -f metadata/classes/MyClass.cls
   force push -checkonly -test MyClass_Test metadata/classes/MyClass.cls
   force push -n MyApex -n MyObject__c
+  force push -f force-app/main/default/lwc/myComponent/myComponent.js
 
 Deployment Options
   -rollbackonerror, -r    Indicates whether any failure causes a complete rollback
@@ -102,9 +103,10 @@ func runPush(cmd *Command, args []string) {
 		if len(resourcepath) != 0 {
 			// It's not a package but does have a path. This could be a path to a file
 			// or to a folder. If it is a folder, we pickup the resources a different
-			// way than if it's a file.
-			validatePushByMetadataTypeCommand()
+			// way than if it's a file.  Paths alone don't need a metadata
+			// directory, so that files in Salesforce DX projects can be pushed.
 			if len(metadataType) != 0 {
+				validatePushByMetadataTypeCommand()
 				pushByTypeAndPath()
 			} else {
 				pushByPathOnly()
@@ -137,7 +139,7 @@ func pushByTypeAndPath() {
 	for _, name := range resourcepath {
 		fi, err := os.Stat(name)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		}
 		if fi.IsDir() {
 
@@ -190,7 +192,7 @@ func wildCardSearch(metaFolder string, name string) []string {
 	cmd.Stdout = &out
 	err := cmd.Run()
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	f := strings.Split(out.String(), "\n")
 	var ret []string
@@ -391,11 +393,11 @@ func zipResource(path string, topLevelFolder string) {
 				}
 				fl, err := zipper.Create(filepath.Join(topLevelFolder, strings.Replace(path, startPath, "", -1)))
 				if err != nil {
-					util.ErrorAndExit(err.Error())
+					exitWithError(err)
 				}
 				_, err = fl.Write([]byte(file))
 				if err != nil {
-					util.ErrorAndExit(err.Error())
+					exitWithError(err)
 				}
 			}
 		}
@@ -456,7 +458,7 @@ func pushByName() {
 		return nil
 	})
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	if len(paths) == 0 {
 		util.ErrorAndExit("Could not find %#v ", metadataName)
@@ -474,8 +476,10 @@ func pushByPath(fpath []string) {
 func pushByPaths(fpaths []string) {
 	force, err := ActiveForce()
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
+	// if the org can't be asked, the static table of metadata types will do.
+	force.Metadata.LoadMetapaths()
 	pb := salesforce.NewPushBuilder(force.Credentials.ApiVersion)
 
 	var badPaths []string
@@ -541,7 +545,7 @@ func deployOpts() *salesforce.ForceDeployOptions {
 // Process and display the result of the push operation
 func processDeployResults(result salesforce.ForceCheckDeploymentStatusResult, err error) {
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	problems := result.Details.ComponentFailures
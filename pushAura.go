@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -79,11 +80,11 @@ func pushAuraComponent(fname string) {
 	if _, err := os.Stat(filepath.Join(filepath.Dir(fname), ".manifest")); os.IsNotExist(err) {
 		// No manifest, but is in aurabundle folder, assume creating a new bundle with this file
 		// as the first artifact.
-		createNewAuraBundleAndDefinition(*force, fname)
+		createNewAuraBundleAndDefinition(force, fname)
 	} else {
 		// Got the manifest, let's update the artifact
 		fmt.Println("Updating")
-		updateAuraDefinition(*force, fname)
+		updateAuraDefinition(force, fname)
 		return
 	}
 }
@@ -98,7 +99,7 @@ func isValidAuraExtension(fname string) bool {
 	return false
 }
 
-func createNewAuraBundleAndDefinition(force salesforce.Force, fname string) {
+func createNewAuraBundleAndDefinition(force *salesforce.Force, fname string) {
 	// 	Creating a new bundle. We need
 	// 		the name of the bundle (parent folder of file)
 	//		the type of artifact (based on naming convention)
@@ -113,20 +114,21 @@ func createNewAuraBundleAndDefinition(force salesforce.Force, fname string) {
 		_, _ = getFormatByresourcepath(fname)
 		targetDirectory, mdbase = SetTargetDirectory(fname)
 		// Create a bundle defintion
-		bundle, err, emessages := force.CreateAuraBundle(bundleName)
+		bundle, err, _ := force.CreateAuraBundle(bundleName)
 		if err != nil {
-			if emessages[0].ErrorCode == "DUPLICATE_VALUE" {
+			var apiError *salesforce.APIError
+			if errors.As(err, &apiError) && apiError.ErrorCode == "DUPLICATE_VALUE" {
 				// Should look up the bundle and get it's id then update it.
 				FetchManifest(bundleName)
 				updateAuraDefinition(force, fname)
 				return
 			}
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		} else {
 			manifest.Id = bundle.Id
-			component, err, emessages := createBundleEntity(manifest, force, fname)
+			component, err, _ := createBundleEntity(manifest, force, fname)
 			if err != nil {
-				util.ErrorAndExit(err.Error(), emessages[0].ErrorCode)
+				exitWithError(err)
 			}
 			createManifest(manifest, component, fname)
 		}
@@ -148,7 +150,7 @@ func SetTargetDirectory(fname string) (dir string, base string) {
 	return
 }
 
-func createBundleEntity(manifest salesforce.BundleManifest, force salesforce.Force, fname string) (component salesforce.ForceCreateRecordResult, err error, emessages []salesforce.ForceError) {
+func createBundleEntity(manifest salesforce.BundleManifest, force *salesforce.Force, fname string) (component salesforce.ForceCreateRecordResult, err error, emessages []salesforce.ForceError) {
 	// create the bundle entity
 	format, deftype := getFormatByresourcepath(fname)
 	mbody, _ := readFile(fname)
@@ -192,7 +194,7 @@ func GetManifest(fname string) (manifest salesforce.BundleManifest, err error) {
 	return
 }
 
-func updateAuraDefinition(force salesforce.Force, fname string) {
+func updateAuraDefinition(force *salesforce.Force, fname string) {
 
 	//Get the manifest
 	manifest, err := GetManifest(fname)
@@ -204,15 +206,15 @@ func updateAuraDefinition(force salesforce.Force, fname string) {
 			mbody, _ := readFile(fname)
 			err := force.UpdateAuraComponent(map[string]string{"source": mbody}, component.ComponentId)
 			if err != nil {
-				util.ErrorAndExit(err.Error())
+				exitWithError(err)
 			}
 			fmt.Printf("Aura definition updated: %s\n", filepath.Base(fname))
 			return
 		}
 	}
-	component, err, emessages := createBundleEntity(manifest, force, fname)
+	component, err, _ := createBundleEntity(manifest, force, fname)
 	if err != nil {
-		util.ErrorAndExit(err.Error(), emessages[0].ErrorCode)
+		exitWithError(err)
 	}
 	updateManifest(manifest, component, fname)
 	fmt.Println("New component in the bundle")
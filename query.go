@@ -1,63 +1,259 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/signal"
 	"strings"
 
+	"github.com/joist-engineering/force/project"
+	"github.com/joist-engineering/force/salesforce"
 	"github.com/joist-engineering/force/util"
 )
 
 var cmdQuery = &Command{
 	Run:   runQuery,
-	Usage: "query <soql statement> [output format]",
+	Usage: "query [-format <format>] [-tooling | -all] [-children rows|json] [-o <file> [-resume]] [-p <name>=<value>]... [-env <environment>] [-explain] (-f <file> | <soql statement>)",
 	Short: "Execute a SOQL statement",
 	Long: `
 Execute a SOQL statement
 
+Results are written as they arrive, a page at a time.
+
+Options:
+
+  -format <format>   console (the default), csv, tsv, json, json-pretty,
+                     ndjson, markdown or html
+  -tooling, -t       Query the Tooling API
+  -all               Include deleted and archived records; select IsDeleted
+                     to tell them apart
+  -children <how>    Write subquery results as JSON in one column (json,
+                     the default), or as a row for each child record (rows)
+  -o <file>          Write the results to a file, in the format its
+                     extension is for unless -format is given, saving a
+                     checkpoint after each page
+  -resume            Carry on writing to the file from where an interrupted
+                     run got to, with its query and options
+  -f <file>          Read the statement from a file, such as report.soql
+  -p <name>=<value>  The value for a :name binding in the statement; give
+                     name:type=value for a number, boolean, date, datetime
+                     or string that might be taken for something else
+  -env <environment> Take values for bindings from the vars of an
+                     environment in the project's environments.json
+  -explain           Show how Salesforce would run the statement, rather
+                     than running it: the plans it considered, cheapest
+                     first, and why it couldn't use indexes.  A relative
+                     cost above 1 is slower than a full table scan.  Plans
+                     are written as a table, or with -format, as json or
+                     json-pretty
+
+Bindings are replaced with SOQL literals: dates such as 2026-01-01 and
+datetimes such as 2026-01-01T09:00:00Z as they are, and anything else as a
+quoted and escaped string.  After IN, INCLUDES or EXCLUDES, a value is a
+comma separated list.
+
+Columns are in the order of the SELECT, and CSV follows RFC 4180.
+
 Examples:
 
   force query "select Id, Name, Account.Name From Contact"
 
-  force query "select Id, Name, Account.Name From Contact" --format:csv
+  force query -format csv "select Id, Name, Account.Name From Contact"
+
+  force query -format csv -children rows "select Name, (select LastName From Contacts) From Account"
 
   force query "select Id, Name From Account Where MailingState IN ('CA', 'NY')"
+
+  force query -all "select Id, Name, IsDeleted From Account Where IsDeleted = true"
+
+  force query -o accounts.csv "select Id, Name From Account"
+
+  force query -o accounts.csv -resume
+
+  force query -f report.soql -p start=2026-01-01 -p ownerIds=005A,005B
+
+  force query -env uat "select Id From Account Where OwnerId = :integrationUser"
+
+  force query -explain "select Id From Account Where Name = 'Acme'"
 `,
 }
 
+var (
+	queryFormat   string
+	queryTooling  bool
+	queryAll      bool
+	queryChildren string
+	queryOutput   string
+	queryResume   bool
+	queryFile     string
+	queryParams   soqlParams
+	queryEnv      string
+	queryExplain  bool
+)
+
+func init() {
+	cmdQuery.Flag.StringVar(&queryFormat, "format", "", "output format")
+	cmdQuery.Flag.BoolVar(&queryTooling, "tooling", false, "query the Tooling API")
+	cmdQuery.Flag.BoolVar(&queryTooling, "t", false, "query the Tooling API")
+	cmdQuery.Flag.BoolVar(&queryAll, "all", false, "include deleted and archived records")
+	cmdQuery.Flag.StringVar(&queryChildren, "children", "json", "write subquery results as json or rows")
+	cmdQuery.Flag.StringVar(&queryOutput, "o", "", "file to write the results to")
+	cmdQuery.Flag.BoolVar(&queryResume, "resume", false, "carry on writing an interrupted query to the -o file")
+	cmdQuery.Flag.StringVar(&queryFile, "f", "", "file to read the statement from")
+	cmdQuery.Flag.Var(&queryParams, "p", "value for a :name binding, as name=value")
+	cmdQuery.Flag.StringVar(&queryEnv, "env", "", "environment in environments.json to take binding values from")
+	cmdQuery.Flag.BoolVar(&queryExplain, "explain", false, "show the query plans rather than running the query")
+}
+
 func runQuery(cmd *Command, args []string) {
-	force, _ := ActiveForce()
-	if len(args) < 1 {
+	if len(args) < 1 && !queryResume && queryFile == "" {
 		cmd.printUsage()
-	} else {
-		format := "console"
-		var formatArg = ""
-		var isTooling = false
-		var formatIndex = 1
-		if len(args) == 2 {
-			formatArg = args[len(args)-formatIndex]
-		} else if len(args) == 3 {
-			formatIndex = 2
-			formatArg = args[len(args)-formatIndex]
-			isTooling = true
-		}
+		return
+	}
+	if queryResume && queryOutput == "" {
+		util.ErrorAndExit("-resume needs the -o file to carry on writing")
+	}
+	format, isTooling := queryFormat, queryTooling
+	args, legacyFormat, legacyTooling := legacyQueryFormat(args)
+	if legacyFormat != "" {
+		format, isTooling = legacyFormat, isTooling || legacyTooling
+	}
+	if queryAll && isTooling {
+		util.ErrorAndExit("-all can't be used with the Tooling API")
+	}
+	if queryChildren != "json" && queryChildren != "rows" {
+		util.ErrorAndExit("-children must be json or rows")
+	}
 
-		if strings.Contains(formatArg, "format:") {
-			args = args[:len(args)-formatIndex]
-			format = strings.SplitN(formatArg, ":", 2)[1]
+	soql, err := querySoql(args)
+	if err != nil {
+		exitWithError(err)
+	}
+	force, _ := ActiveForce()
+	if queryExplain {
+		if isTooling || queryAll || queryOutput != "" {
+			util.ErrorAndExit("-explain can't be used with -tooling, -all or -o")
 		}
+		if format == "" {
+			format = "console"
+		}
+		explanation, err := force.ExplainQuery(soql)
+		if err != nil {
+			exitWithError(err)
+		}
+		if err = writeQueryExplanation(os.Stdout, explanation, format); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
 
-		soql := strings.Join(args, " ")
+	if queryOutput != "" {
+		checkpoint := queryCheckpoint{Query: soql, Tooling: isTooling, All: queryAll, Format: format, Children: queryChildren}
+		if queryResume {
+			// Only the options given are checked against the run resumed.
+			if !isFlagSet(cmd, "children") {
+				checkpoint.Children = ""
+			}
+		} else if checkpoint.Format == "" {
+			checkpoint.Format = fileFormat(queryOutput)
+		}
+		if err := queryToFile(ctx, force, queryOutput, checkpoint, queryResume); err != nil {
+			exitWithError(err)
+		}
+		return
+	}
 
-		records, err := force.Query(fmt.Sprintf("%s", soql), isTooling)
+	if format == "" {
+		format = "console"
+	}
+	out, err := newRecordWriter(os.Stdout, format, parseSoqlFields(soql), queryChildren == "rows")
+	if err != nil {
+		exitWithError(err)
+	}
+	if err = writeQuery(queryIterator(ctx, force, soql, isTooling, queryAll), out); err != nil {
+		exitWithError(err)
+	}
+}
 
+// querySoql returns the statement from the arguments or the -f file, with the
+// values of the -p parameters and the -env environment's vars bound.
+func querySoql(args []string) (soql string, err error) {
+	soql = strings.Join(args, " ")
+	if queryFile != "" {
+		if len(args) > 0 {
+			return "", errors.New("give the statement either with -f or as an argument, not both")
+		}
+		data, err := ioutil.ReadFile(queryFile)
 		if err != nil {
-			util.ErrorAndExit(err.Error())
-		} else {
-			if format == "console" {
-				DisplayForceRecords(records)
-			} else {
-				DisplayForceRecordsf(records.Records, format)
-			}
+			return "", err
 		}
+		soql = strings.TrimRight(strings.TrimSpace(string(data)), ";")
+	}
+
+	params := make(soqlParams)
+	if queryEnv != "" {
+		if params, err = environmentParams(queryEnv); err != nil {
+			return
+		}
+	}
+	params.Override(queryParams)
+	return bindSoqlParams(soql, params)
+}
+
+// environmentParams takes the vars of an environment in the project's
+// environments.json, for queries to refer to per-environment record ids.
+func environmentParams(name string) (params soqlParams, err error) {
+	projectDirectory := "metadata"
+	if _, err := os.Stat(salesforce.SourceProjectFile); err == nil {
+		projectDirectory = "."
+	}
+	env, err := project.LoadProject(projectDirectory).GetEnvironmentConfig(name)
+	if err != nil {
+		return
+	}
+	values, err := env.VariableValues()
+	return soqlParams(values), err
+}
+
+// legacyQueryFormat takes the format from the arguments in the way force used
+// to before -format, as in force query "SELECT ..." --format:csv, where a
+// third argument meant the Tooling API.
+func legacyQueryFormat(args []string) (soqlArgs []string, format string, isTooling bool) {
+	formatIndex := len(args) - 1
+	if len(args) == 3 {
+		formatIndex = 1
+		isTooling = true
+	}
+	if len(args) < 2 || len(args) > 3 || !strings.Contains(args[formatIndex], "format:") {
+		return args, "", false
+	}
+	format = strings.SplitN(args[formatIndex], ":", 2)[1]
+	soqlArgs = append(soqlArgs, args[:formatIndex]...)
+	return
+}
+
+// queryIterator starts a query of the Tooling API, or with all, one that
+// includes deleted and archived records.
+func queryIterator(ctx context.Context, force *salesforce.Force, soql string, isTooling, all bool) *salesforce.QueryIterator {
+	if all {
+		return force.NewQueryAllIterator(ctx, soql)
+	}
+	return force.NewQueryIterator(ctx, soql, isTooling)
+}
+
+// writeQuery writes the records of a query a page at a time, as they arrive.
+func writeQuery(it *salesforce.QueryIterator, out recordWriter) (err error) {
+	for it.NextPage() {
+		if err = out.WritePage(it.Records()); err != nil {
+			return
+		}
+	}
+	if err = it.Err(); err != nil {
+		return
 	}
+	return out.Close()
 }
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joist-engineering/force/salesforce"
+)
+
+// queryCheckpoint records how far a query being written to a file has got,
+// so that an interrupted run can carry on from there with -resume.
+type queryCheckpoint struct {
+	Query    string
+	Tooling  bool
+	All      bool
+	Format   string
+	Children string
+	// NextRecordsUrl is the page after the last one written, or "" if every
+	// page was written.
+	NextRecordsUrl string
+	Rows           int
+	// Bytes is the size of the file after the last page written; anything
+	// after it is from a page that wasn't finished.
+	Bytes int64
+}
+
+// fileFormats are the formats to write files in, by extension.
+var fileFormats = map[string]string{
+	".csv":    "csv",
+	".tsv":    "tsv",
+	".json":   "json",
+	".ndjson": "ndjson",
+	".jsonl":  "ndjson",
+	".md":     "markdown",
+	".html":   "html",
+	".htm":    "html",
+}
+
+// fileFormat returns the format to write a file in if none is given, from its
+// extension.
+func fileFormat(path string) string {
+	if format, ok := fileFormats[strings.ToLower(filepath.Ext(path))]; ok {
+		return format
+	}
+	return "csv"
+}
+
+func checkpointPath(path string) string {
+	return path + ".checkpoint"
+}
+
+func loadQueryCheckpoint(path string) (checkpoint queryCheckpoint, err error) {
+	data, err := ioutil.ReadFile(checkpointPath(path))
+	if os.IsNotExist(err) {
+		return checkpoint, fmt.Errorf("nothing to resume, %s was not found", checkpointPath(path))
+	}
+	if err != nil {
+		return
+	}
+	err = json.Unmarshal(data, &checkpoint)
+	return
+}
+
+// saveQueryCheckpoint replaces the checkpoint in one step, so that it is never
+// left half written.
+func saveQueryCheckpoint(path string, checkpoint queryCheckpoint) (err error) {
+	data, err := json.MarshalIndent(checkpoint, "", "  ")
+	if err != nil {
+		return
+	}
+	tmp := checkpointPath(path) + ".tmp"
+	if err = ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return
+	}
+	return os.Rename(tmp, checkpointPath(path))
+}
+
+// countingWriter counts the bytes written to a file.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (n int, err error) {
+	n, err = c.w.Write(p)
+	c.n += int64(n)
+	return
+}
+
+// resumes checks that the options given to carry on a run, those that are
+// set, are the ones it was started with.
+func (c queryCheckpoint) resumes(saved queryCheckpoint) error {
+	switch {
+	case c.Query != "" && c.Query != saved.Query:
+		return errors.New("the query is not the one being resumed: " + saved.Query)
+	case c.Format != "" && c.Format != saved.Format:
+		return errors.New("the format is not the one being resumed: " + saved.Format)
+	case c.Children != "" && c.Children != saved.Children:
+		return errors.New("-children is not what is being resumed: " + saved.Children)
+	case c.Tooling && !saved.Tooling:
+		return errors.New("the query being resumed is not of the Tooling API")
+	case c.All && !saved.All:
+		return errors.New("the query being resumed doesn't include deleted and archived records")
+	}
+	return nil
+}
+
+// queryToFile writes the results of a query to a file a page at a time,
+// saving a checkpoint after each page.  With resume, it carries on from the
+// checkpoint of an earlier run, whose query, format and options it uses;
+// any that are given must be the same.
+func queryToFile(ctx context.Context, force *salesforce.Force, path string, checkpoint queryCheckpoint, resume bool) (err error) {
+	if resume {
+		saved, err := loadQueryCheckpoint(path)
+		if err != nil {
+			return err
+		}
+		if err = checkpoint.resumes(saved); err != nil {
+			return err
+		}
+		checkpoint = saved
+	}
+
+	// The file is opened only once the format is known to be one that can
+	// be written to it, so that a bad -format doesn't truncate it.
+	counter := &countingWriter{n: checkpoint.Bytes}
+	buffered := bufio.NewWriter(counter)
+	out, err := newRecordWriter(buffered, checkpoint.Format, parseSoqlFields(checkpoint.Query), checkpoint.Children == "rows")
+	if err != nil {
+		return
+	}
+	resumable, ok := out.(resumableRecordWriter)
+	if !ok {
+		return fmt.Errorf("can't write the %s format to a file", checkpoint.Format)
+	}
+
+	var file *os.File
+	var it *salesforce.QueryIterator
+	if resume {
+		if file, err = os.OpenFile(path, os.O_WRONLY, 0); err != nil {
+			return
+		}
+		defer file.Close()
+		if err = file.Truncate(checkpoint.Bytes); err != nil {
+			return
+		}
+		if _, err = file.Seek(checkpoint.Bytes, io.SeekStart); err != nil {
+			return
+		}
+		resumable.Resume(checkpoint.Rows)
+		it = force.ResumeQuery(ctx, checkpoint.NextRecordsUrl)
+	} else {
+		if file, err = os.Create(path); err != nil {
+			return
+		}
+		defer file.Close()
+		// A checkpoint left by an earlier run is for the file just emptied.
+		if err = os.Remove(checkpointPath(path)); err != nil && !os.IsNotExist(err) {
+			return
+		}
+		it = queryIterator(ctx, force, checkpoint.Query, checkpoint.Tooling, checkpoint.All)
+	}
+	counter.w = file
+
+	for it.NextPage() {
+		if err = out.WritePage(it.Records()); err != nil {
+			return
+		}
+		if err = buffered.Flush(); err != nil {
+			return
+		}
+		if err = file.Sync(); err != nil {
+			return
+		}
+		checkpoint.Rows += len(it.Records())
+		checkpoint.Bytes = counter.n
+		checkpoint.NextRecordsUrl = it.NextRecordsUrl()
+		if err = saveQueryCheckpoint(path, checkpoint); err != nil {
+			return
+		}
+		fmt.Printf("\r%d of %d records", checkpoint.Rows, it.TotalSize())
+	}
+	if err = it.Err(); err != nil {
+		fmt.Println()
+		// Until a page has been written, there is no checkpoint to resume.
+		if _, statErr := os.Stat(checkpointPath(path)); statErr != nil {
+			return err
+		}
+		return fmt.Errorf("%w; %d records were written to %s, use -resume to carry on", err, checkpoint.Rows, path)
+	}
+	if err = out.Close(); err != nil {
+		return
+	}
+	if err = buffered.Flush(); err != nil {
+		return
+	}
+	fmt.Printf("\rWrote %d records to %s\n", checkpoint.Rows, path)
+	return os.Remove(checkpointPath(path))
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+// pagedServer serves three pages of two accounts, failing the last page until
+// failing is cleared.
+func pagedServer(failing *bool) http.Handler {
+	page := func(w http.ResponseWriter, n int, next string) {
+		if n == 3 && *failing {
+			w.WriteHeader(503)
+			w.Write([]byte(`[{"message":"Server unavailable","errorCode":"SERVER_UNAVAILABLE"}]`))
+			return
+		}
+		fmt.Fprintf(w, `{"totalSize":6,"done":%t,"nextRecordsUrl":"%s","records":[`+
+			`{"attributes":{"type":"Account"},"Id":"001-%d-a","Name":"Account %d, A"},`+
+			`{"attributes":{"type":"Account"},"Id":"001-%d-b","Name":"Account %d, B"}]}`, next == "", next, n, n, n, n)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services/data/v45.0/query", func(w http.ResponseWriter, r *http.Request) {
+		page(w, 1, "/services/data/v45.0/query/01g-2")
+	})
+	mux.HandleFunc("/services/data/v45.0/query/01g-2", func(w http.ResponseWriter, r *http.Request) {
+		page(w, 2, "/services/data/v45.0/query/01g-4")
+	})
+	mux.HandleFunc("/services/data/v45.0/query/01g-4", func(w http.ResponseWriter, r *http.Request) {
+		page(w, 3, "")
+	})
+	return mux
+}
+
+func TestQueryToFileResumes(t *testing.T) {
+	failing := true
+	force, stop := serveOrg(pagedServer(&failing))
+	defer stop()
+
+	dir, _ := ioutil.TempDir("", "queryfile-test")
+	defer os.RemoveAll(dir)
+
+	for _, format := range []string{"csv", "json"} {
+		path := filepath.Join(dir, "accounts."+format)
+		soql := "SELECT Id, Name FROM Account"
+		failing = true
+
+		err := queryToFile(context.Background(), force, path, queryCheckpoint{Query: soql, Format: format}, false)
+		assert.NotEqual(t, nil, err)
+		assert.T(t, strings.Contains(err.Error(), "use -resume"), err.Error())
+		checkpoint, err := loadQueryCheckpoint(path)
+		assert.Equal(t, nil, err)
+		assert.Equal(t, 4, checkpoint.Rows)
+		assert.Equal(t, "/services/data/v45.0/query/01g-4", checkpoint.NextRecordsUrl)
+
+		// A page that was being written when the run stopped is dropped.
+		f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+		f.WriteString("half a page")
+		f.Close()
+
+		err = queryToFile(context.Background(), force, path, queryCheckpoint{Query: "SELECT Name FROM Contact"}, true)
+		assert.NotEqual(t, nil, err)
+
+		failing = false
+		assert.Equal(t, nil, queryToFile(context.Background(), force, path, queryCheckpoint{}, true))
+		_, err = os.Stat(checkpointPath(path))
+		assert.T(t, os.IsNotExist(err))
+
+		data, _ := ioutil.ReadFile(path)
+		var expected strings.Builder
+		out, _ := newRecordWriter(&expected, format, parseSoqlFields(soql), false)
+		result, _ := force.Query(soql, false)
+		out.WritePage(result.Records)
+		out.Close()
+		assert.Equal(t, expected.String(), string(data))
+	}
+}
+
+func TestQueryToFileFailingOnTheFirstPage(t *testing.T) {
+	force, stop := serveOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+		w.Write([]byte(`[{"message":"Server unavailable","errorCode":"SERVER_UNAVAILABLE"}]`))
+	}))
+	defer stop()
+
+	dir, _ := ioutil.TempDir("", "queryfile-test")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "accounts.csv")
+	// A checkpoint of an earlier run is no use once the file is rewritten.
+	saveQueryCheckpoint(path, queryCheckpoint{Query: "SELECT Id FROM Contact", Rows: 2})
+
+	err := queryToFile(context.Background(), force, path, queryCheckpoint{Query: "SELECT Id FROM Account", Format: "csv"}, false)
+	assert.NotEqual(t, nil, err)
+	assert.T(t, !strings.Contains(err.Error(), "-resume"), err.Error())
+	_, err = os.Stat(checkpointPath(path))
+	assert.T(t, os.IsNotExist(err))
+}
+
+func TestQueryToFileKeepsTheFileForAnUnwritableFormat(t *testing.T) {
+	dir, _ := ioutil.TempDir("", "queryfile-test")
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "existing.txt")
+	ioutil.WriteFile(path, []byte("keep me"), 0600)
+
+	err := queryToFile(context.Background(), nil, path, queryCheckpoint{Query: "SELECT Id FROM Account", Format: "console"}, false)
+	assert.Equal(t, "can't write the console format to a file", err.Error())
+	err = queryToFile(context.Background(), nil, path, queryCheckpoint{}, true)
+	assert.NotEqual(t, nil, err)
+	data, _ := ioutil.ReadFile(path)
+	assert.Equal(t, "keep me", string(data))
+}
+
+func TestQueryCheckpointResumes(t *testing.T) {
+	saved := queryCheckpoint{Query: "SELECT Id FROM Account", Format: "csv", Children: "json"}
+	assert.Equal(t, nil, queryCheckpoint{}.resumes(saved))
+	assert.Equal(t, nil, queryCheckpoint{Query: saved.Query, Format: "csv"}.resumes(saved))
+	for checkpoint, message := range map[queryCheckpoint]string{
+		{Format: "json"}:    "the format is not the one being resumed: csv",
+		{Children: "rows"}:  "-children is not what is being resumed: json",
+		{Tooling: true}:     "the query being resumed is not of the Tooling API",
+		{All: true}:         "the query being resumed doesn't include deleted and archived records",
+		{Query: "SELECT 1"}: "the query is not the one being resumed: SELECT Id FROM Account",
+	} {
+		assert.Equal(t, message, checkpoint.resumes(saved).Error())
+	}
+}
+
+func TestFileFormat(t *testing.T) {
+	assert.Equal(t, "csv", fileFormat("accounts.csv"))
+	assert.Equal(t, "ndjson", fileFormat("accounts.JSONL"))
+	assert.Equal(t, "markdown", fileFormat("accounts.md"))
+	assert.Equal(t, "csv", fileFormat("accounts"))
+}
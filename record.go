@@ -2,6 +2,7 @@ package main
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/joist-engineering/force/salesforce"
 	"github.com/joist-engineering/force/util"
@@ -28,8 +29,12 @@ Usage:
 
   force record update <object> <extid>:<value> [<fields>]
 
+  force record upsert <object> <extid>:<value> [<fields>]
+
   force record delete <object> <id>
 
+  force record import <file>
+
 Examples:
 
   force record get User 00Ei0000000000
@@ -42,7 +47,25 @@ Examples:
 
   force record update User username:user@name.org State:GA
 
+  force record upsert Account External_Id__c:A-1001 Name:Acme
+
   force record delete User 00Ei0000000000
+
+  force record import accounts.json
+
+Import creates records, and the child records under them, in one
+transaction, from a file in the format of the sObject Tree API:
+
+  {"records": [{
+    "attributes": {"type": "Account", "referenceId": "acme"},
+    "Name": "Acme",
+    "Contacts": {"records": [{
+      "attributes": {"type": "Contact", "referenceId": "wile"},
+      "LastName": "Coyote"}]}}]}
+
+Records can refer to others in the file with @{referenceId}, such as
+"ReportsToId": "@{wile}".  The id of each record created is listed by its
+referenceId.
 `,
 }
 
@@ -63,8 +86,12 @@ func runRecord(cmd *Command, args []string) {
 			}
 		case "update":
 			runRecordUpdate(args[1:])
+		case "upsert":
+			runRecordUpsert(args[1:])
 		case "delete", "remove":
 			runRecordDelete(args[1:])
+		case "import":
+			runRecordImport(args[1:])
 		default:
 			util.ErrorAndExit("no such command: %s", args[0])
 		}
@@ -78,7 +105,7 @@ func runRecordGet(args []string) {
 	force, _ := ActiveForce()
 	object, err := force.GetRecord(args[0], args[1])
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	} else {
 		DisplayForceRecord(object)
 	}
@@ -89,10 +116,10 @@ func runRecordCreate(args []string) {
 		util.ErrorAndExit("must specify object")
 	}
 	force, _ := ActiveForce()
-	attrs := salesforce.ParseArgumentAttrs(args[1:])
-	id, err, emessages := force.CreateRecord(args[0], attrs)
+	attrs := recordAttrs(force, args[0], args[1:])
+	id, err, _ := force.CreateRecordValues(args[0], attrs)
 	if err != nil {
-		util.ErrorAndExit(err.Error(), emessages[0].ErrorCode)
+		exitWithError(err)
 	}
 	fmt.Printf("Record created: %s\n", id)
 }
@@ -102,14 +129,43 @@ func runRecordUpdate(args []string) {
 		util.ErrorAndExit("must specify object and id")
 	}
 	force, _ := ActiveForce()
-	attrs := salesforce.ParseArgumentAttrs(args[2:])
-	err := force.UpdateRecord(args[0], args[1], attrs)
+	attrs := recordAttrs(force, args[0], args[2:])
+	err := force.UpdateRecordValues(args[0], args[1], attrs)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Println("Record updated")
 }
 
+func runRecordUpsert(args []string) {
+	if len(args) < 2 {
+		util.ErrorAndExit("must specify object and external id")
+	}
+	split := strings.SplitN(args[1], ":", 2)
+	if len(split) != 2 || split[0] == "" {
+		util.ErrorAndExit("external id must be <field>:<value>, not %s", args[1])
+	}
+	force, _ := ActiveForce()
+	attrs := recordAttrs(force, args[0], args[2:])
+	result, err := force.UpsertRecord(args[0], split[0], split[1], attrs)
+	if err != nil {
+		exitWithError(err)
+	}
+	fmt.Println(upsertMessage(result))
+}
+
+// upsertMessage says whether an upsert created or updated its record.
+func upsertMessage(result salesforce.ForceUpsertRecordResult) string {
+	message := "Record updated"
+	if result.Created {
+		message = "Record created"
+	}
+	if result.Id != "" {
+		message += ": " + result.Id
+	}
+	return message
+}
+
 func runRecordDelete(args []string) {
 	if len(args) != 2 {
 		util.ErrorAndExit("must specify object and id")
@@ -117,7 +173,7 @@ func runRecordDelete(args []string) {
 	force, _ := ActiveForce()
 	err := force.DeleteRecord(args[0], args[1])
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Println("Record deleted")
 }
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/joist-engineering/force/salesforce"
+)
+
+func TestUpsertMessage(t *testing.T) {
+	assert.Equal(t, "Record created: 001A", upsertMessage(salesforce.ForceUpsertRecordResult{Id: "001A", Created: true}))
+	assert.Equal(t, "Record updated: 001A", upsertMessage(salesforce.ForceUpsertRecordResult{Id: "001A"}))
+	assert.Equal(t, "Record updated", upsertMessage(salesforce.ForceUpsertRecordResult{}))
+}
+
+func TestWriteBatchResults(t *testing.T) {
+	var out strings.Builder
+	writeBatchResults(&out, salesforce.BatchResult{Results: []salesforce.Result{
+		{Id: "001A", Success: true, Created: true},
+		{Id: "001B", Success: true},
+		{Message: "DUPLICATE_VALUE"},
+	}})
+	assert.Equal(t, `Row  Id    Result  Error
+1    001A  created
+2    001B  updated
+3          failed  DUPLICATE_VALUE
+`, out.String())
+}
+
+func TestTypedFieldValues(t *testing.T) {
+	defer withTempHome(t)()
+	force := &fakeDescriber{}
+	describes := newFakeDescribeCache(force)
+
+	attrs, err := typedFieldValues(describes, "Account", []string{
+		"Name:Acme",
+		"isactive__c:false",
+		"NumberOfEmployees:250",
+		"AnnualRevenue:1250000.50",
+		"Founded__c:1999-04-01",
+		"LastReviewed__c:2026-01-01T09:00:00Z",
+		"Regions__c:EMEA, APAC",
+		"OwnerId:null",
+		"Rating:Hot",
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]interface{}{
+		"Name":              "Acme",
+		"isactive__c":       false,
+		"NumberOfEmployees": int64(250),
+		"AnnualRevenue":     1250000.5,
+		"Founded__c":        "1999-04-01",
+		"LastReviewed__c":   "2026-01-01T09:00:00Z",
+		"Regions__c":        "EMEA;APAC",
+		"OwnerId":           nil,
+		"Rating":            "Hot",
+	}, attrs)
+	// Rating is unknown, so the describe was fetched again, but only once.
+	assert.Equal(t, 2, force.calls)
+
+	// A field added since the describe was cached is found by refreshing it.
+	describes.describes["account"] = sobjectDescribe{Name: "Account"}
+	attrs, err = typedFieldValues(describes, "Account", []string{"IsActive__c:true", "NumberOfEmployees:3"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]interface{}{"IsActive__c": true, "NumberOfEmployees": int64(3)}, attrs)
+	assert.Equal(t, 3, force.calls)
+
+	// An sObject that can't be described has its values sent as strings.
+	attrs, err = typedFieldValues(describes, "Lead", []string{"NumberOfEmployees:3"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, map[string]interface{}{"NumberOfEmployees": "3"}, attrs)
+	assert.Equal(t, 4, force.calls)
+
+	for pair, message := range map[string]string{
+		"IsActive__c:yes":           `IsActive__c is a field of type boolean: "yes" must be true or false, or null`,
+		"NumberOfEmployees:2.5":     `NumberOfEmployees is a field of type int: "2.5" must be a whole number, or null`,
+		"AnnualRevenue:lots":        `AnnualRevenue is a field of type currency: "lots" must be a number, or null`,
+		"Founded__c:01/04/1999":     `Founded__c is a field of type date: "01/04/1999" must be a date, such as 2026-01-01, or null`,
+		"LastReviewed__c:yesterday": `LastReviewed__c is a field of type datetime: "yesterday" must be a datetime, such as 2026-01-01T09:00:00Z, or null`,
+		"Name":                      "fields must be given as Field:value, not Name",
+	} {
+		_, err = typedFieldValues(describes, "Account", []string{pair})
+		assert.Equal(t, message, err.Error())
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joist-engineering/force/salesforce"
+)
+
+// recordAttrs converts the Field:value arguments of record create, update
+// and upsert to the JSON types of the sObject's fields.
+func recordAttrs(force *salesforce.Force, sobject string, pairs []string) map[string]interface{} {
+	attrs, err := typedFieldValues(newDescribeCache(force), sobject, pairs)
+	if err != nil {
+		exitWithError(err)
+	}
+	return attrs
+}
+
+// typedFieldValues parses Field:value pairs, giving each value the type of
+// its field, as the describe of the sObject has it: true or false for
+// checkboxes, numbers for numbers, and dates and datetimes checked as such.
+// The values of multi-select picklists can be separated with commas or
+// semicolons, and Field:null clears a field of any type.  A field the cached
+// describe doesn't know has the describe fetched again, in case it is new;
+// fields still unknown, or all of them if the sObject can't be described,
+// are sent as strings, for Salesforce to report on.
+func typedFieldValues(describes *describeCache, sobject string, pairs []string) (attrs map[string]interface{}, err error) {
+	attrs = make(map[string]interface{})
+	if len(pairs) == 0 {
+		return
+	}
+	describe, err := describes.Describe(sobject)
+	refreshed := err != nil
+	for _, pair := range pairs {
+		split := strings.SplitN(pair, ":", 2)
+		if len(split) != 2 || split[0] == "" {
+			return nil, fmt.Errorf("fields must be given as Field:value, not %s", pair)
+		}
+		name, value := split[0], split[1]
+		field, ok := describe.Field(name)
+		if !ok && !refreshed {
+			refreshed = true
+			describes.Forget(sobject)
+			if fresh, err := describes.Describe(sobject); err == nil {
+				describe = fresh
+				field, ok = describe.Field(name)
+			}
+		}
+		if !ok {
+			attrs[name] = value
+			continue
+		}
+		if attrs[name], err = typedFieldValue(field, value); err != nil {
+			return nil, err
+		}
+	}
+	return attrs, nil
+}
+
+func typedFieldValue(field describeField, value string) (interface{}, error) {
+	if value == "null" {
+		return nil, nil
+	}
+	invalid := func(want string) error {
+		return fmt.Errorf("%s is a field of type %s: %q must be %s, or null", field.Name, field.Type, value, want)
+	}
+	switch field.Type {
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, invalid("true or false")
+		}
+		return b, nil
+	case "int", "long":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, invalid("a whole number")
+		}
+		return n, nil
+	case "double", "currency", "percent":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return nil, invalid("a number")
+		}
+		return f, nil
+	case "date":
+		if !isSoqlDate(value) {
+			return nil, invalid("a date, such as 2026-01-01")
+		}
+	case "datetime":
+		if !isSoqlDateTime(value) {
+			if _, err := time.Parse("2006-01-02T15:04:05.000Z0700", value); err != nil {
+				return nil, invalid("a datetime, such as 2026-01-01T09:00:00Z")
+			}
+		}
+	case "multipicklist":
+		values := strings.FieldsFunc(value, func(r rune) bool { return r == ';' || r == ',' })
+		for i := range values {
+			values[i] = strings.TrimSpace(values[i])
+		}
+		return strings.Join(values, ";"), nil
+	}
+	return value, nil
+}
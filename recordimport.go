@@ -0,0 +1,356 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/joist-engineering/force/salesforce"
+	"github.com/joist-engineering/force/util"
+)
+
+const (
+	// maxTreeRecords is how many records SObjectTree creates in one call.
+	maxTreeRecords = 200
+	// maxCompositeRequests is how many requests Composite makes in one call.
+	maxCompositeRequests = 25
+)
+
+// recordReference matches the references to other records of a tree file,
+// @{referenceId} or @{referenceId.field}.
+var recordReference = regexp.MustCompile(`@\{([A-Za-z0-9_]+)(\.[A-Za-z0-9_]+)?\}`)
+
+// importRecord is a record of a tree file, taken out from under its parent.
+type importRecord struct {
+	sobject     string
+	referenceId string
+	fields      map[string]interface{}
+	// needs are the reference ids of the records it refers to.
+	needs []string
+}
+
+// importedRecord is a record created by an import.
+type importedRecord struct {
+	ReferenceId string
+	Type        string
+	Id          string
+}
+
+func runRecordImport(args []string) {
+	if len(args) != 1 {
+		util.ErrorAndExit("must specify a file of records")
+	}
+	data, err := ioutil.ReadFile(args[0])
+	if err != nil {
+		exitWithError(err)
+	}
+	records, err := parseRecordTree(data)
+	if err != nil {
+		util.ErrorAndExit("%s: %s", args[0], err)
+	}
+	force, _ := ActiveForce()
+	imported, err := importRecordTree(force, newDescribeCache(force), records)
+	if err != nil {
+		exitWithError(err)
+	}
+	writeImportedRecords(os.Stdout, imported)
+}
+
+// parseRecordTree reads the records of a file in the format of the sObject
+// Tree API, giving a referenceId to any record without one.
+func parseRecordTree(data []byte) (records []salesforce.ForceRecord, err error) {
+	var tree struct {
+		Records []salesforce.ForceRecord `json:"records"`
+	}
+	if err = json.Unmarshal(data, &tree); err != nil {
+		return
+	}
+	if len(tree.Records) == 0 {
+		return nil, fmt.Errorf("no records to import")
+	}
+	seen := make(map[string]bool)
+	counts := make(map[string]int)
+	err = walkRecordTree(tree.Records, func(record salesforce.ForceRecord) error {
+		attributes, _ := record["attributes"].(map[string]interface{})
+		sobject, _ := attributes["type"].(string)
+		if sobject == "" {
+			return fmt.Errorf("every record needs attributes with its type")
+		}
+		referenceId, _ := attributes["referenceId"].(string)
+		if referenceId == "" {
+			for referenceId == "" || seen[referenceId] {
+				counts[sobject]++
+				referenceId = fmt.Sprintf("%sRef%d", sobject, counts[sobject])
+			}
+			attributes["referenceId"] = referenceId
+		} else if seen[referenceId] {
+			return fmt.Errorf("more than one record has the referenceId %s", referenceId)
+		}
+		seen[referenceId] = true
+		return nil
+	})
+	return tree.Records, err
+}
+
+// walkRecordTree calls fn for each record, before the records under it,
+// which are taken relationship by relationship in order of name.
+func walkRecordTree(records []salesforce.ForceRecord, fn func(salesforce.ForceRecord) error) error {
+	for _, record := range records {
+		if err := fn(record); err != nil {
+			return err
+		}
+		var names []string
+		for name := range record {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if children, ok := childRecords(record[name]); ok {
+				if err := walkRecordTree(children, fn); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// childRecords returns the records under a relationship of a record, as in
+// "Contacts": {"records": [...]}.
+func childRecords(value interface{}) (records []salesforce.ForceRecord, ok bool) {
+	relationship, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	list, ok := relationship["records"].([]interface{})
+	if !ok {
+		return
+	}
+	for _, item := range list {
+		if record, isRecord := item.(map[string]interface{}); isRecord {
+			records = append(records, salesforce.ForceRecord(record))
+		}
+	}
+	return
+}
+
+func recordAttribute(record salesforce.ForceRecord, name string) string {
+	attributes, _ := record["attributes"].(map[string]interface{})
+	value, _ := attributes[name].(string)
+	return value
+}
+
+// importRecordTree creates the records of a tree in one transaction.  When
+// they are all of one sObject and don't refer to each other, besides through
+// being children of their parents, that's a call to SObjectTree; otherwise
+// they are flattened into the requests of a call to Composite.
+func importRecordTree(force *salesforce.Force, describes *describeCache, records []salesforce.ForceRecord) (imported []importedRecord, err error) {
+	if sobject, ok := treeImportable(records); ok {
+		result, err := force.SObjectTree(sobject, records)
+		if err != nil {
+			return nil, err
+		}
+		types := make(map[string]string)
+		walkRecordTree(records, func(record salesforce.ForceRecord) error {
+			types[recordAttribute(record, "referenceId")] = recordAttribute(record, "type")
+			return nil
+		})
+		for _, result := range result.Results {
+			imported = append(imported, importedRecord{result.ReferenceId, types[result.ReferenceId], result.Id})
+		}
+		return imported, nil
+	}
+
+	flattened, err := flattenRecordTree(records, describes)
+	if err != nil {
+		return
+	}
+	if len(flattened) > maxCompositeRequests {
+		return nil, fmt.Errorf("%d records can't be created in one transaction: records that refer to each other, or are of more than one sObject, can be at most %d", len(flattened), maxCompositeRequests)
+	}
+	if flattened, err = orderImportRecords(flattened); err != nil {
+		return
+	}
+	var requests []salesforce.CompositeRequest
+	for _, record := range flattened {
+		requests = append(requests, salesforce.CompositeRequest{
+			Method:      "POST",
+			Url:         force.SObjectsUrl(record.sobject),
+			ReferenceId: record.referenceId,
+			Body:        record.fields,
+		})
+	}
+	responses, err := force.Composite(requests, true)
+	if err != nil {
+		return
+	}
+	for i, response := range responses {
+		var result salesforce.CompositeCreateResult
+		if err = json.Unmarshal(response.Body, &result); err != nil {
+			return
+		}
+		imported = append(imported, importedRecord{response.ReferenceId, flattened[i].sobject, result.Id})
+	}
+	return
+}
+
+// treeImportable returns the sObject of the records, if SObjectTree can
+// create them all.
+func treeImportable(records []salesforce.ForceRecord) (sobject string, ok bool) {
+	count := 0
+	references := false
+	walkRecordTree(records, func(record salesforce.ForceRecord) error {
+		count++
+		for _, value := range record {
+			if s, isString := value.(string); isString && recordReference.MatchString(s) {
+				references = true
+			}
+		}
+		return nil
+	})
+	if references || count > maxTreeRecords {
+		return "", false
+	}
+	sobject = recordAttribute(records[0], "type")
+	for _, record := range records[1:] {
+		if recordAttribute(record, "type") != sobject {
+			return "", false
+		}
+	}
+	return sobject, true
+}
+
+// flattenRecordTree takes the child records out from under their parents,
+// setting the lookup of each to its parent's id with a reference, as in
+// "AccountId": "@{acme.id}".  References to a record, @{acme}, are to its id.
+func flattenRecordTree(records []salesforce.ForceRecord, describes *describeCache) (flattened []importRecord, err error) {
+	var flatten func(records []salesforce.ForceRecord, parent, lookup string) error
+	flatten = func(records []salesforce.ForceRecord, parent, lookup string) error {
+		for _, record := range records {
+			flat := importRecord{
+				sobject:     recordAttribute(record, "type"),
+				referenceId: recordAttribute(record, "referenceId"),
+				fields:      make(map[string]interface{}),
+			}
+			if parent != "" {
+				flat.fields[lookup] = "@{" + parent + ".id}"
+				flat.needs = append(flat.needs, parent)
+			}
+			type relationship struct {
+				name    string
+				records []salesforce.ForceRecord
+			}
+			var relationships []relationship
+			for name, value := range record {
+				if name == "attributes" {
+					continue
+				}
+				if children, ok := childRecords(value); ok {
+					relationships = append(relationships, relationship{name, children})
+					continue
+				}
+				if s, ok := value.(string); ok {
+					value = recordReference.ReplaceAllStringFunc(s, func(reference string) string {
+						match := recordReference.FindStringSubmatch(reference)
+						flat.needs = append(flat.needs, match[1])
+						if match[2] == "" {
+							return "@{" + match[1] + ".id}"
+						}
+						return reference
+					})
+				}
+				flat.fields[name] = value
+			}
+			flattened = append(flattened, flat)
+			sort.Slice(relationships, func(i, j int) bool { return relationships[i].name < relationships[j].name })
+
+			for _, relationship := range relationships {
+				describe, err := describes.Describe(flat.sobject)
+				if err != nil {
+					return err
+				}
+				child, ok := describe.ChildRelationship(relationship.name)
+				if !ok || child.Field == "" {
+					// Describes cached before the child's field was kept, or
+					// before the relationship was added, are fetched again.
+					describes.Forget(flat.sobject)
+					if describe, err = describes.Describe(flat.sobject); err != nil {
+						return err
+					}
+					child, ok = describe.ChildRelationship(relationship.name)
+				}
+				if !ok || child.Field == "" {
+					return fmt.Errorf("%s has no child relationship %s", flat.sobject, relationship.name)
+				}
+				if err := flatten(relationship.records, flat.referenceId, child.Field); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	err = flatten(records, "", "")
+	return
+}
+
+// orderImportRecords puts each record after those it refers to, keeping the
+// order of the file where it can.
+func orderImportRecords(records []importRecord) (ordered []importRecord, err error) {
+	known := make(map[string]bool)
+	for _, record := range records {
+		known[record.referenceId] = true
+	}
+	for _, record := range records {
+		for _, need := range record.needs {
+			if !known[need] {
+				return nil, fmt.Errorf("%s refers to @{%s}, but no record has that referenceId", record.referenceId, need)
+			}
+		}
+	}
+
+	done := make(map[string]bool)
+	for len(ordered) < len(records) {
+		progressed := false
+		for _, record := range records {
+			if done[record.referenceId] {
+				continue
+			}
+			ready := true
+			for _, need := range record.needs {
+				ready = ready && done[need]
+			}
+			if ready {
+				ordered = append(ordered, record)
+				done[record.referenceId] = true
+				progressed = true
+				break
+			}
+		}
+		if !progressed {
+			var cycle []string
+			for _, record := range records {
+				if !done[record.referenceId] {
+					cycle = append(cycle, record.referenceId)
+				}
+			}
+			return nil, fmt.Errorf("records refer to each other in a loop: %v", cycle)
+		}
+	}
+	return
+}
+
+// writeImportedRecords writes the id of each record created, by reference id.
+func writeImportedRecords(w io.Writer, imported []importedRecord) {
+	table := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(table, "Reference\tType\tId")
+	for _, record := range imported {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", record.ReferenceId, record.Type, record.Id)
+	}
+	table.Flush()
+	fmt.Fprintf(w, "%d records created\n", len(imported))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/joist-engineering/force/salesforce"
+)
+
+const accountTree = `{"records": [{
+	"attributes": {"type": "Account", "referenceId": "acme"},
+	"Name": "Acme",
+	"Contacts": {"records": [
+		{"attributes": {"type": "Contact", "referenceId": "wile"}, "LastName": "Coyote"},
+		{"attributes": {"type": "Contact"}, "LastName": "Runner"}]}}]}`
+
+func TestParseRecordTree(t *testing.T) {
+	records, err := parseRecordTree([]byte(accountTree))
+	assert.Equal(t, nil, err)
+	var refs []string
+	walkRecordTree(records, func(record salesforce.ForceRecord) error {
+		refs = append(refs, recordAttribute(record, "referenceId"))
+		return nil
+	})
+	assert.Equal(t, []string{"acme", "wile", "ContactRef1"}, refs)
+
+	sobject, ok := treeImportable(records)
+	assert.T(t, ok)
+	assert.Equal(t, "Account", sobject)
+
+	_, err = parseRecordTree([]byte(`{"records": [{"Name": "Acme"}]}`))
+	assert.Equal(t, "every record needs attributes with its type", err.Error())
+	_, err = parseRecordTree([]byte(`{"records": [
+		{"attributes": {"type": "Account", "referenceId": "acme"}},
+		{"attributes": {"type": "Account", "referenceId": "acme"}}]}`))
+	assert.Equal(t, "more than one record has the referenceId acme", err.Error())
+}
+
+func TestFlattenRecordTree(t *testing.T) {
+	defer withTempHome(t)()
+	records, _ := parseRecordTree([]byte(`{"records": [
+		{"attributes": {"type": "Contact", "referenceId": "boss"}, "LastName": "Acme", "AccountId": "@{acme}"},
+		{"attributes": {"type": "Account", "referenceId": "acme"}, "Name": "Acme",
+		 "Contacts": {"records": [{"attributes": {"type": "Contact", "referenceId": "wile"}, "LastName": "Coyote", "ReportsToId": "@{boss.id}"}]}}]}`))
+	_, ok := treeImportable(records)
+	assert.T(t, !ok)
+
+	flattened, err := flattenRecordTree(records, newFakeDescribeCache(&fakeDescriber{}))
+	assert.Equal(t, nil, err)
+	flattened, err = orderImportRecords(flattened)
+	assert.Equal(t, nil, err)
+	var refs []string
+	for _, record := range flattened {
+		refs = append(refs, record.referenceId)
+	}
+	assert.Equal(t, []string{"acme", "boss", "wile"}, refs)
+	assert.Equal(t, "@{acme.id}", flattened[1].fields["AccountId"])
+	assert.Equal(t, map[string]interface{}{"LastName": "Coyote", "AccountId": "@{acme.id}", "ReportsToId": "@{boss.id}"}, flattened[2].fields)
+
+	// A describe cached without the child's field is fetched again.
+	force := &fakeDescriber{}
+	describes := newFakeDescribeCache(force)
+	describes.describes["account"] = sobjectDescribe{Name: "Account", ChildRelationships: []childRelationship{{ChildSObject: "Contact", RelationshipName: "Contacts"}}}
+	flattened, err = flattenRecordTree(records, describes)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "@{acme.id}", flattened[2].fields["AccountId"])
+	assert.Equal(t, 1, force.calls)
+
+	_, err = orderImportRecords([]importRecord{{referenceId: "wile", needs: []string{"roadrunner"}}})
+	assert.Equal(t, "wile refers to @{roadrunner}, but no record has that referenceId", err.Error())
+	_, err = orderImportRecords([]importRecord{{referenceId: "a", needs: []string{"b"}}, {referenceId: "b", needs: []string{"a"}}})
+	assert.Equal(t, "records refer to each other in a loop: [a b]", err.Error())
+}
+
+func TestImportRecordTree(t *testing.T) {
+	defer withTempHome(t)()
+	var paths []string
+	var sent struct {
+		CompositeRequest []salesforce.CompositeRequest
+	}
+	force, stop := serveOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		body, _ := ioutil.ReadAll(r.Body)
+		if strings.HasSuffix(r.URL.Path, "/composite") {
+			json.Unmarshal(body, &sent)
+			w.Write([]byte(`{"compositeResponse":[
+				{"body":{"id":"003B","success":true},"httpStatusCode":201,"referenceId":"boss"},
+				{"body":{"id":"003C","success":true},"httpStatusCode":201,"referenceId":"wile"}]}`))
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"hasErrors":false,"results":[{"referenceId":"acme","id":"001A"},{"referenceId":"wile","id":"003A"},{"referenceId":"ContactRef1","id":"003B"}]}`))
+	}))
+	defer stop()
+	describes := newFakeDescribeCache(&fakeDescriber{})
+
+	records, _ := parseRecordTree([]byte(accountTree))
+	imported, err := importRecordTree(force, describes, records)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"/services/data/v45.0/composite/tree/Account"}, paths)
+	var out strings.Builder
+	writeImportedRecords(&out, imported)
+	assert.Equal(t, `Reference    Type     Id
+acme         Account  001A
+wile         Contact  003A
+ContactRef1  Contact  003B
+3 records created
+`, out.String())
+
+	paths = nil
+	records, _ = parseRecordTree([]byte(`{"records": [
+		{"attributes": {"type": "Contact", "referenceId": "wile"}, "LastName": "Coyote", "ReportsToId": "@{boss}"},
+		{"attributes": {"type": "Contact", "referenceId": "boss"}, "LastName": "Acme"}]}`))
+	imported, err = importRecordTree(force, describes, records)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"/services/data/v45.0/composite"}, paths)
+	assert.Equal(t, "boss", sent.CompositeRequest[0].ReferenceId)
+	assert.Equal(t, "/services/data/v45.0/sobjects/Contact", sent.CompositeRequest[1].Url)
+	assert.Equal(t, []importedRecord{{"boss", "Contact", "003B"}, {"wile", "Contact", "003C"}}, imported)
+}
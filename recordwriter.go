@@ -0,0 +1,487 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/joist-engineering/force/salesforce"
+)
+
+// recordFormats are the formats query results can be written in.
+var recordFormats = []string{"console", "csv", "tsv", "json", "json-pretty", "ndjson", "markdown", "html"}
+
+// recordWriter writes query results as they arrive, a page at a time, so that
+// big queries don't have to be held in memory.
+type recordWriter interface {
+	WritePage(records []salesforce.ForceRecord) error
+	// Close finishes the output, such as by closing a JSON array.
+	Close() error
+}
+
+// resumableRecordWriter is a recordWriter that can carry on from the end of
+// the output of an earlier run, which wrote rows records.
+type resumableRecordWriter interface {
+	recordWriter
+	Resume(rows int)
+}
+
+// newRecordWriter makes a writer for a format.  Tables have a column for each
+// of fields, if they are known, and otherwise for each field of the first
+// page's records.  Subquery results are written in a column as JSON, or with
+// childRows, as a row for each child record.
+func newRecordWriter(w io.Writer, format string, fields []soqlField, childRows bool) (out recordWriter, err error) {
+	table := func(t tableFormat) recordWriter {
+		return &tableRecordWriter{table: t, fields: fields, childRows: childRows}
+	}
+	switch format {
+	case "console":
+		out = &consoleRecordWriter{w: w}
+	case "csv":
+		out = table(&csvTable{w: csv.NewWriter(w)})
+	case "tsv":
+		tsv := csv.NewWriter(w)
+		tsv.Comma = '\t'
+		out = table(&csvTable{w: tsv})
+	case "markdown":
+		out = table(&markdownTable{w: w})
+	case "html":
+		out = table(&htmlTable{w: w})
+	case "json":
+		out = &jsonRecordWriter{w: w}
+	case "json-pretty":
+		out = &jsonRecordWriter{w: w, pretty: true}
+	case "ndjson":
+		out = &ndjsonRecordWriter{w: w}
+	default:
+		err = fmt.Errorf("Format %s not supported, use one of %s", format, strings.Join(recordFormats, ", "))
+	}
+	return
+}
+
+// consoleRecordWriter draws a table a page at a time.  Its columns are sized
+// to the first page, and only when a later page has a column the table lacks,
+// or a value too wide for its column, is the header drawn again, wider.
+type consoleRecordWriter struct {
+	w       io.Writer
+	columns []string
+	lengths map[string]int
+	count   int
+}
+
+func (c *consoleRecordWriter) WritePage(records []salesforce.ForceRecord) (err error) {
+	if len(records) == 0 {
+		return
+	}
+	flattened := make([]salesforce.ForceRecord, len(records))
+	for i, record := range records {
+		flattened[i] = flattenForceRecord(record)
+	}
+	var out bytes.Buffer
+	if c.widen(flattened) {
+		out.WriteString(recordHeader(c.columns, c.lengths, "") + "\n")
+		out.WriteString(recordSeparator(c.columns, c.lengths, "") + "\n")
+	}
+	subRows := recordsHaveSubRows(flattened)
+	for _, record := range flattened {
+		out.WriteString(recordRow(record, c.columns, c.lengths, "") + "\n")
+		if subRows {
+			out.WriteString(recordSeparator(c.columns, c.lengths, "") + "\n")
+		}
+	}
+	c.count += len(records)
+	_, err = out.WriteTo(c.w)
+	return
+}
+
+// widen makes room in the table for the records, returning whether that
+// took new columns or wider ones, so that the header must be drawn.
+func (c *consoleRecordWriter) widen(records []salesforce.ForceRecord) (widened bool) {
+	if c.lengths == nil {
+		c.lengths = make(map[string]int)
+		widened = true
+	}
+	for _, column := range recordColumns(records) {
+		if !StringSliceContains(c.columns, column) {
+			c.columns = append(c.columns, column)
+			widened = true
+		}
+	}
+	for key, length := range columnLengths(records, "") {
+		if length > c.lengths[key] {
+			c.lengths[key] = length
+			widened = true
+		}
+	}
+	return
+}
+
+func (c *consoleRecordWriter) Close() (err error) {
+	_, err = fmt.Fprintf(c.w, " (%d records)\n", c.count)
+	return
+}
+
+// tableFormat writes the rows of a table, the first of which is the header.
+type tableFormat interface {
+	WriteRow(values []string) error
+	// Flush is called after each page.
+	Flush() error
+	Close() error
+	// Resume carries on from a table whose header has been written.
+	Resume()
+}
+
+// tableColumn is a field of the flattened records, or with child, a field of
+// the records of the subquery field.
+type tableColumn struct {
+	field string
+	child string
+}
+
+func (c tableColumn) String() string {
+	if c.child != "" {
+		return c.field + "." + c.child
+	}
+	return c.field
+}
+
+type tableRecordWriter struct {
+	table     tableFormat
+	fields    []soqlField
+	childRows bool
+	columns   []tableColumn
+	resumed   bool
+}
+
+func (t *tableRecordWriter) WritePage(records []salesforce.ForceRecord) (err error) {
+	flattened := make([]salesforce.ForceRecord, len(records))
+	for i, record := range records {
+		flattened[i] = flattenForceRecord(record)
+	}
+	if t.columns == nil && len(flattened) > 0 {
+		if t.resumed {
+			t.columns = t.tableColumns(flattened)
+		} else if err = t.writeHeader(flattened); err != nil {
+			return
+		}
+	}
+	for _, record := range flattened {
+		for _, row := range t.rows(record) {
+			if err = t.table.WriteRow(row); err != nil {
+				return
+			}
+		}
+	}
+	return t.table.Flush()
+}
+
+func (t *tableRecordWriter) Resume(rows int) {
+	t.resumed = true
+	t.table.Resume()
+}
+
+func (t *tableRecordWriter) Close() (err error) {
+	if t.columns == nil && t.fields != nil && !t.resumed {
+		if err = t.writeHeader(nil); err != nil {
+			return
+		}
+	}
+	return t.table.Close()
+}
+
+func (t *tableRecordWriter) writeHeader(records []salesforce.ForceRecord) error {
+	t.columns = t.tableColumns(records)
+	header := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		header[i] = column.String()
+	}
+	return t.table.WriteRow(header)
+}
+
+// tableColumns follows the order of the query's fields, with the names of
+// the fields as they are in the records.
+func (t *tableRecordWriter) tableColumns(records []salesforce.ForceRecord) (columns []tableColumn) {
+	if t.fields == nil {
+		var keys []string
+		for _, record := range records {
+			for key := range record {
+				if !StringSliceContains(keys, key) {
+					keys = append(keys, key)
+				}
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			columns = append(columns, tableColumn{field: key})
+		}
+		return
+	}
+	for _, field := range t.fields {
+		name := recordKey(records, field.Name)
+		if len(field.Children) == 0 || !t.childRows {
+			columns = append(columns, tableColumn{field: name})
+			continue
+		}
+		var children []salesforce.ForceRecord
+		for _, record := range records {
+			if subRecords, ok := record[name].([]salesforce.ForceRecord); ok {
+				children = append(children, subRecords...)
+			}
+		}
+		for _, child := range field.Children {
+			columns = append(columns, tableColumn{field: name, child: recordKey(children, child.Name)})
+		}
+	}
+	return
+}
+
+// recordKey returns the key for a field as the records spell it, since SOQL
+// isn't case sensitive.
+func recordKey(records []salesforce.ForceRecord, field string) string {
+	for _, record := range records {
+		for key := range record {
+			if strings.EqualFold(key, field) {
+				return key
+			}
+		}
+	}
+	return field
+}
+
+// recordValue returns the value of a field, whichever way it is spelt, since
+// the columns may have been named before any record had the field.
+func recordValue(record salesforce.ForceRecord, field string) interface{} {
+	if value, ok := record[field]; ok {
+		return value
+	}
+	return record[recordKey([]salesforce.ForceRecord{record}, field)]
+}
+
+// rows returns the rows for a record: just one, or with childRows, one for
+// each child record, with the parent's values repeated.
+func (t *tableRecordWriter) rows(record salesforce.ForceRecord) (rows [][]string) {
+	parent := make([]string, len(t.columns))
+	var subqueries []string
+	for i, column := range t.columns {
+		if column.child == "" {
+			parent[i] = cellValue(recordValue(record, column.field))
+		} else if !StringSliceContains(subqueries, column.field) {
+			subqueries = append(subqueries, column.field)
+		}
+	}
+	for _, subquery := range subqueries {
+		children, _ := recordValue(record, subquery).([]salesforce.ForceRecord)
+		for _, child := range children {
+			row := append([]string(nil), parent...)
+			for i, column := range t.columns {
+				if column.field == subquery && column.child != "" {
+					row[i] = cellValue(recordValue(child, column.child))
+				}
+			}
+			rows = append(rows, row)
+		}
+	}
+	if len(rows) == 0 {
+		rows = append(rows, parent)
+	}
+	return
+}
+
+func cellValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case []salesforce.ForceRecord, map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(value)
+		return string(b)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+type csvTable struct {
+	w *csv.Writer
+}
+
+func (c *csvTable) WriteRow(values []string) error {
+	return c.w.Write(values)
+}
+
+func (c *csvTable) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvTable) Close() error {
+	return c.Flush()
+}
+
+func (c *csvTable) Resume() {}
+
+type markdownTable struct {
+	w    io.Writer
+	rows int
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+
+func (m *markdownTable) WriteRow(values []string) (err error) {
+	cells := make([]string, len(values))
+	for i, value := range values {
+		cells[i] = markdownEscaper.Replace(value)
+	}
+	if _, err = fmt.Fprintf(m.w, "| %s |\n", strings.Join(cells, " | ")); err != nil {
+		return
+	}
+	if m.rows == 0 {
+		_, err = fmt.Fprintf(m.w, "|%s\n", strings.Repeat(" --- |", len(values)))
+	}
+	m.rows++
+	return
+}
+
+func (m *markdownTable) Flush() error {
+	return nil
+}
+
+func (m *markdownTable) Close() error {
+	return nil
+}
+
+func (m *markdownTable) Resume() {
+	m.rows = 1
+}
+
+type htmlTable struct {
+	w    io.Writer
+	rows int
+}
+
+func (h *htmlTable) WriteRow(values []string) (err error) {
+	cell := "td"
+	if h.rows == 0 {
+		cell = "th"
+		if _, err = io.WriteString(h.w, "<table>\n<thead>\n"); err != nil {
+			return
+		}
+	}
+	var row strings.Builder
+	row.WriteString("<tr>")
+	for _, value := range values {
+		fmt.Fprintf(&row, "<%s>%s</%s>", cell, html.EscapeString(value), cell)
+	}
+	row.WriteString("</tr>\n")
+	if h.rows == 0 {
+		row.WriteString("</thead>\n<tbody>\n")
+	}
+	_, err = io.WriteString(h.w, row.String())
+	h.rows++
+	return
+}
+
+func (h *htmlTable) Flush() error {
+	return nil
+}
+
+func (h *htmlTable) Resume() {
+	h.rows = 1
+}
+
+func (h *htmlTable) Close() (err error) {
+	if h.rows > 0 {
+		_, err = io.WriteString(h.w, "</tbody>\n</table>\n")
+	}
+	return
+}
+
+// jsonRecordWriter writes one JSON array of all the records.
+type jsonRecordWriter struct {
+	w      io.Writer
+	pretty bool
+	count  int
+}
+
+func (j *jsonRecordWriter) WritePage(records []salesforce.ForceRecord) (err error) {
+	for _, record := range records {
+		var b []byte
+		if j.pretty {
+			b, err = json.MarshalIndent(record, "  ", "  ")
+		} else {
+			b, err = json.Marshal(record)
+		}
+		if err != nil {
+			return
+		}
+		if _, err = io.WriteString(j.w, j.separator()); err != nil {
+			return
+		}
+		if _, err = j.w.Write(b); err != nil {
+			return
+		}
+		j.count++
+	}
+	return
+}
+
+func (j *jsonRecordWriter) separator() string {
+	switch {
+	case j.count == 0 && j.pretty:
+		return "[\n  "
+	case j.count == 0:
+		return "["
+	case j.pretty:
+		return ",\n  "
+	}
+	return ","
+}
+
+func (j *jsonRecordWriter) Resume(rows int) {
+	j.count = rows
+}
+
+func (j *jsonRecordWriter) Close() (err error) {
+	switch {
+	case j.count == 0:
+		_, err = io.WriteString(j.w, "[]\n")
+	case j.pretty:
+		_, err = io.WriteString(j.w, "\n]\n")
+	default:
+		_, err = io.WriteString(j.w, "]\n")
+	}
+	return
+}
+
+// ndjsonRecordWriter writes each record as JSON on a line of its own.
+type ndjsonRecordWriter struct {
+	w io.Writer
+}
+
+func (n *ndjsonRecordWriter) WritePage(records []salesforce.ForceRecord) (err error) {
+	for _, record := range records {
+		b, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if _, err = fmt.Fprintf(n.w, "%s\n", b); err != nil {
+			return err
+		}
+	}
+	return
+}
+
+func (n *ndjsonRecordWriter) Resume(rows int) {}
+
+func (n *ndjsonRecordWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/joist-engineering/force/salesforce"
+)
+
+func TestJSONRecordWriterStreamsOneArray(t *testing.T) {
+	pages := [][]salesforce.ForceRecord{
+		{{"Name": "Acme"}, {"Name": "Globex"}},
+		{{"Name": "Initech"}},
+	}
+	all := append(append([]salesforce.ForceRecord{}, pages[0]...), pages[1]...)
+
+	for _, pretty := range []bool{false, true} {
+		var out bytes.Buffer
+		w := &jsonRecordWriter{w: &out, pretty: pretty}
+		for _, page := range pages {
+			assert.Equal(t, nil, w.WritePage(page))
+		}
+		assert.Equal(t, nil, w.Close())
+
+		var expected []byte
+		if pretty {
+			expected, _ = json.MarshalIndent(all, "", "  ")
+		} else {
+			expected, _ = json.Marshal(all)
+		}
+		assert.Equal(t, string(expected)+"\n", out.String())
+	}
+
+	var out bytes.Buffer
+	w := &jsonRecordWriter{w: &out}
+	w.Close()
+	assert.Equal(t, "[]\n", out.String())
+}
+
+// accounts are query results as Salesforce returns them, with a lookup and a
+// subquery.
+var accounts = []salesforce.ForceRecord{
+	{
+		"attributes":    map[string]interface{}{"type": "Account"},
+		"Name":          "Acme, \"The\" Company",
+		"Description":   "line one\nline two",
+		"AnnualRevenue": 1500000.0,
+		"Owner":         map[string]interface{}{"attributes": map[string]interface{}{"type": "User"}, "Name": "Ann"},
+		"Contacts": map[string]interface{}{
+			"totalSize": 2.0,
+			"done":      true,
+			"records": []interface{}{
+				map[string]interface{}{"attributes": map[string]interface{}{"type": "Contact"}, "LastName": "Smith"},
+				map[string]interface{}{"attributes": map[string]interface{}{"type": "Contact"}, "LastName": "Jones"},
+			},
+		},
+	},
+	{
+		"attributes":    map[string]interface{}{"type": "Account"},
+		"Name":          "Globex",
+		"Description":   nil,
+		"AnnualRevenue": nil,
+		"Owner":         nil,
+		"Contacts":      nil,
+	},
+}
+
+func writeRecords(t *testing.T, format, soql string, childRows bool, pages ...[]salesforce.ForceRecord) string {
+	var out bytes.Buffer
+	w, err := newRecordWriter(&out, format, parseSoqlFields(soql), childRows)
+	assert.Equal(t, nil, err)
+	for _, page := range pages {
+		assert.Equal(t, nil, w.WritePage(page))
+	}
+	assert.Equal(t, nil, w.Close())
+	return out.String()
+}
+
+func TestCSVFollowsTheSelectAndRFC4180(t *testing.T) {
+	soql := "SELECT name, Owner.Name, AnnualRevenue, Description, (SELECT LastName FROM Contacts) FROM Account"
+	assert.Equal(t, `Name,Owner.Name,AnnualRevenue,Description,Contacts
+"Acme, ""The"" Company",Ann,1500000,"line one
+line two","[{""LastName"":""Smith""},{""LastName"":""Jones""}]"
+Globex,,,,
+`, writeRecords(t, "csv", soql, false, accounts))
+
+	assert.Equal(t, `Name,Contacts.LastName
+"Acme, ""The"" Company",Smith
+"Acme, ""The"" Company",Jones
+Globex,
+`, writeRecords(t, "csv", "SELECT Name, (SELECT LastName FROM Contacts) FROM Account", true, accounts))
+}
+
+func TestCSVWritesTheHeaderOnce(t *testing.T) {
+	assert.Equal(t, "Id,Name\n001A,Acme\n001B,Globex\n", writeRecords(t, "csv", "SELECT Id, Name FROM Account", false,
+		[]salesforce.ForceRecord{{"Name": "Acme", "Id": "001A"}},
+		[]salesforce.ForceRecord{{"Name": "Globex", "Id": "001B"}}))
+	assert.Equal(t, "Id,Name\n", writeRecords(t, "csv", "SELECT Id, Name FROM Account", false))
+	assert.Equal(t, "Id,Name\n001A,Acme\n", writeRecords(t, "csv", "SELECT FIELDS(STANDARD) FROM Account", false,
+		[]salesforce.ForceRecord{{"Name": "Acme", "Id": "001A"}}))
+}
+
+func TestTableFormats(t *testing.T) {
+	soql := "SELECT Name, Description FROM Account"
+	assert.Equal(t, "Name\tDescription\nGlobex\t\n", writeRecords(t, "tsv", soql, false, accounts[1:]))
+	assert.Equal(t, "| Name | Description |\n| --- | --- |\n| Acme, \"The\" Company | line one<br>line two |\n",
+		writeRecords(t, "markdown", soql, false, accounts[:1]))
+	assert.Equal(t, "<table>\n<thead>\n<tr><th>Name</th><th>Description</th></tr>\n</thead>\n<tbody>\n"+
+		"<tr><td>Acme, &#34;The&#34; Company</td><td>line one\nline two</td></tr>\n</tbody>\n</table>\n",
+		writeRecords(t, "html", soql, false, accounts[:1]))
+}
+
+func TestConsoleStreamsPages(t *testing.T) {
+	assert.Equal(t, RenderForceRecords(accounts)+" (2 records)\n", writeRecords(t, "console", "", false, accounts))
+
+	acme := []salesforce.ForceRecord{{"Id": "001A", "Name": "Acme"}}
+	assert.Equal(t, ` Id   | Name 
+------+------
+ 001A | Acme 
+ 001B | Init 
+ (2 records)
+`, writeRecords(t, "console", "", false, acme, []salesforce.ForceRecord{{"Id": "001B", "Name": "Init"}}))
+	assert.Equal(t, ` Id   | Name 
+------+------
+ 001A | Acme 
+ Id   | Name    
+------+---------
+ 001B | Initech 
+ (2 records)
+`, writeRecords(t, "console", "", false, acme, []salesforce.ForceRecord{{"Id": "001B", "Name": "Initech"}}))
+	assert.Equal(t, " (0 records)\n", writeRecords(t, "console", "", false))
+}
+
+func TestNDJSON(t *testing.T) {
+	out := writeRecords(t, "ndjson", "", false, []salesforce.ForceRecord{{"Name": "Acme"}}, []salesforce.ForceRecord{{"Name": "Globex"}})
+	assert.Equal(t, "{\"Name\":\"Acme\"}\n{\"Name\":\"Globex\"}\n", out)
+}
+
+func TestUnknownFormat(t *testing.T) {
+	_, err := newRecordWriter(&bytes.Buffer{}, "xlsx", nil, false)
+	assert.NotEqual(t, nil, err)
+}
+
+func TestLegacyQueryFormat(t *testing.T) {
+	args, format, isTooling := legacyQueryFormat([]string{"SELECT Id FROM Account", "--format:csv"})
+	assert.Equal(t, []string{"SELECT Id FROM Account"}, args)
+	assert.Equal(t, "csv", format)
+	assert.Equal(t, false, isTooling)
+
+	args, format, isTooling = legacyQueryFormat([]string{"SELECT Id FROM ApexClass", "format:json", "tooling"})
+	assert.Equal(t, []string{"SELECT Id FROM ApexClass"}, args)
+	assert.Equal(t, "json", format)
+	assert.Equal(t, true, isTooling)
+
+	args, format, _ = legacyQueryFormat([]string{"SELECT", "Id", "FROM", "Account"})
+	assert.Equal(t, 4, len(args))
+	assert.Equal(t, "", format)
+}
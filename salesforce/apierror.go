@@ -0,0 +1,111 @@
+package salesforce
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// APIError is a failure reported by Salesforce, from the REST, Bulk or SOAP
+// APIs.
+type APIError struct {
+	// StatusCode is the HTTP status of the response.
+	StatusCode int
+	// ErrorCode is the Salesforce error code, such as DUPLICATE_VALUE or
+	// INVALID_SESSION_ID.  SOAP fault codes are given without their "sf:"
+	// prefix.
+	ErrorCode string
+	Message   string
+	// Fields are the fields the error is about, if any.
+	Fields []string
+	// Body is the whole response, as Salesforce sent it.
+	Body []byte
+}
+
+func (e *APIError) Error() string {
+	switch {
+	case e.Message != "":
+		return e.Message
+	case e.ErrorCode != "":
+		return e.ErrorCode
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Is lets errors.Is match an expired session to ErrAuthorizationExpired and a
+// refused request to ErrForbidden.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrAuthorizationExpired:
+		return e.StatusCode == 401 || e.ErrorCode == "INVALID_SESSION_ID" || e.ErrorCode == "InvalidSessionId"
+	case ErrForbidden:
+		return e.StatusCode == 403
+	}
+	return false
+}
+
+// newAPIError reads the error from the body of a failed response, which may
+// be a list of REST errors, an OAuth error, a Bulk API error in XML or JSON,
+// or a SOAP fault.  Bodies that aren't any of these, such as a proxy's error
+// page, become the message as they are.
+func newAPIError(statusCode int, body []byte) (e *APIError) {
+	e = &APIError{StatusCode: statusCode, Body: body}
+	trimmed := bytes.TrimSpace(body)
+	switch {
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var messages []ForceError
+		if json.Unmarshal(trimmed, &messages) == nil && len(messages) > 0 {
+			e.ErrorCode = messages[0].ErrorCode
+			e.Message = messages[0].Message
+			e.Fields = messages[0].Fields
+			return
+		}
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		var message struct {
+			ForceError
+			GenericForceError
+			ExceptionCode    string `json:"exceptionCode"`
+			ExceptionMessage string `json:"exceptionMessage"`
+		}
+		if json.Unmarshal(trimmed, &message) == nil {
+			e.ErrorCode = firstNonEmpty(message.ErrorCode, message.ExceptionCode, message.Error)
+			e.Message = firstNonEmpty(message.Message, message.ExceptionMessage, message.Error_Description)
+			e.Fields = message.Fields
+			if e.ErrorCode != "" || e.Message != "" {
+				return
+			}
+		}
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		var fault struct {
+			FaultCode        string `xml:"Body>Fault>faultcode"`
+			FaultString      string `xml:"Body>Fault>faultstring"`
+			ExceptionCode    string `xml:"exceptionCode"`
+			ExceptionMessage string `xml:"exceptionMessage"`
+		}
+		if xml.Unmarshal(trimmed, &fault) == nil {
+			code := fault.FaultCode
+			if i := strings.LastIndex(code, ":"); i >= 0 {
+				code = code[i+1:]
+			}
+			e.ErrorCode = firstNonEmpty(code, fault.ExceptionCode)
+			e.Message = firstNonEmpty(fault.FaultString, fault.ExceptionMessage)
+			if e.ErrorCode != "" || e.Message != "" {
+				return
+			}
+		}
+	}
+	e.Message = string(trimmed)
+	return
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,96 @@
+package salesforce_test
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("APIError", func() {
+	var (
+		status int
+		body   string
+	)
+
+	org := serveOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+
+	apiError := func(err error) *salesforce.APIError {
+		var apiError *salesforce.APIError
+		Expect(errors.As(err, &apiError)).To(BeTrue(), "%#v is not an *APIError", err)
+		return apiError
+	}
+
+	It("should carry the REST error code, message and fields", func() {
+		status = 400
+		body = `[{"message":"duplicate value found: Email__c","errorCode":"DUPLICATE_VALUE","fields":["Email__c"]}]`
+		_, err, _ := org.force.CreateRecord("Contact", map[string]string{"Email__c": "a@example.com"})
+		e := apiError(err)
+		Expect(e.StatusCode).To(Equal(400))
+		Expect(e.ErrorCode).To(Equal("DUPLICATE_VALUE"))
+		Expect(e.Message).To(Equal("duplicate value found: Email__c"))
+		Expect(e.Fields).To(Equal([]string{"Email__c"}))
+		Expect(string(e.Body)).To(Equal(body))
+		Expect(err.Error()).To(Equal("duplicate value found: Email__c"))
+	})
+
+	It("should not panic when the body isn't a list of errors", func() {
+		status = 502
+		body = "<html><body>Bad Gateway</body></html>"
+		var err error
+		Expect(func() {
+			_, err, _ = org.force.CreateRecord("Contact", map[string]string{})
+		}).ToNot(Panic())
+		Expect(apiError(err).StatusCode).To(Equal(502))
+	})
+
+	It("should describe empty responses by their status", func() {
+		status = 404
+		body = ""
+		err := org.force.DeleteRecord("Contact", "003000000000001")
+		Expect(err).To(MatchError("404 Not Found"))
+	})
+
+	It("should match an expired session to ErrAuthorizationExpired", func() {
+		status = 401
+		body = `[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`
+		_, err := org.force.Query("SELECT Id FROM Account", false)
+		Expect(errors.Is(err, salesforce.ErrAuthorizationExpired)).To(BeTrue())
+		Expect(errors.Is(err, salesforce.ErrForbidden)).To(BeFalse())
+	})
+
+	It("should read Bulk API errors", func() {
+		status = 400
+		body = `<?xml version="1.0" encoding="UTF-8"?><error xmlns="http://www.force.com/2009/06/asyncapi/dataload">
+			<exceptionCode>InvalidJob</exceptionCode>
+			<exceptionMessage>Unable to find object: Acount</exceptionMessage>
+		</error>`
+		_, err := org.force.CreateBulkJob("<jobInfo/>")
+		e := apiError(err)
+		Expect(e.ErrorCode).To(Equal("InvalidJob"))
+		Expect(e.Message).To(Equal("Unable to find object: Acount"))
+	})
+
+	It("should read SOAP faults", func() {
+		status = 500
+		body = `<?xml version="1.0" encoding="UTF-8"?>
+		<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/" xmlns:sf="http://soap.sforce.com/2006/04/metadata">
+			<soapenv:Body><soapenv:Fault>
+				<faultcode>sf:INVALID_SESSION_ID</faultcode>
+				<faultstring>INVALID_SESSION_ID: Invalid Session ID found in SessionHeader</faultstring>
+			</soapenv:Fault></soapenv:Body>
+		</soapenv:Envelope>`
+		soap := salesforce.NewSoap(org.server.URL, "http://soap.sforce.com/2006/04/metadata", "token")
+		_, err := soap.Execute("checkStatus", "<id>0Af000000000001</id>")
+		e := apiError(err)
+		Expect(e.ErrorCode).To(Equal("INVALID_SESSION_ID"))
+		Expect(e.Message).To(HavePrefix("INVALID_SESSION_ID: Invalid Session ID"))
+		Expect(errors.Is(err, salesforce.ErrAuthorizationExpired)).To(BeTrue())
+	})
+})
@@ -14,6 +14,14 @@ func ParseArgumentAttrs(pairs []string) (parsed map[string]string) {
 	return
 }
 
+func stringValues(attrs map[string]string) (values map[string]interface{}) {
+	values = make(map[string]interface{}, len(attrs))
+	for key, value := range attrs {
+		values[key] = value
+	}
+	return
+}
+
 func PairsToUrlValues(pairs map[string]string) (values url.Values) {
 	values = url.Values{}
 	for key, value := range pairs {
@@ -0,0 +1,139 @@
+package salesforce
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// CompositeRequest is one of the requests of a call to Composite.  Later
+// requests can use the results of earlier ones with @{referenceId.field},
+// such as "AccountId": "@{newAccount.id}".
+type CompositeRequest struct {
+	Method      string            `json:"method"`
+	Url         string            `json:"url"`
+	ReferenceId string            `json:"referenceId"`
+	Body        interface{}       `json:"body,omitempty"`
+	HttpHeaders map[string]string `json:"httpHeaders,omitempty"`
+}
+
+// CompositeResponse is the response to one of the requests of a call to
+// Composite.
+type CompositeResponse struct {
+	Body           json.RawMessage   `json:"body"`
+	HttpHeaders    map[string]string `json:"httpHeaders"`
+	HttpStatusCode int               `json:"httpStatusCode"`
+	ReferenceId    string            `json:"referenceId"`
+}
+
+// CompositeCreateResult is the body of the response to a request that
+// created a record.
+type CompositeCreateResult struct {
+	Id      string `json:"id"`
+	Success bool   `json:"success"`
+}
+
+// SObjectsUrl returns the path of a REST resource for the sObjects, as used
+// by the requests of Composite, such as /services/data/v45.0/sobjects/Account.
+func (f *Force) SObjectsUrl(path string) string {
+	return fmt.Sprintf("/services/data/%s/sobjects/%s", f.Credentials.ApiVersion, path)
+}
+
+// Composite sends up to 25 requests in one call.  With allOrNone, they are
+// made in one transaction, so that if any of them fails, none of their
+// changes are kept.  The error is for the first request that failed, if any.
+func (f *Force) Composite(requests []CompositeRequest, allOrNone bool) (responses []CompositeResponse, err error) {
+	url := fmt.Sprintf("%s/services/data/%s/composite", f.Credentials.InstanceUrl, f.Credentials.ApiVersion)
+	body, err := f.httpSendJSON("POST", url, map[string]interface{}{
+		"allOrNone":        allOrNone,
+		"compositeRequest": requests,
+	})
+	if err != nil {
+		return
+	}
+	var result struct {
+		CompositeResponse []CompositeResponse `json:"compositeResponse"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return
+	}
+	responses = result.CompositeResponse
+	err = compositeError(responses)
+	return
+}
+
+// compositeError returns the error of the first request that failed, passing
+// over those that only failed because another did.
+func compositeError(responses []CompositeResponse) error {
+	var halted error
+	for _, response := range responses {
+		if response.HttpStatusCode/100 == 2 {
+			continue
+		}
+		apiError := newAPIError(response.HttpStatusCode, response.Body)
+		err := fmt.Errorf("%s: %w", response.ReferenceId, apiError)
+		if apiError.ErrorCode != "PROCESSING_HALTED" {
+			return err
+		}
+		if halted == nil {
+			halted = err
+		}
+	}
+	return halted
+}
+
+// SObjectTreeResult is the result of creating records with SObjectTree.
+type SObjectTreeResult struct {
+	HasErrors bool                `json:"hasErrors"`
+	Results   []SObjectTreeRecord `json:"results"`
+}
+
+// SObjectTreeRecord is the id of a record SObjectTree created, or the errors
+// that stopped it being created.
+type SObjectTreeRecord struct {
+	ReferenceId string             `json:"referenceId"`
+	Id          string             `json:"id"`
+	Errors      []SObjectTreeError `json:"errors"`
+}
+
+type SObjectTreeError struct {
+	StatusCode string   `json:"statusCode"`
+	Message    string   `json:"message"`
+	Fields     []string `json:"fields"`
+}
+
+// SObjectTree creates up to 200 records of an sObject, along with their
+// child records, in one transaction.  Each record has attributes with its
+// type and a referenceId, and its child records are under the name of
+// their relationship:
+//
+//	{"attributes": {"type": "Account", "referenceId": "acme"},
+//	 "Name": "Acme",
+//	 "Contacts": {"records": [
+//	     {"attributes": {"type": "Contact", "referenceId": "wile"}, "LastName": "Coyote"}]}}
+func (f *Force) SObjectTree(sobject string, records []ForceRecord) (result SObjectTreeResult, err error) {
+	url := fmt.Sprintf("%s/services/data/%s/composite/tree/%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, sobject)
+	body, err := f.httpSendJSON("POST", url, map[string]interface{}{"records": records})
+	var apiError *APIError
+	if err != nil && !(errors.As(err, &apiError) && apiError.StatusCode == http.StatusBadRequest) {
+		return
+	}
+	if jsonErr := json.Unmarshal(body, &result); jsonErr != nil || !result.HasErrors {
+		return
+	}
+	for _, record := range result.Results {
+		if len(record.Errors) > 0 {
+			e := record.Errors[0]
+			err = fmt.Errorf("%s: %w", record.ReferenceId, &APIError{
+				StatusCode: http.StatusBadRequest,
+				ErrorCode:  e.StatusCode,
+				Message:    e.Message,
+				Fields:     e.Fields,
+				Body:       body,
+			})
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,104 @@
+package salesforce_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Composite", func() {
+	var (
+		path     string
+		sent     map[string]interface{}
+		response string
+		status   int
+	)
+
+	org := serveOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		body, _ := ioutil.ReadAll(r.Body)
+		json.Unmarshal(body, &sent)
+		w.WriteHeader(status)
+		w.Write([]byte(response))
+	}))
+
+	BeforeEach(func() {
+		status = http.StatusOK
+	})
+
+	It("should send the requests and return their responses", func() {
+		response = `{"compositeResponse":[
+			{"body":{"id":"001A","success":true,"errors":[]},"httpHeaders":{},"httpStatusCode":201,"referenceId":"acme"},
+			{"body":{"id":"003A","success":true,"errors":[]},"httpHeaders":{},"httpStatusCode":201,"referenceId":"wile"}]}`
+		responses, err := org.force.Composite([]salesforce.CompositeRequest{
+			{Method: "POST", Url: org.force.SObjectsUrl("Account"), ReferenceId: "acme", Body: map[string]interface{}{"Name": "Acme"}},
+			{Method: "POST", Url: org.force.SObjectsUrl("Contact"), ReferenceId: "wile", Body: map[string]interface{}{"LastName": "Coyote", "AccountId": "@{acme.id}"}},
+		}, true)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(path).To(Equal("/services/data/v45.0/composite"))
+		Expect(sent["allOrNone"]).To(BeTrue())
+		requests := sent["compositeRequest"].([]interface{})
+		Expect(requests[1]).To(HaveKeyWithValue("url", "/services/data/v45.0/sobjects/Contact"))
+		Expect(requests[1]).To(HaveKeyWithValue("body", HaveKeyWithValue("AccountId", "@{acme.id}")))
+
+		Expect(responses).To(HaveLen(2))
+		var created salesforce.CompositeCreateResult
+		Expect(json.Unmarshal(responses[1].Body, &created)).To(Succeed())
+		Expect(created.Id).To(Equal("003A"))
+	})
+
+	It("should return the error of the request that failed, not those it halted", func() {
+		response = `{"compositeResponse":[
+			{"body":[{"errorCode":"PROCESSING_HALTED","message":"The transaction was rolled back since another operation in the same transaction failed."}],"httpHeaders":{},"httpStatusCode":400,"referenceId":"acme"},
+			{"body":[{"message":"Required fields are missing: [LastName]","errorCode":"REQUIRED_FIELD_MISSING","fields":["LastName"]}],"httpHeaders":{},"httpStatusCode":400,"referenceId":"wile"}]}`
+		_, err := org.force.Composite([]salesforce.CompositeRequest{
+			{Method: "POST", Url: org.force.SObjectsUrl("Account"), ReferenceId: "acme"},
+			{Method: "POST", Url: org.force.SObjectsUrl("Contact"), ReferenceId: "wile"},
+		}, true)
+		var apiError *salesforce.APIError
+		Expect(errors.As(err, &apiError)).To(BeTrue())
+		Expect(apiError.ErrorCode).To(Equal("REQUIRED_FIELD_MISSING"))
+		Expect(err.Error()).To(HavePrefix("wile: "))
+	})
+
+	Describe("SObjectTree", func() {
+		records := []salesforce.ForceRecord{{
+			"attributes": map[string]interface{}{"type": "Account", "referenceId": "acme"},
+			"Name":       "Acme",
+			"Contacts": map[string]interface{}{"records": []interface{}{
+				map[string]interface{}{"attributes": map[string]interface{}{"type": "Contact", "referenceId": "wile"}, "LastName": "Coyote"},
+			}},
+		}}
+
+		It("should return the ids of the records it created", func() {
+			status = http.StatusCreated
+			response = `{"hasErrors":false,"results":[{"referenceId":"acme","id":"001A"},{"referenceId":"wile","id":"003A"}]}`
+			result, err := org.force.SObjectTree("Account", records)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal("/services/data/v45.0/composite/tree/Account"))
+			Expect(sent["records"]).To(HaveLen(1))
+			Expect(result.Results).To(Equal([]salesforce.SObjectTreeRecord{
+				{ReferenceId: "acme", Id: "001A"},
+				{ReferenceId: "wile", Id: "003A"},
+			}))
+		})
+
+		It("should return the error of the record that couldn't be created", func() {
+			status = http.StatusBadRequest
+			response = `{"hasErrors":true,"results":[{"referenceId":"wile","errors":[{"statusCode":"REQUIRED_FIELD_MISSING","message":"Required fields are missing: [LastName]","fields":["LastName"]}]}]}`
+			result, err := org.force.SObjectTree("Account", records)
+			Expect(result.HasErrors).To(BeTrue())
+			var apiError *salesforce.APIError
+			Expect(errors.As(err, &apiError)).To(BeTrue())
+			Expect(apiError.ErrorCode).To(Equal("REQUIRED_FIELD_MISSING"))
+			Expect(apiError.Fields).To(Equal([]string{"LastName"}))
+			Expect(err.Error()).To(HavePrefix("wile: "))
+		})
+	})
+})
@@ -0,0 +1,458 @@
+package salesforce
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The decomposed source format splits the repeating child elements of large
+// metadata files (the fields of a CustomObject, the field permissions of a
+// Profile, ...) out into one file per component, so that unrelated changes to
+// the same object no longer touch the same file.  For example
+// `objects/Account.object` becomes:
+//
+//   objects/Account/Account.object-meta.xml
+//   objects/Account/fields/Industry.field-meta.xml
+//   objects/Account/listViews/AllAccounts.listView-meta.xml
+//   ...
+//
+// The layout for CustomObject matches the Salesforce DX source format.
+// DecomposeMetadata and RecomposeMetadata convert between the two shapes, and
+// are exact inverses of one another: a file is only ever decomposed when
+// recomposing it yields the original bytes.
+
+// decomposedChild describes one kind of repeating child element that gets
+// split out into its own directory.
+type decomposedChild struct {
+	// tag is the element name within the parent, and also the directory name.
+	tag string
+	// suffix is the file suffix of the split out files, minus `-meta.xml`.
+	suffix string
+	// root is the root element name used in the split out files.
+	root string
+	// keys are the child elements whose values name the split out file.
+	keys []string
+}
+
+type decomposedType struct {
+	path     string
+	suffix   string
+	name     string
+	children []decomposedChild
+}
+
+const metadataNamespace = "http://soap.sforce.com/2006/04/metadata"
+
+// decomposedIndent is the indentation used by the Metadata API when it
+// renders metadata files.
+const decomposedIndent = "    "
+
+var decomposedTypes = []decomposedType{
+	decomposedType{path: "objects", suffix: "object", name: "CustomObject", children: []decomposedChild{
+		decomposedChild{tag: "businessProcesses", suffix: "businessProcess", root: "BusinessProcess", keys: []string{"fullName"}},
+		decomposedChild{tag: "compactLayouts", suffix: "compactLayout", root: "CompactLayout", keys: []string{"fullName"}},
+		decomposedChild{tag: "fieldSets", suffix: "fieldSet", root: "FieldSet", keys: []string{"fullName"}},
+		decomposedChild{tag: "fields", suffix: "field", root: "CustomField", keys: []string{"fullName"}},
+		decomposedChild{tag: "indexes", suffix: "index", root: "Index", keys: []string{"fullName"}},
+		decomposedChild{tag: "listViews", suffix: "listView", root: "ListView", keys: []string{"fullName"}},
+		decomposedChild{tag: "recordTypes", suffix: "recordType", root: "RecordType", keys: []string{"fullName"}},
+		decomposedChild{tag: "sharingReasons", suffix: "sharingReason", root: "SharingReason", keys: []string{"fullName"}},
+		decomposedChild{tag: "validationRules", suffix: "validationRule", root: "ValidationRule", keys: []string{"fullName"}},
+		decomposedChild{tag: "webLinks", suffix: "webLink", root: "WebLink", keys: []string{"fullName"}},
+	}},
+	decomposedType{path: "profiles", suffix: "profile", name: "Profile", children: []decomposedChild{
+		decomposedChild{tag: "applicationVisibilities", suffix: "applicationVisibility", root: "ProfileApplicationVisibility", keys: []string{"application"}},
+		decomposedChild{tag: "classAccesses", suffix: "classAccess", root: "ProfileApexClassAccess", keys: []string{"apexClass"}},
+		decomposedChild{tag: "customPermissions", suffix: "customPermission", root: "ProfileCustomPermissions", keys: []string{"name"}},
+		decomposedChild{tag: "fieldPermissions", suffix: "fieldPermission", root: "ProfileFieldLevelSecurity", keys: []string{"field"}},
+		decomposedChild{tag: "layoutAssignments", suffix: "layoutAssignment", root: "ProfileLayoutAssignment", keys: []string{"layout", "recordType"}},
+		decomposedChild{tag: "objectPermissions", suffix: "objectPermission", root: "ProfileObjectPermissions", keys: []string{"object"}},
+		decomposedChild{tag: "pageAccesses", suffix: "pageAccess", root: "ProfileApexPageAccess", keys: []string{"apexPage"}},
+		decomposedChild{tag: "recordTypeVisibilities", suffix: "recordTypeVisibility", root: "ProfileRecordTypeVisibility", keys: []string{"recordType"}},
+		decomposedChild{tag: "tabVisibilities", suffix: "tabVisibility", root: "ProfileTabVisibility", keys: []string{"tab"}},
+		decomposedChild{tag: "userPermissions", suffix: "userPermission", root: "ProfileUserPermission", keys: []string{"name"}},
+	}},
+}
+
+func (dt decomposedType) child(tag string) (decomposedChild, bool) {
+	for _, child := range dt.children {
+		if child.tag == tag {
+			return child, true
+		}
+	}
+	return decomposedChild{}, false
+}
+
+// decomposedTypeForPath returns the decomposable type a path such as `objects/Account.object`
+// belongs to.
+func decomposedTypeForPath(name string) (dt decomposedType, componentName string, ok bool) {
+	dir, file := path.Split(filepath.ToSlash(name))
+	for _, dt = range decomposedTypes {
+		if dir == dt.path+"/" && strings.HasSuffix(file, "."+dt.suffix) {
+			return dt, strings.TrimSuffix(file, "."+dt.suffix), true
+		}
+	}
+	return
+}
+
+// xmlElement is a top-level child element of a metadata file, along with its
+// position in the raw file.
+type xmlElement struct {
+	tag string
+	// key is the file name this element would be decomposed into.
+	key string
+	// start and end are the offsets of the element itself, and leading is
+	// the offset at which the whitespace preceding it starts.
+	leading, start, end int64
+}
+
+// xmlDocument records the positions of the root element and its children in a
+// raw metadata file.
+type xmlDocument struct {
+	data     []byte
+	rootName string
+	// contentStart is the offset just after the root start tag, contentEnd
+	// the offset of the root end tag.
+	contentStart, contentEnd int64
+	children                 []xmlElement
+}
+
+func parseXmlDocument(data []byte, keysForTag func(tag string) []string) (doc xmlDocument, err error) {
+	doc.data = data
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	depth := 0
+	var current *xmlElement
+	var keys []string
+	keyValues := make(map[string]string)
+	keyName := ""
+	lastEnd := int64(0)
+	for {
+		start := decoder.InputOffset()
+		token, terr := decoder.Token()
+		if terr != nil {
+			if depth != 0 || doc.rootName == "" {
+				err = fmt.Errorf("malformed XML: %v", terr)
+			}
+			return
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+			switch depth {
+			case 1:
+				doc.rootName = t.Name.Local
+				doc.contentStart = decoder.InputOffset()
+			case 2:
+				current = &xmlElement{tag: t.Name.Local, leading: lastEnd, start: start}
+				keys = nil
+				if keysForTag != nil {
+					keys = keysForTag(t.Name.Local)
+				}
+				keyValues = make(map[string]string)
+			case 3:
+				for _, key := range keys {
+					if key == t.Name.Local {
+						keyName = key
+					}
+				}
+			}
+		case xml.CharData:
+			if depth == 3 && keyName != "" {
+				keyValues[keyName] += string(t)
+			}
+		case xml.EndElement:
+			switch depth {
+			case 1:
+				doc.contentEnd = start
+			case 2:
+				current.end = decoder.InputOffset()
+				var parts []string
+				for _, key := range keys {
+					if value := keyValues[key]; value != "" {
+						parts = append(parts, value)
+					}
+				}
+				current.key = strings.Join(parts, ".")
+				doc.children = append(doc.children, *current)
+				current = nil
+			case 3:
+				keyName = ""
+			}
+			depth--
+		}
+		if _, isText := token.(xml.CharData); !isText && depth <= 1 {
+			lastEnd = decoder.InputOffset()
+		}
+	}
+}
+
+// reindentXml rewrites the whitespace between the elements of an XML fragment
+// with transform, leaving any text content untouched.  It returns false if
+// transform could not be applied.
+func reindentXml(fragment []byte, transform func(whitespace []byte) ([]byte, bool)) ([]byte, bool) {
+	var out bytes.Buffer
+	decoder := xml.NewDecoder(bytes.NewReader(fragment))
+	for {
+		start := decoder.InputOffset()
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		raw := fragment[start:decoder.InputOffset()]
+		if _, isText := token.(xml.CharData); isText && len(bytes.TrimSpace(raw)) == 0 {
+			transformed, ok := transform(raw)
+			if !ok {
+				return nil, false
+			}
+			out.Write(transformed)
+		} else {
+			out.Write(raw)
+		}
+	}
+	if decoder.InputOffset() != int64(len(fragment)) {
+		return nil, false
+	}
+	return out.Bytes(), true
+}
+
+func indentXml(whitespace []byte) ([]byte, bool) {
+	return bytes.Replace(whitespace, []byte("\n"), []byte("\n"+decomposedIndent), -1), true
+}
+
+func dedentXml(whitespace []byte) ([]byte, bool) {
+	if bytes.Count(whitespace, []byte("\n")) != bytes.Count(whitespace, []byte("\n"+decomposedIndent)) {
+		return nil, false
+	}
+	return bytes.Replace(whitespace, []byte("\n"+decomposedIndent), []byte("\n"), -1), true
+}
+
+func isSafeFileName(name string) bool {
+	return name != "" && !strings.HasPrefix(name, ".") && !strings.ContainsAny(name, `/\:*?"<>|`)
+}
+
+// decomposeFile splits a single metadata file into the files of the
+// decomposed source format, keyed by path relative to the type directory.
+func decomposeFile(dt decomposedType, componentName string, data []byte) (files ForceMetadataFiles, ok bool) {
+	doc, err := parseXmlDocument(data, func(tag string) []string {
+		child, _ := dt.child(tag)
+		return child.keys
+	})
+	if err != nil || doc.rootName != dt.name {
+		return
+	}
+
+	// only split out the elements of a given tag if every one of them can
+	// be given a distinct file name.
+	decomposable := make(map[string]bool)
+	seen := make(map[string]map[string]bool)
+	for _, child := range doc.children {
+		if _, known := dt.child(child.tag); !known {
+			continue
+		}
+		if _, present := decomposable[child.tag]; !present {
+			decomposable[child.tag] = true
+			seen[child.tag] = make(map[string]bool)
+		}
+		if !isSafeFileName(child.key) || seen[child.tag][child.key] {
+			decomposable[child.tag] = false
+		}
+		seen[child.tag][child.key] = true
+	}
+
+	files = make(ForceMetadataFiles)
+	var residual bytes.Buffer
+	position := int64(0)
+	for _, child := range doc.children {
+		if !decomposable[child.tag] {
+			continue
+		}
+		residual.Write(data[position:child.leading])
+		position = child.end
+
+		spec, _ := dt.child(child.tag)
+		element := data[child.start:child.end]
+		openTag := fmt.Sprintf("<%s>", child.tag)
+		closeTag := fmt.Sprintf("</%s>", child.tag)
+		if !bytes.HasPrefix(element, []byte(openTag)) || !bytes.HasSuffix(element, []byte(closeTag)) {
+			return nil, false
+		}
+		inner, dedented := reindentXml(element[len(openTag):len(element)-len(closeTag)], dedentXml)
+		if !dedented {
+			return nil, false
+		}
+		var file bytes.Buffer
+		file.WriteString(xml.Header)
+		fmt.Fprintf(&file, "<%s xmlns=\"%s\">", spec.root, metadataNamespace)
+		file.Write(inner)
+		fmt.Fprintf(&file, "</%s>\n", spec.root)
+		files[path.Join(componentName, child.tag, fmt.Sprintf("%s.%s-meta.xml", child.key, spec.suffix))] = file.Bytes()
+	}
+	if len(files) == 0 {
+		return nil, false
+	}
+	residual.Write(data[position:])
+	files[path.Join(componentName, fmt.Sprintf("%s.%s-meta.xml", componentName, dt.suffix))] = residual.Bytes()
+
+	// we only want to decompose files that we can reproduce exactly.
+	recomposed, err := recomposeFiles(dt, componentName, files)
+	if err != nil || !bytes.Equal(recomposed, data) {
+		return nil, false
+	}
+	return files, true
+}
+
+// recomposeFiles reassembles the decomposed files of a single component,
+// keyed by path relative to the type directory, into the original metadata
+// file.
+func recomposeFiles(dt decomposedType, componentName string, files ForceMetadataFiles) (data []byte, err error) {
+	residualName := path.Join(componentName, fmt.Sprintf("%s.%s-meta.xml", componentName, dt.suffix))
+	residualData, hasResidual := files[residualName]
+	if !hasResidual {
+		residualData = []byte(fmt.Sprintf("%s<%s xmlns=\"%s\">\n</%s>\n", xml.Header, dt.name, metadataNamespace, dt.name))
+	}
+	residual, err := parseXmlDocument(residualData, nil)
+	if err != nil {
+		err = fmt.Errorf("%s/%s: %v", dt.path, residualName, err)
+		return
+	}
+
+	// gather the decomposed elements, grouped by tag and ordered by name.
+	elements := make(map[string][][]byte)
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	// sort on the component key alone, so that `Layout` comes before
+	// `Layout.RecordType` as it does in the original file.
+	sortKey := func(name string) string {
+		name = strings.TrimSuffix(name, "-meta.xml")
+		return strings.TrimSuffix(name, path.Ext(name))
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return sortKey(names[i]) < sortKey(names[j])
+	})
+	for _, name := range names {
+		if name == residualName {
+			continue
+		}
+		parts := strings.Split(name, "/")
+		var spec decomposedChild
+		known := false
+		if len(parts) == 3 {
+			spec, known = dt.child(parts[1])
+		}
+		if !known || !strings.HasSuffix(parts[2], fmt.Sprintf(".%s-meta.xml", spec.suffix)) {
+			err = fmt.Errorf("%s/%s is not a recognized %s component", dt.path, name, dt.name)
+			return
+		}
+		doc, perr := parseXmlDocument(files[name], nil)
+		if perr != nil {
+			err = fmt.Errorf("%s/%s: %v", dt.path, name, perr)
+			return
+		}
+		inner, _ := reindentXml(doc.data[doc.contentStart:doc.contentEnd], indentXml)
+		element := fmt.Sprintf("<%s>%s</%s>", spec.tag, inner, spec.tag)
+		elements[spec.tag] = append(elements[spec.tag], []byte(element))
+	}
+	var tags []string
+	for tag := range elements {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	// the Metadata API renders child elements ordered by tag, so we merge the
+	// decomposed elements back in at their place in that order.
+	var out bytes.Buffer
+	writeUpTo := func(tag string) {
+		for len(tags) > 0 && (tag == "" || tags[0] < tag) {
+			for _, element := range elements[tags[0]] {
+				out.WriteString("\n" + decomposedIndent)
+				out.Write(element)
+			}
+			tags = tags[1:]
+		}
+	}
+	position := residual.contentStart
+	out.Write(residualData[:position])
+	for _, child := range residual.children {
+		writeUpTo(child.tag)
+		out.Write(residualData[position:child.end])
+		position = child.end
+	}
+	writeUpTo("")
+	out.Write(residualData[position:])
+	data = out.Bytes()
+	return
+}
+
+// DecomposeMetadata converts any CustomObject and Profile files in a set of
+// Metadata API files into the decomposed source format.  Files that can not be
+// decomposed losslessly are left as they are.
+func DecomposeMetadata(files ForceMetadataFiles) ForceMetadataFiles {
+	decomposed := make(ForceMetadataFiles)
+	for name, data := range files {
+		if dt, componentName, isDecomposable := decomposedTypeForPath(name); isDecomposable {
+			if componentFiles, ok := decomposeFile(dt, componentName, data); ok {
+				for componentFile, componentData := range componentFiles {
+					decomposed[path.Join(dt.path, componentFile)] = componentData
+				}
+				continue
+			}
+		}
+		decomposed[name] = data
+	}
+	return decomposed
+}
+
+// IsDecomposedPath reports whether the given path, relative to the metadata
+// root, belongs to a decomposed component.
+func IsDecomposedPath(name string) bool {
+	_, _, ok := decomposedComponentForPath(name)
+	return ok
+}
+
+func decomposedComponentForPath(name string) (dt decomposedType, componentName string, ok bool) {
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	if len(parts) < 3 {
+		return
+	}
+	for _, dt = range decomposedTypes {
+		if parts[0] == dt.path {
+			return dt, parts[1], true
+		}
+	}
+	return
+}
+
+// RecomposeMetadata converts any decomposed components in a set of files back
+// into the Metadata API shape, ready to be deployed.
+func RecomposeMetadata(files ForceMetadataFiles) (recomposed ForceMetadataFiles, err error) {
+	recomposed = make(ForceMetadataFiles)
+	components := make(map[string]ForceMetadataFiles)
+	for name, data := range files {
+		if dt, componentName, ok := decomposedComponentForPath(name); ok {
+			key := path.Join(dt.path, componentName)
+			if components[key] == nil {
+				components[key] = make(ForceMetadataFiles)
+			}
+			components[key][strings.TrimPrefix(filepath.ToSlash(name), dt.path+"/")] = data
+			continue
+		}
+		recomposed[name] = data
+	}
+
+	for key, componentFiles := range components {
+		dt, componentName, _ := decomposedComponentForPath(key + "/")
+		var data []byte
+		if data, err = recomposeFiles(dt, componentName, componentFiles); err != nil {
+			return
+		}
+		recomposed[fmt.Sprintf("%s.%s", key, dt.suffix)] = data
+	}
+	return
+}
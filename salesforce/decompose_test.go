@@ -0,0 +1,169 @@
+package salesforce_test
+
+import (
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+const accountObject = `<?xml version="1.0" encoding="UTF-8"?>
+<CustomObject xmlns="http://soap.sforce.com/2006/04/metadata">
+    <actionOverrides>
+        <actionName>View</actionName>
+        <type>Default</type>
+    </actionOverrides>
+    <enableFeeds>false</enableFeeds>
+    <fields>
+        <fullName>Industry</fullName>
+        <trackFeedHistory>false</trackFeedHistory>
+        <type>Picklist</type>
+    </fields>
+    <fields>
+        <fullName>Region__c</fullName>
+        <description>Sales region &amp; territory</description>
+        <externalId>false</externalId>
+        <label>Region</label>
+        <length>80</length>
+        <required>false</required>
+        <type>Text</type>
+        <unique>false</unique>
+    </fields>
+    <listViews>
+        <fullName>AllAccounts</fullName>
+        <filterScope>Everything</filterScope>
+        <label>All Accounts</label>
+    </listViews>
+    <searchLayouts>
+        <customTabListAdditionalFields>ACCOUNT.NAME</customTabListAdditionalFields>
+    </searchLayouts>
+    <sharingModel>ReadWrite</sharingModel>
+    <validationRules>
+        <fullName>Region_Required</fullName>
+        <active>true</active>
+        <errorConditionFormula>ISBLANK(Region__c)</errorConditionFormula>
+        <errorMessage>Region is required</errorMessage>
+    </validationRules>
+</CustomObject>
+`
+
+const adminProfile = `<?xml version="1.0" encoding="UTF-8"?>
+<Profile xmlns="http://soap.sforce.com/2006/04/metadata">
+    <classAccesses>
+        <apexClass>AccountController</apexClass>
+        <enabled>true</enabled>
+    </classAccesses>
+    <custom>false</custom>
+    <fieldPermissions>
+        <editable>true</editable>
+        <field>Account.Region__c</field>
+        <readable>true</readable>
+    </fieldPermissions>
+    <layoutAssignments>
+        <layout>Account-Account Layout</layout>
+    </layoutAssignments>
+    <layoutAssignments>
+        <layout>Account-Account Layout</layout>
+        <recordType>Account.Business</recordType>
+    </layoutAssignments>
+    <userLicense>Salesforce</userLicense>
+    <userPermissions>
+        <enabled>true</enabled>
+        <name>ApiEnabled</name>
+    </userPermissions>
+</Profile>
+`
+
+var _ = Describe("Decompose", func() {
+	var files salesforce.ForceMetadataFiles
+
+	BeforeEach(func() {
+		files = salesforce.ForceMetadataFiles{
+			"package.xml":            []byte("<Package/>"),
+			"classes/Foo.cls":        []byte("public class Foo {}"),
+			"objects/Account.object": []byte(accountObject),
+			"profiles/Admin.profile": []byte(adminProfile),
+		}
+	})
+
+	Describe("DecomposeMetadata", func() {
+		It("should split objects into one file per component", func() {
+			decomposed := salesforce.DecomposeMetadata(files)
+			Expect(decomposed).ToNot(HaveKey("objects/Account.object"))
+			Expect(decomposed).To(HaveKey("objects/Account/Account.object-meta.xml"))
+			Expect(decomposed).To(HaveKey("objects/Account/fields/Industry.field-meta.xml"))
+			Expect(decomposed).To(HaveKey("objects/Account/fields/Region__c.field-meta.xml"))
+			Expect(decomposed).To(HaveKey("objects/Account/listViews/AllAccounts.listView-meta.xml"))
+			Expect(decomposed).To(HaveKey("objects/Account/validationRules/Region_Required.validationRule-meta.xml"))
+		})
+
+		It("should write each component as a standalone metadata file", func() {
+			decomposed := salesforce.DecomposeMetadata(files)
+			Expect(string(decomposed["objects/Account/fields/Industry.field-meta.xml"])).To(Equal(`<?xml version="1.0" encoding="UTF-8"?>
+<CustomField xmlns="http://soap.sforce.com/2006/04/metadata">
+    <fullName>Industry</fullName>
+    <trackFeedHistory>false</trackFeedHistory>
+    <type>Picklist</type>
+</CustomField>
+`))
+		})
+
+		It("should split profiles into one file per permission", func() {
+			decomposed := salesforce.DecomposeMetadata(files)
+			Expect(decomposed).To(HaveKey("profiles/Admin/Admin.profile-meta.xml"))
+			Expect(decomposed).To(HaveKey("profiles/Admin/classAccesses/AccountController.classAccess-meta.xml"))
+			Expect(decomposed).To(HaveKey("profiles/Admin/fieldPermissions/Account.Region__c.fieldPermission-meta.xml"))
+			Expect(decomposed).To(HaveKey("profiles/Admin/layoutAssignments/Account-Account Layout.layoutAssignment-meta.xml"))
+			Expect(decomposed).To(HaveKey("profiles/Admin/layoutAssignments/Account-Account Layout.Account.Business.layoutAssignment-meta.xml"))
+			Expect(decomposed).To(HaveKey("profiles/Admin/userPermissions/ApiEnabled.userPermission-meta.xml"))
+		})
+
+		It("should leave other files alone", func() {
+			decomposed := salesforce.DecomposeMetadata(files)
+			Expect(decomposed["classes/Foo.cls"]).To(Equal(files["classes/Foo.cls"]))
+			Expect(decomposed["package.xml"]).To(Equal(files["package.xml"]))
+		})
+
+		It("should leave files it can not decompose losslessly alone", func() {
+			files["objects/Contact.object"] = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<CustomObject xmlns="http://soap.sforce.com/2006/04/metadata"><fields><fullName>A</fullName></fields></CustomObject>`)
+			decomposed := salesforce.DecomposeMetadata(files)
+			Expect(decomposed["objects/Contact.object"]).To(Equal(files["objects/Contact.object"]))
+		})
+	})
+
+	Describe("RecomposeMetadata", func() {
+		It("should reassemble byte identical files", func() {
+			recomposed, err := salesforce.RecomposeMetadata(salesforce.DecomposeMetadata(files))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(recomposed).To(HaveLen(len(files)))
+			for name, data := range files {
+				Expect(string(recomposed[name])).To(Equal(string(data)), name)
+			}
+		})
+
+		It("should pick up components added by hand", func() {
+			decomposed := salesforce.DecomposeMetadata(files)
+			decomposed["objects/Account/fields/Tier__c.field-meta.xml"] = []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<CustomField xmlns="http://soap.sforce.com/2006/04/metadata">
+    <fullName>Tier__c</fullName>
+    <type>Text</type>
+</CustomField>
+`)
+			recomposed, err := salesforce.RecomposeMetadata(decomposed)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(recomposed["objects/Account.object"])).To(ContainSubstring(`    <fields>
+        <fullName>Tier__c</fullName>
+        <type>Text</type>
+    </fields>
+    <listViews>`))
+		})
+
+		It("should fail on files it does not recognize", func() {
+			decomposed := salesforce.DecomposeMetadata(files)
+			decomposed["objects/Account/widgets/Foo.widget-meta.xml"] = []byte("<Widget/>")
+			_, err := salesforce.RecomposeMetadata(decomposed)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
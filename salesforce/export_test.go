@@ -0,0 +1,12 @@
+package salesforce
+
+var JWTAudience = jwtAudience
+
+// SaveMetapaths returns a function that puts back the table of metadata
+// types, for tests that replace it with UseDescribedMetapaths.
+func SaveMetapaths() (restore func()) {
+	saved := metapaths
+	return func() {
+		metapaths = saved
+	}
+}
@@ -2,6 +2,8 @@ package salesforce
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"encoding/xml"
 	"errors"
@@ -13,6 +15,7 @@ import (
 	"net/url"
 	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/joist-engineering/force/util"
 )
@@ -48,6 +51,18 @@ type Force struct {
 	Credentials ForceCredentials
 	Metadata    *ForceMetadata
 	Partner     *ForcePartner
+
+	// Client sends every request to Salesforce, including SOAP ones.
+	Client *http.Client
+	// Retry says how requests that fail for transient reasons are retried.
+	Retry RetryPolicy
+
+	// CredentialsRefreshed, if set, is called with the new credentials
+	// whenever an expired access token has been refreshed, so that they can be
+	// saved.
+	CredentialsRefreshed func(creds ForceCredentials) error
+
+	refreshLock sync.Mutex
 }
 
 type ForceCredentials struct {
@@ -63,6 +78,15 @@ type ForceCredentials struct {
 	// form of `v36.0`.
 	ApiVersion    string
 	ForceEndpoint ForceEndpoint
+	// RefreshToken, when present, is used to get a new access token once the
+	// current one expires.
+	RefreshToken string
+	// ClientId is the consumer key of the connected app the tokens were
+	// issued to.  It defaults to the client id for the endpoint.
+	ClientId string
+	// ClientSecret is the consumer secret of the connected app, for apps that
+	// require it to refresh tokens.
+	ClientSecret string
 }
 
 type LoginFault struct {
@@ -84,10 +108,29 @@ type GenericForceError struct {
 type ForceError struct {
 	Message   string
 	ErrorCode string
+	Fields    []string
 }
 
 type ForceEndpoint int
 
+// ErrAuthorizationExpired matches, with errors.Is, an *APIError for an access
+// token that Salesforce rejects.
+var ErrAuthorizationExpired = errors.New("authorization expired, please run `force login`")
+
+// ErrForbidden matches, with errors.Is, an *APIError for a request that
+// Salesforce refuses, which may also mean that the access token has expired.
+var ErrForbidden = errors.New("Forbidden; Your authorization may have expired, or you do not have access. Please run `force login` and try again")
+
+// RefreshError is returned when an expired access token couldn't be refreshed
+// because the token endpoint refused the refresh token as an invalid grant.
+type RefreshError struct {
+	Reason string
+}
+
+func (e *RefreshError) Error() string {
+	return fmt.Sprintf("authorization expired and could not be refreshed (%s), please run `force login`", e.Reason)
+}
+
 type ForceRecord map[string]interface{}
 
 type ForceSobject map[string]interface{}
@@ -98,6 +141,13 @@ type ForceCreateRecordResult struct {
 	Success bool
 }
 
+// ForceUpsertRecordResult is the record an upsert created or updated.
+// Updates made with API versions before 46.0 don't return the id.
+type ForceUpsertRecordResult struct {
+	Id      string
+	Created bool
+}
+
 type ForceLimits map[string]ForceLimit
 
 type ForceLimit struct {
@@ -121,6 +171,33 @@ type ForceQueryResult struct {
 	NextRecordsUrl string
 }
 
+// ForceQueryExplanation is the query plans Salesforce considered for a query,
+// cheapest first.
+type ForceQueryExplanation struct {
+	Plans       []ForceQueryPlan `json:"plans"`
+	SourceQuery string           `json:"sourceQuery"`
+}
+
+// ForceQueryPlan is one way of running a query.  A RelativeCost above 1 means
+// it is slower than scanning the whole table.
+type ForceQueryPlan struct {
+	Cardinality          int                  `json:"cardinality"`
+	Fields               []string             `json:"fields"`
+	LeadingOperationType string               `json:"leadingOperationType"`
+	Notes                []ForceQueryPlanNote `json:"notes"`
+	RelativeCost         float64              `json:"relativeCost"`
+	SobjectCardinality   int                  `json:"sobjectCardinality"`
+	SobjectType          string               `json:"sobjectType"`
+}
+
+// ForceQueryPlanNote says why a plan couldn't use an index, or other things
+// that affected it.
+type ForceQueryPlanNote struct {
+	Description   string   `json:"description"`
+	Fields        []string `json:"fields"`
+	TableEnumOrId string   `json:"tableEnumOrId"`
+}
+
 type ForceSobjectsResult struct {
 	Encoding     string
 	MaxBatchSize int
@@ -169,6 +246,7 @@ type JobInfo struct {
 	TotalProcessingTime     int    `xml:"totalProcessingTime"`
 	ApiActiveProcessingTime int    `xml:"apiActiveProcessingTime"`
 	ApexProcessingTime      int    `xml:"apexProcessingTime"`
+	ExternalIdFieldName     string `xml:"externalIdFieldName"`
 }
 
 type AuraDefinitionBundleResult struct {
@@ -231,6 +309,8 @@ func (creds *ForceCredentials) ApiVersionNumber() string {
 func NewForce(creds ForceCredentials) (force *Force) {
 	force = new(Force)
 	force.Credentials = creds
+	force.Client = SharedHTTPClient()
+	force.Retry = DefaultRetryPolicy
 	force.Metadata = NewForceMetadata(force)
 	force.Partner = NewForcePartner(force)
 	return
@@ -274,7 +354,15 @@ func ForceSoapLogin(endpoint ForceEndpoint, apiVersion string, username string,
 	}
 	instanceUrl := u.Scheme + "://" + u.Host
 	identity := u.Scheme + "://" + u.Host + "/id/" + orgid + "/" + result.Id
-	creds = ForceCredentials{result.SessionId, identity, result.Id, instanceUrl, "", "", endpoint == EndpointCustom, "", apiVersion, endpoint}
+	creds = ForceCredentials{
+		AccessToken:   result.SessionId,
+		Id:            identity,
+		UserId:        result.Id,
+		InstanceUrl:   instanceUrl,
+		IsCustomEP:    endpoint == EndpointCustom,
+		ApiVersion:    apiVersion,
+		ForceEndpoint: endpoint,
+	}
 
 	f := NewForce(creds)
 	url := "https://force-cli"
@@ -321,9 +409,38 @@ func ForceLogin(endpoint ForceEndpoint) (creds ForceCredentials, err error) {
 	err = util.Open(url)
 	creds = <-ch
 	creds.ForceEndpoint = endpoint
+	creds.ClientId = clientIdForEndpoint(endpoint)
 	return
 }
 
+// loginUrl returns the URL of the login server for an endpoint.
+func loginUrl(endpoint ForceEndpoint) (string, error) {
+	switch endpoint {
+	case EndpointProduction:
+		return "https://login.salesforce.com", nil
+	case EndpointTest:
+		return "https://test.salesforce.com", nil
+	case EndpointPrerelease:
+		return "https://prerellogin.pre.salesforce.com", nil
+	case EndpointMobile1:
+		return "https://mobile1.t.salesforce.com", nil
+	case EndpointCustom:
+		return CustomEndpoint, nil
+	}
+	return "", errors.New("unknown endpoint type")
+}
+
+func clientIdForEndpoint(endpoint ForceEndpoint) string {
+	switch endpoint {
+	case EndpointPrerelease:
+		return PrereleaseClientId
+	case EndpointMobile1:
+		return Mobile1ClientId
+	default:
+		return ProductionClientId
+	}
+}
+
 func (f *Force) GetCodeCoverage(classId string, className string) (err error) {
 	url := fmt.Sprintf("%s/services/data/%s/query/?q=Select+Id+From+ApexClass+Where+Name+=+'%s'", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, className)
 
@@ -588,33 +705,38 @@ func (f *Force) GetSobject(name string) (sobject ForceSobject, err error) {
 	return
 }
 
+// Query runs a query and returns all of its records.  Use NewQueryIterator
+// for queries that may be too big to hold in memory.
 func (f *Force) Query(query string, isTooling bool) (result ForceQueryResult, err error) {
-	vurl := ""
-	if isTooling == true {
-		vurl = fmt.Sprintf("%s/services/data/%s/tooling/query?q=%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, url.QueryEscape(query))
-	} else {
-		vurl = fmt.Sprintf("%s/services/data/%s/query?q=%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, url.QueryEscape(query))
-	}
-	body, err := f.httpGet(vurl)
+	return readQuery(f.NewQueryIterator(context.Background(), query, isTooling))
+}
+
+// QueryAll runs a query like Query, but includes records that have been
+// deleted and are in the recycle bin, or that have been archived.
+func (f *Force) QueryAll(query string) (result ForceQueryResult, err error) {
+	return readQuery(f.NewQueryAllIterator(context.Background(), query))
+}
+
+// ExplainQuery asks Salesforce how it would run a query, without running it.
+func (f *Force) ExplainQuery(query string) (explanation ForceQueryExplanation, err error) {
+	aurl := fmt.Sprintf("%s/services/data/%s/query?explain=%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, url.QueryEscape(query))
+	body, err := f.httpGet(aurl)
 	if err != nil {
 		return
 	}
-	json.Unmarshal(body, &result)
-	if result.Done == false {
-		var nextResult ForceQueryResult
-		nextResult.NextRecordsUrl = result.NextRecordsUrl
-		for nextResult.Done == false {
-			nextUrl := fmt.Sprintf("%s%s", f.Credentials.InstanceUrl, nextResult.NextRecordsUrl)
-			nextBody, nextErr := f.httpGet(nextUrl)
-			if nextErr != nil {
-				return
-			}
-			nextResult.Records = []ForceRecord{}
-			json.Unmarshal(nextBody, &nextResult)
+	err = json.Unmarshal(body, &explanation)
+	return
+}
 
-			result.Records = append(result.Records, nextResult.Records...)
-		}
+func readQuery(it *QueryIterator) (result ForceQueryResult, err error) {
+	for it.NextPage() {
+		result.Records = append(result.Records, it.Records()...)
 	}
+	if err = it.Err(); err != nil {
+		return
+	}
+	result.Done = true
+	result.TotalSize = it.TotalSize()
 	return
 }
 
@@ -683,6 +805,12 @@ func (f *Force) GetRecord(sobject, id string) (object ForceRecord, err error) {
 }
 
 func (f *Force) CreateRecord(sobject string, attrs map[string]string) (id string, err error, emessages []ForceError) {
+	return f.CreateRecordValues(sobject, stringValues(attrs))
+}
+
+// CreateRecordValues creates a record with fields of any JSON type, such as
+// numbers and booleans, rather than only strings.
+func (f *Force) CreateRecordValues(sobject string, attrs map[string]interface{}) (id string, err error, emessages []ForceError) {
 	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, sobject)
 	body, err, emessages := f.httpPost(url, attrs)
 	var result ForceCreateRecordResult
@@ -695,10 +823,8 @@ func (f *Force) CreateBulkJob(xmlbody string) (result JobInfo, err error) {
 	url := fmt.Sprintf("%s/services/async/%s/job", f.Credentials.InstanceUrl, f.Credentials.ApiVersionNumber())
 	body, err := f.httpPostXML(url, xmlbody)
 	xml.Unmarshal(body, &result)
-	if len(result.Id) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(body, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && len(result.Id) == 0 {
+		err = newAPIError(http.StatusOK, body)
 	}
 	return
 }
@@ -707,10 +833,8 @@ func (f *Force) CloseBulkJob(jobId string, xmlbody string) (result JobInfo, err
 	url := fmt.Sprintf("%s/services/async/%s/job/%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersionNumber(), jobId)
 	body, err := f.httpPostXML(url, xmlbody)
 	xml.Unmarshal(body, &result)
-	if len(result.Id) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(body, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && len(result.Id) == 0 {
+		err = newAPIError(http.StatusOK, body)
 	}
 	return
 }
@@ -719,10 +843,8 @@ func (f *Force) GetBulkJobs() (result []JobInfo, err error) {
 	url := fmt.Sprintf("%s/services/async/%s/jobs", f.Credentials.InstanceUrl, f.Credentials.ApiVersionNumber())
 	body, err := f.httpGetBulk(url)
 	xml.Unmarshal(body, &result)
-	if len(result[0].Id) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(body, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && (len(result) == 0 || len(result[0].Id) == 0) {
+		err = newAPIError(http.StatusOK, body)
 	}
 	return
 }
@@ -738,10 +860,8 @@ func (f *Force) BulkQuery(soql string, jobId string, contettype string) (result
 		body, err = f.httpPostXML(url, soql)
 		xml.Unmarshal(body, &result)
 	}
-	if len(result.Id) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(body, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && len(result.Id) == 0 {
+		err = newAPIError(http.StatusOK, body)
 	}
 	return
 }
@@ -750,10 +870,8 @@ func (f *Force) AddBatchToJob(xmlbody string, jobId string) (result BatchInfo, e
 	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch", f.Credentials.InstanceUrl, f.Credentials.ApiVersionNumber(), jobId)
 	body, err := f.httpPostCSV(url, xmlbody)
 	xml.Unmarshal(body, &result)
-	if len(result.Id) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(body, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && len(result.Id) == 0 {
+		err = newAPIError(http.StatusOK, body)
 	}
 	return
 }
@@ -762,10 +880,8 @@ func (f *Force) GetBatchInfo(jobId string, batchId string) (result BatchInfo, er
 	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersionNumber(), jobId, batchId)
 	body, err := f.httpGetBulk(url)
 	xml.Unmarshal(body, &result)
-	if len(result.Id) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(body, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && len(result.Id) == 0 {
+		err = newAPIError(http.StatusOK, body)
 	}
 	return
 }
@@ -780,10 +896,8 @@ func (f *Force) GetBatches(jobId string) (result []BatchInfo, err error) {
 
 	xml.Unmarshal(body, &batchInfoList)
 	result = batchInfoList.BatchInfos
-	if len(result) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(body, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && len(result) == 0 {
+		err = newAPIError(http.StatusOK, body)
 	}
 	return
 }
@@ -792,10 +906,8 @@ func (f *Force) GetJobInfo(jobId string) (result JobInfo, err error) {
 	url := fmt.Sprintf("%s/services/async/%s/job/%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersionNumber(), jobId)
 	body, err := f.httpGetBulk(url)
 	xml.Unmarshal(body, &result)
-	if len(result.Id) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(body, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && len(result.Id) == 0 {
+		err = newAPIError(http.StatusOK, body)
 	}
 	return
 }
@@ -815,12 +927,65 @@ func (f *Force) RetrieveBulkQueryResults(jobId string, batchId string, resultId
 func (f *Force) RetrieveBulkBatchResults(jobId string, batchId string) (results BatchResult, err error) {
 	url := fmt.Sprintf("%s/services/async/%s/job/%s/batch/%s/result", f.Credentials.InstanceUrl, f.Credentials.ApiVersionNumber(), jobId, batchId)
 	result, err := f.httpGetBulk(url)
-	if len(result) == 0 {
-		var fault LoginFault
-		xml.Unmarshal(result, &fault)
-		err = errors.New(fmt.Sprintf("%s: %s", fault.ExceptionCode, fault.ExceptionMessage))
+	if err == nil && len(result) == 0 {
+		err = &APIError{StatusCode: http.StatusOK, Message: "no batch results"}
+	}
+	if err != nil {
+		return
+	}
+	results.Results, err = parseBatchResults(result)
+	return
+}
+
+// parseBatchResults reads the result of each row of a batch, which is CSV
+// ("Id","Success","Created","Error") for CSV jobs and XML for XML jobs.
+func parseBatchResults(data []byte) (results []Result, err error) {
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		var parsed struct {
+			Results []struct {
+				Id      string `xml:"id"`
+				Success bool   `xml:"success"`
+				Created bool   `xml:"created"`
+				Errors  []struct {
+					Message string `xml:"message"`
+				} `xml:"errors"`
+			} `xml:"result"`
+		}
+		if err = xml.Unmarshal(data, &parsed); err != nil {
+			return
+		}
+		for _, r := range parsed.Results {
+			result := Result{Id: r.Id, Success: r.Success, Created: r.Created}
+			if len(r.Errors) > 0 {
+				result.Message = r.Errors[0].Message
+			}
+			results = append(results, result)
+		}
+		return
+	}
+
+	rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil || len(rows) == 0 {
+		return
+	}
+	columns := make(map[string]int)
+	for i, name := range rows[0] {
+		columns[strings.ToLower(name)] = i
+	}
+	value := func(row []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(row) {
+			return row[i]
+		}
+		return ""
+	}
+	for _, row := range rows[1:] {
+		results = append(results, Result{
+			Id:      value(row, "id"),
+			Success: value(row, "success") == "true",
+			Created: value(row, "created") == "true",
+			Message: value(row, "error"),
+		})
 	}
-	//	sreader = Reader.NewReader(result);
 	return
 }
 
@@ -923,6 +1088,12 @@ func (f *Force) DescribeSObject(objecttype string) (result string, err error) {
 }
 
 func (f *Force) UpdateRecord(sobject string, id string, attrs map[string]string) (err error) {
+	return f.UpdateRecordValues(sobject, id, stringValues(attrs))
+}
+
+// UpdateRecordValues updates a record with fields of any JSON type, as
+// CreateRecordValues creates one.
+func (f *Force) UpdateRecordValues(sobject string, id string, attrs map[string]interface{}) (err error) {
 	fields := strings.Split(id, ":")
 	var url string
 	if len(fields) == 1 {
@@ -934,6 +1105,19 @@ func (f *Force) UpdateRecord(sobject string, id string, attrs map[string]string)
 	return
 }
 
+// UpsertRecord updates the record whose external id field has a value, or
+// creates one if there isn't one.
+func (f *Force) UpsertRecord(sobject string, extIdField string, value string, attrs map[string]interface{}) (result ForceUpsertRecordResult, err error) {
+	value = url.PathEscape(value)
+	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s/%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, sobject, extIdField, value)
+	body, err := f.httpPatch(url, attrs)
+	if err != nil || len(body) == 0 {
+		return
+	}
+	err = json.Unmarshal(body, &result)
+	return
+}
+
 func (f *Force) DeleteRecord(sobject string, id string) (err error) {
 	url := fmt.Sprintf("%s/services/data/%s/sobjects/%s/%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, sobject, id)
 	_, err = f.httpDelete(url)
@@ -947,44 +1131,37 @@ func (f *Force) Whoami() (me ForceRecord, err error) {
 }
 
 func (f *Force) httpGet(url string) (body []byte, err error) {
-	body, err = f.httpGetRequest(url, "Authorization", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
+	body, err = f.httpGetRequest(context.Background(), url, "Authorization")
 	return
 }
 
 func (f *Force) httpGetBulk(url string) (body []byte, err error) {
-	body, err = f.httpGetRequest(url, "X-SFDC-Session", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
+	body, err = f.httpGetRequest(context.Background(), url, "X-SFDC-Session")
 	return
 }
 
-func (f *Force) httpGetRequest(url string, headerName string, headerValue string) (body []byte, err error) {
-	req, err := httpRequest("GET", url, nil)
-	if err != nil {
+func (f *Force) httpGetContext(ctx context.Context, url string) (body []byte, err error) {
+	body, err = f.httpGetRequest(ctx, url, "Authorization")
+	return
+}
+
+func (f *Force) httpGetRequest(ctx context.Context, url string, headerName string) (body []byte, err error) {
+	res, err := f.httpDo(true, func(token string) (req *http.Request, err error) {
+		req, err = httpRequest("GET", url, nil)
+		if err != nil {
+			return
+		}
+		req = req.WithContext(ctx)
+		req.Header.Add(headerName, fmt.Sprintf("Bearer %s", token))
 		return
-	}
-	req.Header.Add(headerName, headerValue)
-	res, err := doRequest(req)
+	})
 	if err != nil {
 		return
 	}
 	defer res.Body.Close()
-	if res.StatusCode == 401 {
-		err = errors.New("authorization expired, please run `force login`")
-		return
-	}
-	if res.StatusCode == 403 {
-		err = errors.New("Forbidden; Your authorization may have expired, or you do not have access. Please run `force login` and try again")
-		return
-	}
 	body, err = ioutil.ReadAll(res.Body)
-	if res.StatusCode/100 != 2 {
-		var messages []ForceError
-		json.Unmarshal(body, &messages)
-		if len(messages) > 0 {
-			err = errors.New(messages[0].Message)
-		} else {
-			err = errors.New(string(body))
-		}
-		return
+	if err == nil && res.StatusCode/100 != 2 {
+		err = newAPIError(res.StatusCode, body)
 	}
 	return
 }
@@ -1000,121 +1177,223 @@ func (f *Force) httpPostXML(url string, data string) (body []byte, err error) {
 }
 
 func (f *Force) httpPostWithContentType(url string, data string, contenttype string) (body []byte, err error) {
-	rbody := data
-	req, err := httpRequest("POST", url, bytes.NewReader([]byte(rbody)))
-	if err != nil {
+	res, err := f.httpDo(false, func(token string) (req *http.Request, err error) {
+		req, err = httpRequest("POST", url, strings.NewReader(data))
+		if err != nil {
+			return
+		}
+		req.Header.Add("X-SFDC-Session", token)
+		req.Header.Add("Content-Type", contenttype)
 		return
-	}
-
-	req.Header.Add("X-SFDC-Session", f.Credentials.AccessToken)
-	req.Header.Add("Content-Type", contenttype)
-	res, err := doRequest(req)
+	})
 	if err != nil {
 		return
 	}
 	defer res.Body.Close()
-	if res.StatusCode == 401 {
-		err = errors.New("authorization expired, please run `force login`")
-		return
-	}
 	body, err = ioutil.ReadAll(res.Body)
+	if err == nil && res.StatusCode/100 != 2 {
+		err = newAPIError(res.StatusCode, body)
+	}
+	return
+}
 
-	if res.StatusCode/100 != 2 {
-		var messages []ForceError
-		json.Unmarshal(body, &messages)
-		if messages != nil {
-			err = errors.New(messages[0].Message)
+func (f *Force) httpPost(url string, attrs interface{}) (body []byte, err error, emessages []ForceError) {
+	rbody, _ := json.Marshal(attrs)
+	res, err := f.httpDo(false, func(token string) (req *http.Request, err error) {
+		req, err = httpRequest("POST", url, bytes.NewReader(rbody))
+		if err != nil {
+			return
 		}
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Add("Content-Type", "application/json")
+		return
+	})
+	if err != nil {
 		return
 	}
+	defer res.Body.Close()
+	body, err = ioutil.ReadAll(res.Body)
+	if err == nil && res.StatusCode/100 != 2 {
+		json.Unmarshal(body, &emessages)
+		err = newAPIError(res.StatusCode, body)
+	}
 	return
 }
 
-func (f *Force) httpPost(url string, attrs map[string]string) (body []byte, err error, emessages []ForceError) {
-	rbody, _ := json.Marshal(attrs)
-	req, err := httpRequest("POST", url, bytes.NewReader(rbody))
+func (f *Force) httpPatch(url string, attrs interface{}) (body []byte, err error) {
+	return f.httpSendJSON("PATCH", url, attrs)
+}
+
+// httpSendJSON sends data as JSON, with a method such as POST or PATCH.
+func (f *Force) httpSendJSON(method, url string, data interface{}) (body []byte, err error) {
+	rbody, err := json.Marshal(data)
 	if err != nil {
 		return
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
-	req.Header.Add("Content-Type", "application/json")
-	res, err := doRequest(req)
+	res, err := f.httpDo(false, func(token string) (req *http.Request, err error) {
+		req, err = httpRequest(method, url, bytes.NewReader(rbody))
+		if err != nil {
+			return
+		}
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
+		req.Header.Add("Content-Type", "application/json")
+		return
+	})
 	if err != nil {
 		return
 	}
 	defer res.Body.Close()
-	if res.StatusCode == 401 {
-		err = errors.New("authorization expired, please run `force login`")
-		return
-	}
 	body, err = ioutil.ReadAll(res.Body)
-	if res.StatusCode/100 != 2 {
-		var messages []ForceError
-		json.Unmarshal(body, &messages)
-		err = errors.New(messages[0].Message)
-		emessages = messages
-		return
+	if err == nil && res.StatusCode/100 != 2 {
+		err = newAPIError(res.StatusCode, body)
 	}
 	return
 }
 
-func (f *Force) httpPatch(url string, attrs map[string]string) (body []byte, err error) {
-	rbody, _ := json.Marshal(attrs)
-	req, err := httpRequest("PATCH", url, bytes.NewReader(rbody))
-	if err != nil {
+func (f *Force) httpDelete(url string) (body []byte, err error) {
+	res, err := f.httpDo(false, func(token string) (req *http.Request, err error) {
+		req, err = httpRequest("DELETE", url, nil)
+		if err != nil {
+			return
+		}
+		req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 		return
-	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
-	req.Header.Add("Content-Type", "application/json")
-	res, err := doRequest(req)
+	})
 	if err != nil {
 		return
 	}
 	defer res.Body.Close()
-	if res.StatusCode == 401 {
-		err = errors.New("Authorization expired, please run `force login`")
+	body, err = ioutil.ReadAll(res.Body)
+	if err == nil && res.StatusCode/100 != 2 {
+		err = newAPIError(res.StatusCode, body)
+	}
+	return
+}
+
+// httpDo sends the request built by newRequest with the current access token,
+// retrying transient failures as f.Retry allows.  If the token has expired and
+// there is a refresh token, it gets a new access token and sends the request
+// once more.  newRequest is called again for each retry, so that the request
+// body can be read a second time.
+func (f *Force) httpDo(idempotent bool, newRequest func(token string) (*http.Request, error)) (res *http.Response, err error) {
+	return f.httpDoUnless(idempotent, newRequest, func(res *http.Response) bool {
+		return res.StatusCode == http.StatusUnauthorized
+	})
+}
+
+// httpDoUnless is httpDo for APIs that say the token has expired other than
+// with a 401, which expired tells apart.
+func (f *Force) httpDoUnless(idempotent bool, newRequest func(token string) (*http.Request, error), expired func(*http.Response) bool) (res *http.Response, err error) {
+	token := f.accessToken()
+	res, err = f.Retry.Do(f.Client, idempotent, func() (*http.Request, error) {
+		return newRequest(token)
+	})
+	if err != nil || f.Credentials.RefreshToken == "" || !expired(res) {
 		return
 	}
-	body, err = ioutil.ReadAll(res.Body)
-	if res.StatusCode/100 != 2 {
-		var messages []ForceError
-		json.Unmarshal(body, &messages)
-		err = errors.New(messages[0].Message)
+	res.Body.Close()
+
+	if err = f.RefreshSession(token); err != nil {
+		return nil, err
+	}
+	token = f.accessToken()
+	return f.Retry.Do(f.Client, idempotent, func() (*http.Request, error) {
+		return newRequest(token)
+	})
+}
+
+func (f *Force) accessToken() string {
+	f.refreshLock.Lock()
+	defer f.refreshLock.Unlock()
+	return f.Credentials.AccessToken
+}
+
+// RefreshSession exchanges the refresh token for a new access token, and
+// passes the new credentials to CredentialsRefreshed.  expiredToken is the
+// access token that was rejected; if another request has already replaced it,
+// the new token is used as it is rather than being refreshed again.
+func (f *Force) RefreshSession(expiredToken string) (err error) {
+	f.refreshLock.Lock()
+	defer f.refreshLock.Unlock()
+	if f.Credentials.AccessToken != expiredToken {
 		return
 	}
+
+	clientId := f.Credentials.ClientId
+	if clientId == "" {
+		clientId = clientIdForEndpoint(f.Credentials.ForceEndpoint)
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {f.Credentials.RefreshToken},
+		"client_id":     {clientId},
+	}
+	if f.Credentials.ClientSecret != "" {
+		form.Set("client_secret", f.Credentials.ClientSecret)
+	}
+	token, err := requestToken(f.Client, f.Credentials.InstanceUrl, form)
+	if err != nil {
+		// Only invalid_grant means the refresh token is no good; anything
+		// else, such as the token endpoint being unavailable, may pass.
+		var apiError *APIError
+		if errors.As(err, &apiError) && isInvalidGrant(apiError) {
+			return &RefreshError{Reason: apiError.Error()}
+		}
+		return
+	}
+	f.Credentials.AccessToken = token.AccessToken
+	f.Credentials.IssuedAt = token.IssuedAt
+	if f.CredentialsRefreshed != nil {
+		err = f.CredentialsRefreshed(f.Credentials)
+	}
 	return
 }
 
-func (f *Force) httpDelete(url string) (body []byte, err error) {
-	req, err := httpRequest("DELETE", url, nil)
+// isInvalidGrant reports whether the token endpoint refused a grant because
+// it has expired or been revoked.
+func isInvalidGrant(e *APIError) bool {
+	return (e.StatusCode == http.StatusBadRequest || e.StatusCode == http.StatusUnauthorized) && e.ErrorCode == "invalid_grant"
+}
+
+// oauthToken is the response from the OAuth token endpoint.
+type oauthToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	InstanceUrl  string `json:"instance_url"`
+	Id           string `json:"id"`
+	IssuedAt     string `json:"issued_at"`
+	Scope        string `json:"scope"`
+}
+
+// requestToken posts an OAuth grant to the token endpoint of the given server.
+func requestToken(client *http.Client, serverUrl string, form url.Values) (token oauthToken, err error) {
+	req, err := httpRequest("POST", serverUrl+"/services/oauth2/token", strings.NewReader(form.Encode()))
 	if err != nil {
 		return
 	}
-	req.Header.Add("Authorization", fmt.Sprintf("Bearer %s", f.Credentials.AccessToken))
-	res, err := doRequest(req)
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	res, err := client.Do(req)
 	if err != nil {
 		return
 	}
 	defer res.Body.Close()
-	if res.StatusCode == 401 {
-		err = errors.New("authorization expired, please run `force login`")
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
 		return
 	}
-	body, err = ioutil.ReadAll(res.Body)
 	if res.StatusCode/100 != 2 {
-		var messages []ForceError
-		json.Unmarshal(body, &messages)
-		err = errors.New(messages[0].Message)
+		err = newAPIError(res.StatusCode, body)
 		return
 	}
+	if err = json.Unmarshal(body, &token); err != nil {
+		return
+	}
+	if token.AccessToken == "" {
+		err = errors.New("no access token in the response")
+	}
 	return
 }
 
-func doRequest(request *http.Request) (res *http.Response, err error) {
-	client := &http.Client{}
-	return client.Do(request)
-}
-
 func httpRequest(method, url string, body io.Reader) (request *http.Request, err error) {
 	request, err = http.NewRequest(method, url, body)
 	if err != nil {
@@ -1148,6 +1427,7 @@ func startLocalHttpServer(ch chan ForceCredentials) (port int, err error) {
 			creds.InstanceUrl = query.Get("instance_url")
 			creds.IssuedAt = query.Get("issued_at")
 			creds.Scope = query.Get("scope")
+			creds.RefreshToken = query.Get("refresh_token")
 			ch <- creds
 			if _, ok := r.Header["X-Requested-With"]; ok == false {
 				http.Redirect(w, r, fmt.Sprintf("%s/auth/complete", url), http.StatusSeeOther)
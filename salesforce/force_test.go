@@ -0,0 +1,290 @@
+package salesforce_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Force", func() {
+	Describe("refreshing an expired access token", func() {
+		var (
+			server       *httptest.Server
+			force        *salesforce.Force
+			validToken   string
+			refreshes    int32
+			refreshForm  chan map[string]string
+			refreshError string
+			refreshDown  bool
+			saved        []salesforce.ForceCredentials
+			bodies       []string
+			lock         sync.Mutex
+		)
+
+		BeforeEach(func() {
+			validToken = "new-token"
+			refreshes = 0
+			refreshError = ""
+			refreshDown = false
+			saved = nil
+			bodies = nil
+			refreshForm = make(chan map[string]string, 100)
+
+			mux := http.NewServeMux()
+			mux.HandleFunc("/services/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&refreshes, 1)
+				r.ParseForm()
+				refreshForm <- map[string]string{
+					"grant_type":    r.PostForm.Get("grant_type"),
+					"refresh_token": r.PostForm.Get("refresh_token"),
+					"client_id":     r.PostForm.Get("client_id"),
+				}
+				if refreshDown {
+					w.WriteHeader(503)
+					return
+				}
+				if refreshError != "" {
+					w.WriteHeader(400)
+					w.Write([]byte(`{"error":"invalid_grant","error_description":"` + refreshError + `"}`))
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]string{
+					"access_token": validToken,
+					"issued_at":    "1500000000000",
+				})
+			})
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer "+validToken {
+					w.WriteHeader(401)
+					w.Write([]byte(`[{"message":"Session expired or invalid","errorCode":"INVALID_SESSION_ID"}]`))
+					return
+				}
+				body, _ := ioutil.ReadAll(r.Body)
+				lock.Lock()
+				bodies = append(bodies, string(body))
+				lock.Unlock()
+				w.Write([]byte(`{"id":"001000000000001","success":true}`))
+			})
+			server = httptest.NewServer(mux)
+
+			force = salesforce.NewForce(salesforce.ForceCredentials{
+				AccessToken:  "old-token",
+				InstanceUrl:  server.URL,
+				ApiVersion:   "v45.0",
+				RefreshToken: "refresh-token",
+				ClientId:     "client-id",
+			})
+			force.CredentialsRefreshed = func(creds salesforce.ForceCredentials) error {
+				lock.Lock()
+				defer lock.Unlock()
+				saved = append(saved, creds)
+				return nil
+			}
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		It("should refresh the token and retry the request", func() {
+			record, err := force.Get(server.URL + "/services/data/v45.0/sobjects/Account/001000000000001")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(record["id"]).To(Equal("001000000000001"))
+			Expect(<-refreshForm).To(Equal(map[string]string{
+				"grant_type":    "refresh_token",
+				"refresh_token": "refresh-token",
+				"client_id":     "client-id",
+			}))
+			Expect(force.Credentials.AccessToken).To(Equal("new-token"))
+		})
+
+		It("should save the new credentials", func() {
+			_, err := force.Get(server.URL + "/services/data/v45.0/limits")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(saved).To(HaveLen(1))
+			Expect(saved[0].AccessToken).To(Equal("new-token"))
+			Expect(saved[0].IssuedAt).To(Equal("1500000000000"))
+			Expect(saved[0].RefreshToken).To(Equal("refresh-token"))
+		})
+
+		It("should send the request body again", func() {
+			id, err, _ := force.CreateRecord("Account", map[string]string{"Name": "Acme"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(id).To(Equal("001000000000001"))
+			Expect(bodies).To(Equal([]string{`{"Name":"Acme"}`}))
+		})
+
+		It("should send values of any JSON type with CreateRecordValues", func() {
+			_, err, _ := force.CreateRecordValues("Account", map[string]interface{}{"NumberOfEmployees": 250})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bodies).To(Equal([]string{`{"NumberOfEmployees":250}`}))
+		})
+
+		It("should only refresh once for concurrent requests", func() {
+			var wg sync.WaitGroup
+			errs := make(chan error, 10)
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, err := force.Get(server.URL + "/services/data/v45.0/limits")
+					errs <- err
+				}()
+			}
+			wg.Wait()
+			close(errs)
+			for err := range errs {
+				Expect(err).ToNot(HaveOccurred())
+			}
+			Expect(atomic.LoadInt32(&refreshes)).To(Equal(int32(1)))
+			Expect(saved).To(HaveLen(1))
+		})
+
+		It("should report a refresh token that has been revoked", func() {
+			refreshError = "expired access/refresh token"
+			_, err := force.Get(server.URL + "/services/data/v45.0/limits")
+			Expect(err).To(MatchError(ContainSubstring("expired access/refresh token")))
+			Expect(err).To(MatchError(ContainSubstring("force login")))
+			Expect(saved).To(BeEmpty())
+		})
+
+		It("should not take a token endpoint that is down for a revoked refresh token", func() {
+			refreshDown = true
+			force.Retry = salesforce.RetryPolicy{}
+			_, err := force.Get(server.URL + "/services/data/v45.0/limits")
+			var refreshErr *salesforce.RefreshError
+			Expect(errors.As(err, &refreshErr)).To(BeFalse())
+			var apiError *salesforce.APIError
+			Expect(errors.As(err, &apiError)).To(BeTrue())
+			Expect(apiError.StatusCode).To(Equal(503))
+		})
+
+		It("should not try to refresh without a refresh token", func() {
+			force.Credentials.RefreshToken = ""
+			_, err := force.Get(server.URL + "/services/data/v45.0/limits")
+			Expect(errors.Is(err, salesforce.ErrAuthorizationExpired)).To(BeTrue())
+			Expect(atomic.LoadInt32(&refreshes)).To(Equal(int32(0)))
+		})
+	})
+
+	Describe("refreshing an expired session for a SOAP call", func() {
+		It("should refresh the access token and make the call again", func() {
+			var calls, refreshes int32
+			var server *httptest.Server
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case r.URL.Path == "/services/oauth2/token":
+					atomic.AddInt32(&refreshes, 1)
+					w.Write([]byte(`{"access_token":"new-token","issued_at":"1500000000000"}`))
+				case r.Method == "GET":
+					w.Write([]byte(`{"urls":{"metadata":"` + server.URL + `/services/Soap/m/{version}"}}`))
+				default:
+					atomic.AddInt32(&calls, 1)
+					body, _ := ioutil.ReadAll(r.Body)
+					if !strings.Contains(string(body), "<cmd:sessionId>new-token</cmd:sessionId>") {
+						w.WriteHeader(500)
+						w.Write([]byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><soapenv:Fault><faultcode>sf:INVALID_SESSION_ID</faultcode><faultstring>INVALID_SESSION_ID: Invalid Session ID found in SessionHeader</faultstring></soapenv:Fault></soapenv:Body></soapenv:Envelope>`))
+						return
+					}
+					w.Write([]byte(`<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><checkStatusResponse><result><done>true</done><state>Completed</state></result></checkStatusResponse></soapenv:Body></soapenv:Envelope>`))
+				}
+			}))
+			defer server.Close()
+			force := salesforce.NewForce(salesforce.ForceCredentials{
+				AccessToken:  "old-token",
+				RefreshToken: "refresh",
+				InstanceUrl:  server.URL,
+				Id:           server.URL + "/id/00D/005",
+				ApiVersion:   "v45.0",
+			})
+			force.Retry = salesforce.RetryPolicy{}
+
+			Expect(force.Metadata.CheckStatus("0Af000000000001")).To(Succeed())
+			Expect(atomic.LoadInt32(&refreshes)).To(Equal(int32(1)))
+			Expect(atomic.LoadInt32(&calls)).To(Equal(int32(2)))
+			Expect(force.Credentials.AccessToken).To(Equal("new-token"))
+		})
+	})
+
+	Describe("upserting a record", func() {
+		var (
+			method   string
+			path     string
+			status   int
+			response string
+		)
+
+		org := serveOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.EscapedPath()
+			w.WriteHeader(status)
+			w.Write([]byte(response))
+		}))
+
+		It("should patch the record by its external id and say it was created", func() {
+			status, response = http.StatusCreated, `{"id":"001A","success":true,"errors":[],"created":true}`
+			result, err := org.force.UpsertRecord("Account", "External_Id__c", "A/1001", map[string]interface{}{"Name": "Acme"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(method).To(Equal("PATCH"))
+			Expect(path).To(Equal("/services/data/v45.0/sobjects/Account/External_Id__c/A%2F1001"))
+			Expect(result).To(Equal(salesforce.ForceUpsertRecordResult{Id: "001A", Created: true}))
+		})
+
+		It("should say it was updated", func() {
+			status, response = http.StatusOK, `{"id":"001A","success":true,"errors":[],"created":false}`
+			result, err := org.force.UpsertRecord("Account", "External_Id__c", "A-1001", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(salesforce.ForceUpsertRecordResult{Id: "001A"}))
+
+			status, response = http.StatusNoContent, ""
+			result, err = org.force.UpsertRecord("Account", "External_Id__c", "A-1001", nil)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Created).To(BeFalse())
+		})
+	})
+
+	Describe("retrieving the results of a bulk batch", func() {
+		var response string
+
+		org := serveOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(response))
+		}))
+
+		It("should read the results of a CSV batch", func() {
+			response = "\"Id\",\"Success\",\"Created\",\"Error\"\n" +
+				"\"001A\",\"true\",\"true\",\"\"\n" +
+				"\"001B\",\"true\",\"false\",\"\"\n" +
+				"\"\",\"false\",\"false\",\"REQUIRED_FIELD_MISSING:Required fields are missing: [Name]:Name --\"\n"
+			result, err := org.force.RetrieveBulkBatchResults("750A", "751A")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Results).To(Equal([]salesforce.Result{
+				{Id: "001A", Success: true, Created: true},
+				{Id: "001B", Success: true},
+				{Message: "REQUIRED_FIELD_MISSING:Required fields are missing: [Name]:Name --"},
+			}))
+		})
+
+		It("should read the results of an XML batch", func() {
+			response = `<?xml version="1.0" encoding="UTF-8"?>
+<results xmlns="http://www.force.com/2009/06/asyncapi/dataload">
+ <result><id>001A</id><success>true</success><created>false</created></result>
+ <result><success>false</success><created>false</created><errors><message>bad value</message></errors></result>
+</results>`
+			result, err := org.force.RetrieveBulkBatchResults("750A", "751A")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.Results).To(Equal([]salesforce.Result{
+				{Id: "001A", Success: true},
+				{Message: "bad value"},
+			}))
+		})
+	})
+})
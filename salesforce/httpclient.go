@@ -0,0 +1,120 @@
+package salesforce
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joist-engineering/force/util"
+)
+
+// CAFileVariable is the environment variable naming a PEM file of extra
+// certificate authorities to trust, such as for a corporate proxy.
+const CAFileVariable = "FORCE_CA_FILE"
+
+const (
+	DefaultTimeout        = 10 * time.Minute
+	DefaultConnectTimeout = 30 * time.Second
+)
+
+// HTTPOptions configures the HTTP client used to talk to Salesforce.
+type HTTPOptions struct {
+	// Timeout limits how long each request waits for the response to
+	// start.  Reading the body isn't limited, so that big retrieves and
+	// downloads aren't cut off.  Zero means no limit.
+	Timeout time.Duration
+	// ConnectTimeout limits making a connection, and the TLS handshake.
+	ConnectTimeout time.Duration
+	// CAFile is a PEM file of certificate authorities to trust as well as
+	// the system ones.
+	CAFile string
+}
+
+// DefaultHTTPOptions reads the HTTP options from the environment, and the
+// "timeout" and "connecttimeout" settings (in seconds) from the config store.
+func DefaultHTTPOptions() HTTPOptions {
+	return HTTPOptions{
+		Timeout:        configDuration("timeout", DefaultTimeout),
+		ConnectTimeout: configDuration("connecttimeout", DefaultConnectTimeout),
+		CAFile:         os.Getenv(CAFileVariable),
+	}
+}
+
+func configDuration(key string, fallback time.Duration) time.Duration {
+	value, err := util.Config.Load("settings", key)
+	if err != nil {
+		return fallback
+	}
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds < 0 {
+		return fallback
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// NewHTTPClient creates an HTTP client that uses the proxy from the
+// HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables, and keeps
+// connections open for reuse.
+func NewHTTPClient(options HTTPOptions) (client *http.Client, err error) {
+	tlsConfig := &tls.Config{}
+	if options.CAFile != "" {
+		pem, err := ioutil.ReadFile(options.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + options.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   options.ConnectTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   options.ConnectTimeout,
+		ResponseHeaderTimeout: options.Timeout,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	client = &http.Client{Transport: transport}
+	return
+}
+
+var (
+	sharedClient     *http.Client
+	sharedClientOnce sync.Once
+)
+
+// SharedHTTPClient returns the HTTP client made with the default options,
+// which is used unless a Force is given another one.  If the CA file can't be
+// used, it warns and trusts only the system's certificate authorities.
+func SharedHTTPClient() *http.Client {
+	sharedClientOnce.Do(func() {
+		options := DefaultHTTPOptions()
+		var err error
+		if sharedClient, err = NewHTTPClient(options); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: not using %s: %s\n", CAFileVariable, err)
+			options.CAFile = ""
+			sharedClient, _ = NewHTTPClient(options)
+		}
+	})
+	return sharedClient
+}
@@ -0,0 +1,122 @@
+package salesforce_test
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/joist-engineering/force/salesforce"
+	"github.com/joist-engineering/force/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HTTP client", func() {
+	var (
+		server  *httptest.Server
+		tempDir string
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/slow":
+				time.Sleep(500 * time.Millisecond)
+			case "/slow-body":
+				w.Write([]byte("o"))
+				w.(http.Flusher).Flush()
+				time.Sleep(500 * time.Millisecond)
+			}
+			w.Write([]byte("ok"))
+		}))
+		tempDir, _ = ioutil.TempDir("", "httpclient-test")
+	})
+
+	AfterEach(func() {
+		server.Close()
+		os.RemoveAll(tempDir)
+	})
+
+	writeCA := func() string {
+		caFile := filepath.Join(tempDir, "ca.pem")
+		cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		Expect(ioutil.WriteFile(caFile, cert, 0600)).To(Succeed())
+		return caFile
+	}
+
+	It("should trust the certificate authorities in the CA file", func() {
+		client, err := salesforce.NewHTTPClient(salesforce.HTTPOptions{CAFile: writeCA()})
+		Expect(err).ToNot(HaveOccurred())
+		res, err := client.Get(server.URL)
+		Expect(err).ToNot(HaveOccurred())
+		res.Body.Close()
+		Expect(res.StatusCode).To(Equal(200))
+	})
+
+	It("should not trust unknown certificate authorities", func() {
+		client, err := salesforce.NewHTTPClient(salesforce.HTTPOptions{})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.Get(server.URL)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject a CA file without certificates", func() {
+		caFile := filepath.Join(tempDir, "empty.pem")
+		ioutil.WriteFile(caFile, []byte("nothing here"), 0600)
+		_, err := salesforce.NewHTTPClient(salesforce.HTTPOptions{CAFile: caFile})
+		Expect(err).To(MatchError(ContainSubstring("no certificates found")))
+	})
+
+	It("should time out slow requests", func() {
+		client, err := salesforce.NewHTTPClient(salesforce.HTTPOptions{CAFile: writeCA(), Timeout: 100 * time.Millisecond})
+		Expect(err).ToNot(HaveOccurred())
+		_, err = client.Get(server.URL + "/slow")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should not cut off a response that is slow to read", func() {
+		client, err := salesforce.NewHTTPClient(salesforce.HTTPOptions{CAFile: writeCA(), Timeout: 100 * time.Millisecond})
+		Expect(err).ToNot(HaveOccurred())
+		res, err := client.Get(server.URL + "/slow-body")
+		Expect(err).ToNot(HaveOccurred())
+		defer res.Body.Close()
+		body, err := ioutil.ReadAll(res.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("ook"))
+	})
+
+	Describe("DefaultHTTPOptions", func() {
+		var home string
+
+		BeforeEach(func() {
+			home = os.Getenv("HOME")
+			os.Setenv("HOME", tempDir)
+			os.Setenv(salesforce.CAFileVariable, "/etc/corporate-ca.pem")
+		})
+
+		AfterEach(func() {
+			os.Setenv("HOME", home)
+			os.Unsetenv(salesforce.CAFileVariable)
+		})
+
+		It("should read the timeouts from the config", func() {
+			util.Config.Save("settings", "timeout", "90")
+			util.Config.Save("settings", "connecttimeout", "2.5")
+			options := salesforce.DefaultHTTPOptions()
+			Expect(options.Timeout).To(Equal(90 * time.Second))
+			Expect(options.ConnectTimeout).To(Equal(2500 * time.Millisecond))
+			Expect(options.CAFile).To(Equal("/etc/corporate-ca.pem"))
+		})
+
+		It("should default the timeouts", func() {
+			options := salesforce.DefaultHTTPOptions()
+			Expect(options.Timeout).To(Equal(salesforce.DefaultTimeout))
+			Expect(options.ConnectTimeout).To(Equal(salesforce.DefaultConnectTimeout))
+		})
+	})
+})
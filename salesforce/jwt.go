@@ -0,0 +1,133 @@
+package salesforce
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+const jwtBearerGrantType = "urn:ietf:params:oauth:grant-type:jwt-bearer"
+
+// ForceJWTLogin logs in with the OAuth JWT bearer flow, which needs no browser
+// or password.  The connected app with the given client id must have the
+// certificate for the private key, and the user must be pre-authorized for it.
+// The audience of the assertion is the one for the endpoint's type of org
+// unless one is given, such as a community's URL.
+func ForceJWTLogin(endpoint ForceEndpoint, clientId string, username string, audience string, keyPEM []byte) (creds ForceCredentials, err error) {
+	key, err := ParseRSAPrivateKey(keyPEM)
+	if err != nil {
+		return
+	}
+	tokenUrl, err := loginUrl(endpoint)
+	if err != nil {
+		return
+	}
+	if audience == "" {
+		audience = jwtAudience(endpoint, tokenUrl)
+	}
+	assertion, err := signJWT(key, jwtClaims{
+		Issuer:    clientId,
+		Subject:   username,
+		Audience:  audience,
+		ExpiresAt: time.Now().Add(3 * time.Minute).Unix(),
+	})
+	if err != nil {
+		return
+	}
+
+	token, err := requestToken(SharedHTTPClient(), tokenUrl, url.Values{
+		"grant_type": {jwtBearerGrantType},
+		"assertion":  {assertion},
+	})
+	if err != nil {
+		return
+	}
+	creds = ForceCredentials{
+		AccessToken:   token.AccessToken,
+		Id:            token.Id,
+		InstanceUrl:   token.InstanceUrl,
+		IssuedAt:      token.IssuedAt,
+		Scope:         token.Scope,
+		IsCustomEP:    endpoint == EndpointCustom,
+		ApiVersion:    DefaultApiVersion,
+		ForceEndpoint: endpoint,
+		ClientId:      clientId,
+	}
+	return
+}
+
+// sandboxHost matches the hosts of sandboxes: My Domains such as
+// acme--uat.sandbox.my.salesforce.com or acme--uat.my.salesforce.com, and
+// instances such as cs42.salesforce.com.
+var sandboxHost = regexp.MustCompile(`(?i)(--|\.sandbox\.|^cs\d+\.|^test\.salesforce\.com$)`)
+
+// jwtAudience is the audience Salesforce wants of a JWT: test.salesforce.com
+// for sandboxes and login.salesforce.com for production orgs, whichever URL
+// the token is asked for at.
+func jwtAudience(endpoint ForceEndpoint, tokenUrl string) string {
+	switch endpoint {
+	case EndpointPrerelease, EndpointMobile1:
+		return tokenUrl
+	case EndpointTest:
+		return "https://test.salesforce.com"
+	case EndpointCustom:
+		if u, err := url.Parse(tokenUrl); err == nil && sandboxHost.MatchString(u.Hostname()) {
+			return "https://test.salesforce.com"
+		}
+	}
+	return "https://login.salesforce.com"
+}
+
+// ParseRSAPrivateKey reads a PEM encoded RSA private key, in either the PKCS#1
+// ("RSA PRIVATE KEY") or the PKCS#8 ("PRIVATE KEY") format.
+func ParseRSAPrivateKey(keyPEM []byte) (key *rsa.PrivateKey, err error) {
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errors.New("no PEM encoded private key found")
+	}
+	if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.New("unable to parse the private key: " + err.Error())
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("the private key is not an RSA key")
+	}
+	return
+}
+
+type jwtClaims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	Audience  string `json:"aud"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// signJWT encodes the claims as a JWT signed with RS256.
+func signJWT(key *rsa.PrivateKey, claims jwtClaims) (token string, err error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return
+	}
+	encoding := base64.RawURLEncoding
+	signingInput := encoding.EncodeToString(header) + "." + encoding.EncodeToString(payload)
+	hash := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	if err != nil {
+		return
+	}
+	return signingInput + "." + encoding.EncodeToString(signature), nil
+}
@@ -0,0 +1,144 @@
+package salesforce_test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ForceJWTLogin", func() {
+	var (
+		server    *httptest.Server
+		key       *rsa.PrivateKey
+		keyPEM    []byte
+		form      chan map[string]string
+		tokenFail bool
+	)
+
+	BeforeEach(func() {
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		form = make(chan map[string]string, 1)
+		tokenFail = false
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/services/oauth2/token"))
+			r.ParseForm()
+			form <- map[string]string{
+				"grant_type": r.PostForm.Get("grant_type"),
+				"assertion":  r.PostForm.Get("assertion"),
+			}
+			if tokenFail {
+				w.WriteHeader(400)
+				w.Write([]byte(`{"error":"invalid_grant","error_description":"user hasn't approved this consumer"}`))
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"access_token": "00D!token",
+				"instance_url": "https://example.my.salesforce.com",
+				"id":           "https://test.salesforce.com/id/00D000000000001/005000000000001",
+				"scope":        "api",
+			})
+		}))
+		salesforce.CustomEndpoint = server.URL
+	})
+
+	AfterEach(func() {
+		server.Close()
+		salesforce.CustomEndpoint = ""
+	})
+
+	It("should exchange a signed assertion for credentials", func() {
+		creds, err := salesforce.ForceJWTLogin(salesforce.EndpointCustom, "client-id", "user@example.com", "", keyPEM)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(creds.AccessToken).To(Equal("00D!token"))
+		Expect(creds.InstanceUrl).To(Equal("https://example.my.salesforce.com"))
+		Expect(creds.Id).To(Equal("https://test.salesforce.com/id/00D000000000001/005000000000001"))
+		Expect(creds.ClientId).To(Equal("client-id"))
+		Expect(creds.ForceEndpoint).To(BeEquivalentTo(salesforce.EndpointCustom))
+	})
+
+	It("should sign the assertion with RS256", func() {
+		_, err := salesforce.ForceJWTLogin(salesforce.EndpointCustom, "client-id", "user@example.com", "", keyPEM)
+		Expect(err).ToNot(HaveOccurred())
+		sent := <-form
+		Expect(sent["grant_type"]).To(Equal("urn:ietf:params:oauth:grant-type:jwt-bearer"))
+
+		parts := strings.Split(sent["assertion"], ".")
+		Expect(parts).To(HaveLen(3))
+		var header map[string]string
+		headerJSON, _ := base64.RawURLEncoding.DecodeString(parts[0])
+		Expect(json.Unmarshal(headerJSON, &header)).To(Succeed())
+		Expect(header["alg"]).To(Equal("RS256"))
+
+		var claims map[string]interface{}
+		claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		Expect(json.Unmarshal(claimsJSON, &claims)).To(Succeed())
+		Expect(claims["iss"]).To(Equal("client-id"))
+		Expect(claims["sub"]).To(Equal("user@example.com"))
+		Expect(claims["aud"]).To(Equal("https://login.salesforce.com"))
+		Expect(claims["exp"]).To(BeNumerically(">", time.Now().Unix()))
+
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		Expect(rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature)).To(Succeed())
+	})
+
+	It("should use the audience it is given", func() {
+		_, err := salesforce.ForceJWTLogin(salesforce.EndpointCustom, "client-id", "user@example.com", "https://acme.force.com/customers", keyPEM)
+		Expect(err).ToNot(HaveOccurred())
+		parts := strings.Split((<-form)["assertion"], ".")
+		var claims map[string]interface{}
+		claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		Expect(json.Unmarshal(claimsJSON, &claims)).To(Succeed())
+		Expect(claims["aud"]).To(Equal("https://acme.force.com/customers"))
+	})
+
+	DescribeTable("the audience for an endpoint",
+		func(endpoint salesforce.ForceEndpoint, tokenUrl string, audience string) {
+			Expect(salesforce.JWTAudience(endpoint, tokenUrl)).To(Equal(audience))
+		},
+		Entry("production", salesforce.ForceEndpoint(salesforce.EndpointProduction), "https://login.salesforce.com", "https://login.salesforce.com"),
+		Entry("sandbox", salesforce.ForceEndpoint(salesforce.EndpointTest), "https://test.salesforce.com", "https://test.salesforce.com"),
+		Entry("a My Domain", salesforce.ForceEndpoint(salesforce.EndpointCustom), "https://acme.my.salesforce.com", "https://login.salesforce.com"),
+		Entry("a sandbox My Domain", salesforce.ForceEndpoint(salesforce.EndpointCustom), "https://acme--uat.sandbox.my.salesforce.com", "https://test.salesforce.com"),
+		Entry("a legacy sandbox My Domain", salesforce.ForceEndpoint(salesforce.EndpointCustom), "https://acme--uat.my.salesforce.com", "https://test.salesforce.com"),
+		Entry("a sandbox instance", salesforce.ForceEndpoint(salesforce.EndpointCustom), "https://cs42.salesforce.com", "https://test.salesforce.com"),
+	)
+
+	It("should accept PKCS#8 keys", func() {
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		Expect(err).ToNot(HaveOccurred())
+		keyPEM = pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+		_, err = salesforce.ForceJWTLogin(salesforce.EndpointCustom, "client-id", "user@example.com", "", keyPEM)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("should report errors from the token endpoint", func() {
+		tokenFail = true
+		_, err := salesforce.ForceJWTLogin(salesforce.EndpointCustom, "client-id", "user@example.com", "", keyPEM)
+		Expect(err).To(MatchError("user hasn't approved this consumer"))
+	})
+
+	It("should reject a file that isn't a private key", func() {
+		_, err := salesforce.ForceJWTLogin(salesforce.EndpointCustom, "client-id", "user@example.com", "", []byte("not a key"))
+		Expect(err).To(MatchError("no PEM encoded private key found"))
+	})
+})
@@ -14,6 +14,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -357,7 +358,7 @@ type MDFileProperties struct {
 	Id                 string    `xml:"id"`
 	LastModifiedById   string    `xml:"lastModifiedById"`
 	LastModifiedByName string    `xml:"lastModifiedByName"`
-	LastModifedByDate  time.Time `xml:"lastModifiedByDate"`
+	LastModifiedDate   time.Time `xml:"lastModifiedDate"`
 	ManageableState    string    `xml:"manageableState"`
 	NamespacePrefix    string    `xml:"namespacePrefix"`
 	Type               string    `xml:"type"`
@@ -706,7 +707,7 @@ func (fm *ForceMetadata) CheckDeployStatus(id string) (results ForceCheckDeploym
 	}
 
 	if err = xml.Unmarshal(body, &deployResult); err != nil {
-		util.ErrorAndExit(err.Error())
+		return
 	}
 
 	results = deployResult.Results
@@ -1029,9 +1030,6 @@ func (fm *ForceMetadata) CreateBigObject(object BigObject) (err error) {
 		fmt.Sprintf("-f=%s", path))
 	cmd.Stdout = os.Stdout
 	err = cmd.Run()
-	if err != nil {
-		util.ErrorAndExit(err.Error())
-	}
 	return
 }
 
@@ -1138,6 +1136,13 @@ func (fm *ForceMetadata) MakeZip(files ForceMetadataFiles) (zipdata []byte, err
 func (fm *ForceMetadata) Deploy(files ForceMetadataFiles, options ForceDeployOptions) (results ForceCheckDeploymentStatusResult, err error) {
 	soap := fm.MakeDeploySoap(options)
 
+	// any components kept in the decomposed source format need to be put
+	// back together before the Metadata API will accept them.
+	files, err = RecomposeMetadata(files)
+	if err != nil {
+		return
+	}
+
 	zipfile, err := fm.MakeZip(files)
 
 	results, err = fm.DeployZipFile(soap, zipfile)
@@ -1260,6 +1265,181 @@ func (fm *ForceMetadata) ListMetadata(query string) (res []byte, err error) {
 	}
 }
 
+// ListMetadataQuery is a single query for listMetadata.  Folder is required
+// for types stored in folders.
+type ListMetadataQuery struct {
+	Type   string
+	Folder string
+}
+
+// folderTypes maps the metadata types stored in folders to the type of their
+// folders.
+var folderTypes = map[string]string{
+	"Dashboard":     "DashboardFolder",
+	"Document":      "DocumentFolder",
+	"EmailTemplate": "EmailFolder",
+	"Report":        "ReportFolder",
+}
+
+// ListMetadataProperties runs listMetadata for up to three queries at a time,
+// which is as many as the Metadata API allows in one call.
+func (fm *ForceMetadata) ListMetadataProperties(queries []ListMetadataQuery) (properties []MDFileProperties, err error) {
+	if len(queries) > 3 {
+		return nil, fmt.Errorf("listMetadata accepts at most 3 queries, got %d", len(queries))
+	}
+	var body bytes.Buffer
+	for _, query := range queries {
+		body.WriteString("<queries><type>")
+		xml.EscapeText(&body, []byte(query.Type))
+		body.WriteString("</type>")
+		if query.Folder != "" {
+			body.WriteString("<folder>")
+			xml.EscapeText(&body, []byte(query.Folder))
+			body.WriteString("</folder>")
+		}
+		body.WriteString("</queries>")
+	}
+	fmt.Fprintf(&body, "<asOfVersion>%s</asOfVersion>", fm.ApiVersion)
+
+	res, err := fm.soapExecute("listMetadata", body.String())
+	if err != nil {
+		return
+	}
+	var response struct {
+		Result []MDFileProperties `xml:"Body>listMetadataResponse>result"`
+	}
+	if err = xml.Unmarshal(res, &response); err != nil {
+		return
+	}
+	properties = response.Result
+	return
+}
+
+// ListAllMetadataProperties lists every component of the given types,
+// including the contents of every folder for types stored in folders.  Types
+// which can not be listed are skipped.
+func (fm *ForceMetadata) ListAllMetadataProperties(describe MetadataDescribeResult) (properties []MDFileProperties, err error) {
+	var queries []ListMetadataQuery
+	var folderQueries []ListMetadataQuery
+	for _, object := range describe.MetadataObjects {
+		if folderType, inFolder := folderTypes[object.XmlName]; inFolder && object.InFolder {
+			folderQueries = append(folderQueries, ListMetadataQuery{Type: folderType})
+		} else if !object.InFolder {
+			queries = append(queries, ListMetadataQuery{Type: object.XmlName})
+		}
+	}
+
+	// the folders themselves are listed first, to find out what else to list.
+	folders, err := fm.listMetadataInBatches(folderQueries)
+	if err != nil {
+		return
+	}
+	properties = append(properties, folders...)
+	for _, folder := range folders {
+		for contentType, folderType := range folderTypes {
+			if folder.Type == folderType {
+				queries = append(queries, ListMetadataQuery{Type: contentType, Folder: folder.FullName})
+			}
+		}
+	}
+	queries = append(queries,
+		ListMetadataQuery{Type: "EmailTemplate", Folder: "unfiled$public"},
+		ListMetadataQuery{Type: "Report", Folder: "unfiled$public"},
+	)
+
+	listed, err := fm.listMetadataInBatches(queries)
+	if err != nil {
+		return nil, err
+	}
+	properties = append(properties, listed...)
+	return
+}
+
+// unlistableTypeFaults are the faults listMetadata gives for a type that the
+// org can't list, which are skipped rather than failing the whole listing.
+var unlistableTypeFaults = map[string]bool{
+	"INVALID_TYPE":               true,
+	"INVALID_TYPE_FOR_OPERATION": true,
+}
+
+func isUnlistableType(err error) bool {
+	var apiError *APIError
+	return errors.As(err, &apiError) && unlistableTypeFaults[apiError.ErrorCode]
+}
+
+// listMetadataInBatches lists metadata three queries at a time, falling back to
+// one at a time when a batch has a type that can't be listed so that it
+// doesn't hide the rest.  Any other failure, such as an expired session, is
+// returned.
+func (fm *ForceMetadata) listMetadataInBatches(queries []ListMetadataQuery) (properties []MDFileProperties, err error) {
+	for len(queries) > 0 {
+		batch := queries
+		if len(batch) > 3 {
+			batch = batch[:3]
+		}
+		queries = queries[len(batch):]
+
+		result, err := fm.ListMetadataProperties(batch)
+		if err == nil {
+			properties = append(properties, result...)
+			continue
+		}
+		if !isUnlistableType(err) {
+			return nil, err
+		}
+		for _, query := range batch {
+			result, err := fm.ListMetadataProperties([]ListMetadataQuery{query})
+			if err == nil {
+				properties = append(properties, result...)
+			} else if !isUnlistableType(err) {
+				return nil, err
+			}
+		}
+	}
+	return
+}
+
+// FilterChangedMetadata returns the components last modified at or after
+// since.  If by is given, only the components last modified by the user with
+// that name or Id are returned.
+func FilterChangedMetadata(properties []MDFileProperties, since time.Time, by string) (changed []MDFileProperties) {
+	for _, property := range properties {
+		if property.LastModifiedDate.Before(since) {
+			continue
+		}
+		if by != "" && !strings.EqualFold(property.LastModifiedByName, by) && property.LastModifiedById != by {
+			continue
+		}
+		changed = append(changed, property)
+	}
+	return
+}
+
+// RetrieveQueryForProperties builds the query to retrieve the given
+// components.  Folders are retrieved as members of the type they contain.
+func RetrieveQueryForProperties(properties []MDFileProperties) (query ForceMetadataQuery) {
+	members := make(map[string][]string)
+	var types []string
+	for _, property := range properties {
+		metadataType := property.Type
+		for contentType, folderType := range folderTypes {
+			if metadataType == folderType {
+				metadataType = contentType
+			}
+		}
+		if _, seen := members[metadataType]; !seen {
+			types = append(types, metadataType)
+		}
+		members[metadataType] = append(members[metadataType], property.FullName)
+	}
+	sort.Strings(types)
+	for _, metadataType := range types {
+		sort.Strings(members[metadataType])
+		query = append(query, ForceMetadataQueryElement{Name: metadataType, Members: members[metadataType]})
+	}
+	return
+}
+
 func (fm *ForceMetadata) ListAllMetadata() (describe MetadataDescribeResult, err error) {
 	describe, err = fm.DescribeMetadata()
 	return
@@ -1282,9 +1462,10 @@ func (fm *ForceMetadata) ListConnectedApps() (apps ForceConnectedApps, err error
 }
 
 // EnumerateMetadataByType allows for finding all metadata of a given type in a
-// given ForceMetadataFiles map of Salesforce metadata.
-func EnumerateMetadataByType(files ForceMetadataFiles, metadataName string, metadataFolderPath string, metadataFileExtension string, ignoreRegex string) ForceMetadataFilesForType {
-	TypeFiles := ForceMetadataFilesForType{
+// given ForceMetadataFiles map of Salesforce metadata.  It fails if the
+// folder, extension or ignoreRegex don't make a valid regular expression.
+func EnumerateMetadataByType(files ForceMetadataFiles, metadataName string, metadataFolderPath string, metadataFileExtension string, ignoreRegex string) (TypeFiles ForceMetadataFilesForType, err error) {
+	TypeFiles = ForceMetadataFilesForType{
 		Members: make([]ForceMetadataItem, 0),
 		Name:    metadataName,
 	}
@@ -1295,12 +1476,12 @@ func EnumerateMetadataByType(files ForceMetadataFiles, metadataName string, meta
 	// regex:
 	metadataNameScraper, err := regexp.Compile(fmt.Sprintf("^%s\\/(.*)\\.%s$", metadataFolderPath, metadataFileExtension))
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		return
 	}
 
 	ignoreMatcher, err := regexp.Compile(ignoreRegex)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		return
 	}
 
 	for name, fdata := range files {
@@ -1321,7 +1502,7 @@ func EnumerateMetadataByType(files ForceMetadataFiles, metadataName string, meta
 		}
 	}
 
-	return TypeFiles
+	return
 }
 
 func (fm *ForceMetadata) soapExecute(action, query string) (response []byte, err error) {
@@ -1330,7 +1511,5 @@ func (fm *ForceMetadata) soapExecute(action, query string) (response []byte, err
 		return
 	}
 	url := strings.Replace(login["urls"].(map[string]interface{})["metadata"].(string), "{version}", fm.ApiVersion, 1)
-	soap := NewSoap(url, "http://soap.sforce.com/2006/04/metadata", fm.Force.Credentials.AccessToken)
-	response, err = soap.Execute(action, query)
-	return
+	return fm.Force.soapExecute(url, "http://soap.sforce.com/2006/04/metadata", "", action, query)
 }
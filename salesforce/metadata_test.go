@@ -1,6 +1,13 @@
 package salesforce_test
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"time"
+
 	"github.com/joist-engineering/force/salesforce"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -15,7 +22,8 @@ var _ = Describe("Metadata", func() {
 				"pages/Kase_List_View.page": []byte(""),
 			}
 
-			filtered := salesforce.EnumerateMetadataByType(files, "ApexClass", "classes", "cls", "")
+			filtered, err := salesforce.EnumerateMetadataByType(files, "ApexClass", "classes", "cls", "")
+			Ω(err).ShouldNot(HaveOccurred())
 
 			Ω(filtered.Name).Should(Equal("ApexClass"))
 
@@ -37,7 +45,8 @@ var _ = Describe("Metadata", func() {
 				"pages/Kase_List_View.page": []byte(""),
 			}
 
-			filtered := salesforce.EnumerateMetadataByType(files, "ApexClass", "classes", "cls", "^DS")
+			filtered, err := salesforce.EnumerateMetadataByType(files, "ApexClass", "classes", "cls", "^DS")
+			Ω(err).ShouldNot(HaveOccurred())
 
 			Ω(filtered.Name).Should(Equal("ApexClass"))
 
@@ -49,5 +58,117 @@ var _ = Describe("Metadata", func() {
 
 			Ω(len(filtered.Members)).Should(Equal(1))
 		})
+		It("should return an error for an ignore regex that doesn't compile", func() {
+			_, err := salesforce.EnumerateMetadataByType(map[string][]byte{}, "ApexClass", "classes", "cls", "^(DS")
+			Ω(err).Should(HaveOccurred())
+		})
+
+	})
+
+	Describe("FilterChangedMetadata", func() {
+		since := time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)
+		properties := []salesforce.MDFileProperties{
+			{Type: "ApexClass", FullName: "Old", LastModifiedById: "005A", LastModifiedByName: "Jane Doe", LastModifiedDate: since.Add(-time.Hour)},
+			{Type: "ApexClass", FullName: "Janes", LastModifiedById: "005A", LastModifiedByName: "Jane Doe", LastModifiedDate: since.Add(time.Hour)},
+			{Type: "Layout", FullName: "Bobs", LastModifiedById: "005B", LastModifiedByName: "Bob Smith", LastModifiedDate: since},
+		}
+
+		It("should only include metadata changed since the given time", func() {
+			changed := salesforce.FilterChangedMetadata(properties, since, "")
+			Ω(changed).Should(HaveLen(2))
+			Ω(changed[0].FullName).Should(Equal("Janes"))
+			Ω(changed[1].FullName).Should(Equal("Bobs"))
+		})
+
+		It("should filter by the name of the user who made the change", func() {
+			changed := salesforce.FilterChangedMetadata(properties, since, "jane doe")
+			Ω(changed).Should(HaveLen(1))
+			Ω(changed[0].FullName).Should(Equal("Janes"))
+		})
+
+		It("should filter by the Id of the user who made the change", func() {
+			changed := salesforce.FilterChangedMetadata(properties, since, "005B")
+			Ω(changed).Should(HaveLen(1))
+			Ω(changed[0].FullName).Should(Equal("Bobs"))
+		})
+	})
+
+	Describe("ListAllMetadataProperties", func() {
+		var fault func(types []string) string
+		listedType := regexp.MustCompile(`<type>(\w+)</type>`)
+		describe := salesforce.MetadataDescribeResult{MetadataObjects: []salesforce.DescribeMetadataObject{
+			{XmlName: "ApexClass"}, {XmlName: "Unlistable"}, {XmlName: "Layout"}, {XmlName: "Flow"},
+		}}
+
+		org := serveOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == "GET" {
+				fmt.Fprintf(w, `{"urls":{"metadata":"http://%s/services/Soap/m/{version}"}}`, r.Host)
+				return
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			var types []string
+			for _, match := range listedType.FindAllStringSubmatch(string(body), -1) {
+				types = append(types, match[1])
+			}
+			if code := fault(types); code != "" {
+				w.WriteHeader(500)
+				fmt.Fprintf(w, `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><soapenv:Fault><faultcode>sf:%s</faultcode><faultstring>%s: no</faultstring></soapenv:Fault></soapenv:Body></soapenv:Envelope>`, code, code)
+				return
+			}
+			fmt.Fprint(w, `<soapenv:Envelope xmlns:soapenv="http://schemas.xmlsoap.org/soap/envelope/"><soapenv:Body><listMetadataResponse>`)
+			for _, t := range types {
+				fmt.Fprintf(w, `<result><fullName>A%s</fullName><type>%s</type></result>`, t, t)
+			}
+			fmt.Fprint(w, `</listMetadataResponse></soapenv:Body></soapenv:Envelope>`)
+		}))
+
+		BeforeEach(func() {
+			fault = func([]string) string { return "" }
+		})
+
+		It("should skip the types the org can't list", func() {
+			fault = func(types []string) string {
+				for _, t := range types {
+					if t == "Unlistable" {
+						return "INVALID_TYPE"
+					}
+				}
+				return ""
+			}
+			properties, err := org.force.Metadata.ListAllMetadataProperties(describe)
+			Ω(err).ShouldNot(HaveOccurred())
+			var listed []string
+			for _, property := range properties {
+				listed = append(listed, property.Type)
+			}
+			Ω(listed).Should(ContainElement("ApexClass"))
+			Ω(listed).Should(ContainElement("Layout"))
+			Ω(listed).Should(ContainElement("Flow"))
+			Ω(listed).ShouldNot(ContainElement("Unlistable"))
+		})
+
+		It("should return any other failure", func() {
+			fault = func([]string) string { return "INVALID_SESSION_ID" }
+			properties, err := org.force.Metadata.ListAllMetadataProperties(describe)
+			Ω(properties).Should(BeEmpty())
+			var apiError *salesforce.APIError
+			Ω(errors.As(err, &apiError)).Should(BeTrue())
+			Ω(apiError.ErrorCode).Should(Equal("INVALID_SESSION_ID"))
+		})
+	})
+
+	Describe("RetrieveQueryForProperties", func() {
+		It("should group members by type", func() {
+			query := salesforce.RetrieveQueryForProperties([]salesforce.MDFileProperties{
+				{Type: "Report", FullName: "Sales/Pipeline"},
+				{Type: "ApexClass", FullName: "Foo"},
+				{Type: "ReportFolder", FullName: "Sales"},
+				{Type: "ApexClass", FullName: "Bar"},
+			})
+			Ω(query).Should(Equal(salesforce.ForceMetadataQuery{
+				{Name: "ApexClass", Members: []string{"Bar", "Foo"}},
+				{Name: "Report", Members: []string{"Sales", "Sales/Pipeline"}},
+			}))
+		})
 	})
 })
@@ -0,0 +1,233 @@
+package salesforce
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/joist-engineering/force/util"
+)
+
+// metapath maps a directory of a metadata project to the metadata type stored
+// within it.
+type metapath struct {
+	path string
+	name string
+	// suffix is the file extension of the type, without the dot.  Bundles and
+	// documents don't have one.
+	suffix string
+	// hasFolder is set for types that live one directory further down, in
+	// either a folder (reports, documents, ...) or a bundle (aura, lwc).
+	hasFolder bool
+	// onlyFolder is set for bundles, which are named after their directory.
+	onlyFolder bool
+	// metaFile is set for types that have a separate `-meta.xml` file.
+	metaFile bool
+}
+
+// metapaths is the static table of metadata types, used until (or unless) the
+// org's own is loaded with LoadMetapaths.
+var metapaths = staticMetapaths
+
+var staticMetapaths = []metapath{
+	metapath{path: "actionLinkGroupTemplates", name: "ActionLinkGroupTemplate", suffix: "actionLinkGroupTemplate"},
+	metapath{path: "analyticSnapshots", name: "AnalyticSnapshot", suffix: "snapshot"},
+	metapath{path: "animationRules", name: "AnimationRule", suffix: "animationRule"},
+	metapath{path: "appMenus", name: "AppMenu", suffix: "appMenu"},
+	metapath{path: "applications", name: "CustomApplication", suffix: "app"},
+	metapath{path: "approvalProcesses", name: "ApprovalProcess", suffix: "approvalProcess"},
+	metapath{path: "assignmentRules", name: "AssignmentRules", suffix: "assignmentRules"},
+	metapath{path: "aura", name: "AuraDefinitionBundle", hasFolder: true, onlyFolder: true},
+	metapath{path: "authproviders", name: "AuthProvider", suffix: "authprovider"},
+	metapath{path: "autoResponseRules", name: "AutoResponseRules", suffix: "autoResponseRules"},
+	metapath{path: "brandingSets", name: "BrandingSet", suffix: "brandingSet"},
+	metapath{path: "cachePartitions", name: "PlatformCachePartition", suffix: "cachePartition"},
+	metapath{path: "callCenters", name: "CallCenter", suffix: "callCenter"},
+	metapath{path: "certs", name: "Certificate", suffix: "crt", metaFile: true},
+	metapath{path: "channelLayouts", name: "ChannelLayout", suffix: "channelLayout"},
+	metapath{path: "classes", name: "ApexClass", suffix: "cls", metaFile: true},
+	metapath{path: "cleanDataServices", name: "CleanDataService", suffix: "cleanDataService"},
+	metapath{path: "communities", name: "Community", suffix: "community"},
+	metapath{path: "components", name: "ApexComponent", suffix: "component", metaFile: true},
+	metapath{path: "connectedApps", name: "ConnectedApp", suffix: "connectedApp"},
+	metapath{path: "contentassets", name: "ContentAsset", suffix: "asset", metaFile: true},
+	metapath{path: "corsWhitelistOrigins", name: "CorsWhitelistOrigin", suffix: "corsWhitelistOrigin"},
+	metapath{path: "cspTrustedSites", name: "CspTrustedSite", suffix: "cspTrustedSite"},
+	metapath{path: "customApplicationComponents", name: "CustomApplicationComponent", suffix: "customApplicationComponent"},
+	metapath{path: "customHelpMenuSections", name: "CustomHelpMenuSection", suffix: "customHelpMenuSection"},
+	metapath{path: "customMetadata", name: "CustomMetadata", suffix: "md"},
+	// the Metadata API uses notificationtypes, but customNotificationTypes
+	// is common in older projects.
+	metapath{path: "customNotificationTypes", name: "CustomNotificationType", suffix: "notiftype"},
+	metapath{path: "customPermissions", name: "CustomPermission", suffix: "customPermission"},
+	metapath{path: "dashboards", name: "Dashboard", suffix: "dashboard", hasFolder: true},
+	metapath{path: "dataSources", name: "ExternalDataSource", suffix: "dataSource"},
+	metapath{path: "delegateGroups", name: "DelegateGroup", suffix: "delegateGroup"},
+	metapath{path: "documents", name: "Document", hasFolder: true, metaFile: true},
+	metapath{path: "duplicateRules", name: "DuplicateRule", suffix: "duplicateRule"},
+	metapath{path: "email", name: "EmailTemplate", suffix: "email", hasFolder: true, metaFile: true},
+	metapath{path: "emailservices", name: "EmailServicesFunction", suffix: "xml"},
+	metapath{path: "entitlementProcesses", name: "EntitlementProcess", suffix: "entitlementProcess"},
+	metapath{path: "escalationRules", name: "EscalationRules", suffix: "escalationRules"},
+	metapath{path: "eventDeliveries", name: "EventDelivery", suffix: "delivery"},
+	metapath{path: "eventSubscriptions", name: "EventSubscription", suffix: "subscription"},
+	metapath{path: "externalCredentials", name: "ExternalCredential", suffix: "externalCredential"},
+	metapath{path: "externalServiceRegistrations", name: "ExternalServiceRegistration", suffix: "externalServiceRegistration"},
+	metapath{path: "flexipages", name: "FlexiPage", suffix: "flexipage"},
+	metapath{path: "flowCategories", name: "FlowCategory", suffix: "flowCategory"},
+	metapath{path: "flowDefinitions", name: "FlowDefinition", suffix: "flowDefinition"},
+	metapath{path: "flows", name: "Flow", suffix: "flow"},
+	metapath{path: "globalPicklists", name: "GlobalPicklist", suffix: "globalPicklist"},
+	metapath{path: "globalValueSets", name: "GlobalValueSet", suffix: "globalValueSet"},
+	metapath{path: "globalValueSetTranslations", name: "GlobalValueSetTranslation", suffix: "globalValueSetTranslation"},
+	metapath{path: "groups", name: "Group", suffix: "group"},
+	metapath{path: "homePageComponents", name: "HomePageComponent", suffix: "homePageComponent"},
+	metapath{path: "homePageLayouts", name: "HomePageLayout", suffix: "homePageLayout"},
+	metapath{path: "installedPackages", name: "InstalledPackage", suffix: "installedPackage"},
+	metapath{path: "labels", name: "CustomLabels", suffix: "labels"},
+	metapath{path: "layouts", name: "Layout", suffix: "layout"},
+	metapath{path: "letterhead", name: "Letterhead", suffix: "letter"},
+	metapath{path: "lightningExperienceThemes", name: "LightningExperienceTheme", suffix: "lightningExperienceTheme"},
+	metapath{path: "lwc", name: "LightningComponentBundle", hasFolder: true, onlyFolder: true},
+	metapath{path: "managedTopics", name: "ManagedTopics", suffix: "managedTopics"},
+	metapath{path: "matchingRules", name: "MatchingRules", suffix: "matchingRule"},
+	metapath{path: "messageChannels", name: "LightningMessageChannel", suffix: "messageChannel"},
+	metapath{path: "milestoneTypes", name: "MilestoneType", suffix: "milestoneType"},
+	metapath{path: "moderation", name: "ModerationRule", suffix: "rule"},
+	metapath{path: "namedCredentials", name: "NamedCredential", suffix: "namedCredential"},
+	metapath{path: "navigationMenus", name: "NavigationMenu", suffix: "navigationMenu"},
+	metapath{path: "networkBranding", name: "NetworkBranding", suffix: "networkBranding", metaFile: true},
+	metapath{path: "networks", name: "Network", suffix: "network"},
+	metapath{path: "notificationtypes", name: "CustomNotificationType", suffix: "notiftype"},
+	metapath{path: "objects", name: "CustomObject", suffix: "object"},
+	metapath{path: "objectTranslations", name: "CustomObjectTranslation", suffix: "objectTranslation"},
+	metapath{path: "pages", name: "ApexPage", suffix: "page", metaFile: true},
+	metapath{path: "pathAssistants", name: "PathAssistant", suffix: "pathAssistant"},
+	metapath{path: "permissionsetgroups", name: "PermissionSetGroup", suffix: "permissionsetgroup"},
+	metapath{path: "permissionsets", name: "PermissionSet", suffix: "permissionset"},
+	metapath{path: "platformEventChannelMembers", name: "PlatformEventChannelMember", suffix: "platformEventChannelMember"},
+	metapath{path: "platformEventChannels", name: "PlatformEventChannel", suffix: "platformEventChannel"},
+	metapath{path: "platformEventSubscriberConfigs", name: "PlatformEventSubscriberConfig", suffix: "platformEventSubscriberConfig"},
+	metapath{path: "postTemplates", name: "PostTemplate", suffix: "postTemplate"},
+	metapath{path: "profilePasswordPolicies", name: "ProfilePasswordPolicy", suffix: "profilePasswordPolicy"},
+	metapath{path: "profiles", name: "Profile", suffix: "profile"},
+	metapath{path: "profileSessionSettings", name: "ProfileSessionSetting", suffix: "profileSessionSetting"},
+	metapath{path: "queueRoutingConfigs", name: "QueueRoutingConfig", suffix: "queueRoutingConfig"},
+	metapath{path: "queues", name: "Queue", suffix: "queue"},
+	metapath{path: "quickActions", name: "QuickAction", suffix: "quickAction"},
+	metapath{path: "recommendationStrategies", name: "RecommendationStrategy", suffix: "recommendationStrategy"},
+	metapath{path: "remoteSiteSettings", name: "RemoteSiteSetting", suffix: "remoteSite"},
+	metapath{path: "reports", name: "Report", suffix: "report", hasFolder: true},
+	metapath{path: "reportTypes", name: "ReportType", suffix: "reportType"},
+	metapath{path: "restrictionRules", name: "RestrictionRule", suffix: "rule"},
+	metapath{path: "roles", name: "Role", suffix: "role"},
+	metapath{path: "samlssoconfigs", name: "SamlSsoConfig", suffix: "samlssoconfig"},
+	metapath{path: "scontrols", name: "Scontrol", suffix: "scf", metaFile: true},
+	metapath{path: "settings", name: "Settings", suffix: "settings"},
+	metapath{path: "sharingRules", name: "SharingRules", suffix: "sharingRules"},
+	metapath{path: "sharingSets", name: "SharingSet", suffix: "sharingSet"},
+	metapath{path: "sites", name: "CustomSite", suffix: "site"},
+	metapath{path: "standardValueSets", name: "StandardValueSet", suffix: "standardValueSet"},
+	metapath{path: "staticresources", name: "StaticResource", suffix: "resource", metaFile: true},
+	metapath{path: "synonymDictionaries", name: "SynonymDictionary", suffix: "synonymDictionary"},
+	metapath{path: "tabs", name: "CustomTab", suffix: "tab"},
+	metapath{path: "topicsForObjects", name: "TopicsForObjects", suffix: "topicsForObjects"},
+	metapath{path: "translations", name: "Translations", suffix: "translation"},
+	metapath{path: "triggers", name: "ApexTrigger", suffix: "trigger", metaFile: true},
+	metapath{path: "weblinks", name: "CustomPageWebLink", suffix: "weblink"},
+	metapath{path: "workflows", name: "Workflow", suffix: "workflow"},
+}
+
+// metapathForDir returns the metadata type stored in the given directory.
+func metapathForDir(dir string) (metapath, bool) {
+	for _, mp := range metapaths {
+		if mp.path == dir {
+			return mp, true
+		}
+	}
+	return metapath{}, false
+}
+
+// metapathForName returns the directory for the given metadata type.
+func metapathForName(name string) (metapath, bool) {
+	for _, mp := range metapaths {
+		if strings.EqualFold(mp.name, name) {
+			return mp, true
+		}
+	}
+	return metapath{}, false
+}
+
+// memberName gives the package.xml member name for a file, given its path
+// relative to the type directory as returned by getMetaForPath.
+func memberName(metaName string, objectName string) string {
+	mp, known := metapathForName(metaName)
+	switch {
+	case !known:
+		return strings.TrimSuffix(objectName, filepath.Ext(objectName))
+	case mp.suffix == "":
+		// bundles are named after their directory, and documents keep
+		// their extension.
+		return objectName
+	default:
+		return strings.TrimSuffix(objectName, "."+mp.suffix)
+	}
+}
+
+// metapathsFromDescribe builds the table of metadata types from the result of
+// describeMetadata, keeping any from the static table it doesn't mention.
+func metapathsFromDescribe(objects []DescribeMetadataObject) (described []metapath) {
+	seen := make(map[string]bool)
+	for _, object := range objects {
+		if object.DirectoryName == "" || seen[object.DirectoryName] {
+			continue
+		}
+		seen[object.DirectoryName] = true
+		bundle := object.Suffix == "" && !object.InFolder
+		described = append(described, metapath{
+			path:       object.DirectoryName,
+			name:       object.XmlName,
+			suffix:     object.Suffix,
+			hasFolder:  object.InFolder || bundle,
+			onlyFolder: bundle,
+			metaFile:   object.MetaFile,
+		})
+	}
+	for _, mp := range staticMetapaths {
+		if !seen[mp.path] {
+			described = append(described, mp)
+		}
+	}
+	return
+}
+
+// UseDescribedMetapaths replaces the table of metadata types used to build
+// packages with the one from an org's describeMetadata.
+func UseDescribedMetapaths(describe MetadataDescribeResult) {
+	if len(describe.MetadataObjects) > 0 {
+		metapaths = metapathsFromDescribe(describe.MetadataObjects)
+	}
+}
+
+// LoadMetapaths loads the table of metadata types used to build packages from
+// the org, so that types newer than the static table are recognized.  The
+// result is cached in the config directory per API version.
+func (fm *ForceMetadata) LoadMetapaths() (err error) {
+	var describe MetadataDescribeResult
+	if cached, lerr := util.Config.Load("metapaths", fm.ApiVersion); lerr == nil {
+		if jerr := json.Unmarshal([]byte(cached), &describe.MetadataObjects); jerr == nil {
+			UseDescribedMetapaths(describe)
+			return
+		}
+	}
+
+	if describe, err = fm.DescribeMetadata(); err != nil {
+		return
+	}
+	if len(describe.MetadataObjects) > 0 {
+		data, _ := json.Marshal(describe.MetadataObjects)
+		util.Config.Save("metapaths", fm.ApiVersion, string(data))
+	}
+	UseDescribedMetapaths(describe)
+	return
+}
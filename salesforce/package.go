@@ -0,0 +1,178 @@
+package salesforce
+
+import (
+	"encoding/xml"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PackageMember is a single member of a single type in a package.xml.
+type PackageMember struct {
+	Type   string
+	Member string
+}
+
+// ParsePackage reads a package.xml.
+func ParsePackage(data []byte) (p Package, err error) {
+	err = xml.Unmarshal(data, &p)
+	if p.Xmlns == "" {
+		p.Xmlns = metadataNamespace
+	}
+	return
+}
+
+// Xml renders the package.xml, with its types and members sorted.
+func (p Package) Xml() []byte {
+	p = MergePackages(p)
+	byteXml, _ := xml.MarshalIndent(p, "", "    ")
+	byteXml = append([]byte(xml.Header), byteXml...)
+	return append(byteXml, '\n')
+}
+
+// Members lists every member of every type in the package, in order.
+func (p Package) Members() (members []PackageMember) {
+	for _, metaType := range MergePackages(p).Types {
+		for _, member := range metaType.Members {
+			members = append(members, PackageMember{Type: metaType.Name, Member: member})
+		}
+	}
+	return
+}
+
+// MergePackages returns the union of the given packages, with types and members
+// sorted and deduplicated, and the newest version of any of them.
+func MergePackages(packages ...Package) (merged Package) {
+	merged.Xmlns = metadataNamespace
+	members := make(map[string]map[string]bool)
+	for _, p := range packages {
+		if newerPackageVersion(p.Version, merged.Version) {
+			merged.Version = p.Version
+		}
+		for _, metaType := range p.Types {
+			if members[metaType.Name] == nil {
+				members[metaType.Name] = make(map[string]bool)
+			}
+			for _, member := range metaType.Members {
+				members[metaType.Name][member] = true
+			}
+		}
+	}
+
+	for name, typeMembers := range members {
+		metaType := MetaType{Name: name}
+		for member := range typeMembers {
+			metaType.Members = append(metaType.Members, member)
+		}
+		sort.Strings(metaType.Members)
+		merged.Types = append(merged.Types, metaType)
+	}
+	sort.Slice(merged.Types, func(i, j int) bool {
+		return merged.Types[i].Name < merged.Types[j].Name
+	})
+	return
+}
+
+func newerPackageVersion(version string, than string) bool {
+	v, err := strconv.ParseFloat(version, 64)
+	if err != nil {
+		return false
+	}
+	t, err := strconv.ParseFloat(than, 64)
+	return err != nil || v > t
+}
+
+// DiffPackages returns the members only in a, and the members only in b.
+func DiffPackages(a Package, b Package) (onlyInA []PackageMember, onlyInB []PackageMember) {
+	inA := make(map[PackageMember]bool)
+	inB := make(map[PackageMember]bool)
+	for _, member := range a.Members() {
+		inA[member] = true
+	}
+	for _, member := range b.Members() {
+		inB[member] = true
+		if !inA[member] {
+			onlyInB = append(onlyInB, member)
+		}
+	}
+	for _, member := range a.Members() {
+		if !inB[member] {
+			onlyInA = append(onlyInA, member)
+		}
+	}
+	return
+}
+
+// GeneratePackage builds a package.xml covering every component in a set of
+// files in the Metadata API format.
+func GeneratePackage(files ForceMetadataFiles, apiVersion string) (p Package, err error) {
+	files, err = RecomposeMetadata(files)
+	if err != nil {
+		return
+	}
+	pb := NewFetchBuilder(apiVersion)
+	for name := range files {
+		pb.addPackageMember(name)
+	}
+	return ParsePackage(pb.PackageXml())
+}
+
+// ValidatePackage compares a package.xml against a set of files in the
+// Metadata API format.  It returns the members of the package that have no
+// file, and the files that aren't covered by the package.  Wildcard members
+// cover every file of their type.
+func ValidatePackage(p Package, files ForceMetadataFiles) (missing []PackageMember, uncovered []string, err error) {
+	files, err = RecomposeMetadata(files)
+	if err != nil {
+		return
+	}
+
+	backed := make(map[PackageMember]bool)
+	fileMembers := make(map[string]PackageMember)
+	for name := range files {
+		if metaName, member, ok := packageMemberForFile(name); ok {
+			backed[PackageMember{Type: metaName, Member: member}] = true
+			fileMembers[name] = PackageMember{Type: metaName, Member: member}
+		}
+	}
+
+	wildcards := make(map[string]bool)
+	listed := make(map[PackageMember]bool)
+	for _, member := range p.Members() {
+		if member.Member == "*" {
+			wildcards[member.Type] = true
+			continue
+		}
+		listed[member] = true
+		if !backed[member] && !isBackedByParent(member, backed) {
+			missing = append(missing, member)
+		}
+	}
+
+	for name, member := range fileMembers {
+		if !wildcards[member.Type] && !listed[member] {
+			uncovered = append(uncovered, name)
+		}
+	}
+	sort.Strings(uncovered)
+	return
+}
+
+// isBackedByParent reports whether a member of a child type, such as a
+// CustomField, is backed by the file of its parent.
+func isBackedByParent(member PackageMember, backed map[PackageMember]bool) bool {
+	if member.Type == "CustomLabel" {
+		return backed[PackageMember{Type: "CustomLabels", Member: "CustomLabels"}]
+	}
+	for _, dt := range decomposedTypes {
+		for _, child := range dt.children {
+			if child.root != member.Type {
+				continue
+			}
+			if i := strings.Index(member.Member, "."); i > 0 {
+				return backed[PackageMember{Type: dt.name, Member: member.Member[:i]}]
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+package salesforce_test
+
+import (
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Package", func() {
+	var (
+		a salesforce.Package
+		b salesforce.Package
+	)
+
+	BeforeEach(func() {
+		a = salesforce.Package{Version: "40.0", Types: []salesforce.MetaType{
+			{Name: "CustomObject", Members: []string{"Account"}},
+			{Name: "ApexClass", Members: []string{"Foo", "Bar"}},
+		}}
+		b = salesforce.Package{Version: "45.0", Types: []salesforce.MetaType{
+			{Name: "ApexClass", Members: []string{"Foo", "Baz", "Foo"}},
+		}}
+	})
+
+	Describe("ParsePackage", func() {
+		It("should read a package.xml", func() {
+			p, err := salesforce.ParsePackage([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<Package xmlns="http://soap.sforce.com/2006/04/metadata">
+    <types>
+        <members>Foo</members>
+        <name>ApexClass</name>
+    </types>
+    <version>45.0</version>
+</Package>`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Version).To(Equal("45.0"))
+			Expect(p.Types).To(Equal([]salesforce.MetaType{{Name: "ApexClass", Members: []string{"Foo"}}}))
+		})
+	})
+
+	Describe("MergePackages", func() {
+		It("should sort and deduplicate types and members", func() {
+			merged := salesforce.MergePackages(a, b)
+			Expect(merged.Types).To(Equal([]salesforce.MetaType{
+				{Name: "ApexClass", Members: []string{"Bar", "Baz", "Foo"}},
+				{Name: "CustomObject", Members: []string{"Account"}},
+			}))
+		})
+
+		It("should use the newest version", func() {
+			Expect(salesforce.MergePackages(a, b).Version).To(Equal("45.0"))
+		})
+	})
+
+	Describe("DiffPackages", func() {
+		It("should list the members only in either package", func() {
+			onlyInA, onlyInB := salesforce.DiffPackages(a, b)
+			Expect(onlyInA).To(Equal([]salesforce.PackageMember{
+				{Type: "ApexClass", Member: "Bar"},
+				{Type: "CustomObject", Member: "Account"},
+			}))
+			Expect(onlyInB).To(Equal([]salesforce.PackageMember{
+				{Type: "ApexClass", Member: "Baz"},
+			}))
+		})
+	})
+
+	Describe("GeneratePackage", func() {
+		It("should list every component", func() {
+			p, err := salesforce.GeneratePackage(salesforce.ForceMetadataFiles{
+				"classes/Foo.cls":               []byte(""),
+				"classes/Foo.cls-meta.xml":      []byte(""),
+				"reports/Sales-meta.xml":        []byte(""),
+				"reports/Sales/Pipeline.report": []byte(""),
+				"environments.json":             []byte("{}"),
+			}, "45.0")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(p.Version).To(Equal("45.0"))
+			Expect(p.Types).To(Equal([]salesforce.MetaType{
+				{Name: "ApexClass", Members: []string{"Foo"}},
+				{Name: "Report", Members: []string{"Sales", "Sales/Pipeline"}},
+			}))
+		})
+	})
+
+	Describe("ValidatePackage", func() {
+		files := salesforce.ForceMetadataFiles{
+			"classes/Foo.cls":            []byte(""),
+			"classes/Foo.cls-meta.xml":   []byte(""),
+			"classes/Baz.cls":            []byte(""),
+			"objects/Account.object":     []byte(""),
+			"labels/CustomLabels.labels": []byte(""),
+		}
+
+		It("should report members without a file and files without a member", func() {
+			missing, uncovered, err := salesforce.ValidatePackage(a, files)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(Equal([]salesforce.PackageMember{{Type: "ApexClass", Member: "Bar"}}))
+			Expect(uncovered).To(Equal([]string{"classes/Baz.cls", "labels/CustomLabels.labels"}))
+		})
+
+		It("should accept members backed by their parent's file", func() {
+			p := salesforce.Package{Types: []salesforce.MetaType{
+				{Name: "CustomField", Members: []string{"Account.Region__c"}},
+				{Name: "CustomLabel", Members: []string{"Greeting"}},
+			}}
+			missing, _, err := salesforce.ValidatePackage(p, files)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(BeEmpty())
+		})
+
+		It("should treat wildcards as covering every file of the type", func() {
+			p := salesforce.Package{Types: []salesforce.MetaType{
+				{Name: "ApexClass", Members: []string{"*"}},
+				{Name: "CustomObject", Members: []string{"Account"}},
+				{Name: "CustomLabels", Members: []string{"CustomLabels"}},
+			}}
+			missing, uncovered, err := salesforce.ValidatePackage(p, files)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(missing).To(BeEmpty())
+			Expect(uncovered).To(BeEmpty())
+		})
+	})
+})
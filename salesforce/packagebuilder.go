@@ -1,7 +1,6 @@
 package salesforce
 
 import (
-	"encoding/xml"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -31,56 +30,6 @@ func createPackage(apiVersion string) Package {
 	}
 }
 
-type metapath struct {
-	path       string
-	name       string
-	hasFolder  bool
-	onlyFolder bool
-}
-
-var metapaths = []metapath{
-	metapath{path: "applications", name: "CustomApplication"},
-	metapath{path: "assignmentRules", name: "AssignmentRules"},
-	metapath{path: "aura", name: "AuraDefinitionBundle", hasFolder: true, onlyFolder: true},
-	metapath{path: "autoResponseRules", name: "AutoResponseRules"},
-	metapath{path: "classes", name: "ApexClass"},
-	metapath{path: "communities", name: "Community"},
-	metapath{path: "components", name: "ApexComponent"},
-	metapath{path: "connectedApps", name: "ConnectedApp"},
-	metapath{path: "customMetadata", name: "CustomMetadata"},
-	metapath{path: "customPermissions", name: "CustomPermission"},
-	metapath{path: "dashboards", name: "Dashboard", hasFolder: true},
-	metapath{path: "documents", name: "Document", hasFolder: true},
-	metapath{path: "email", name: "EmailTemplate", hasFolder: true},
-	metapath{path: "flexipages", name: "FlexiPage"},
-	metapath{path: "flowDefinitions", name: "FlowDefinition"},
-	metapath{path: "flows", name: "Flow"},
-	metapath{path: "globalPicklists", name: "GlobalPicklist"},
-	metapath{path: "groups", name: "Group"},
-	metapath{path: "homePageLayouts", name: "HomePageLayout"},
-	metapath{path: "installedPackages", name: "InstalledPackage"},
-	metapath{path: "labels", name: "CustomLabels"},
-	metapath{path: "layouts", name: "Layout"},
-	metapath{path: "objects", name: "CustomObject"},
-	metapath{path: "objectTranslations", name: "CustomObjectTranslation"},
-	metapath{path: "pages", name: "ApexPage"},
-	metapath{path: "permissionsets", name: "PermissionSet"},
-	metapath{path: "profiles", name: "Profile"},
-	metapath{path: "queues", name: "Queue"},
-	metapath{path: "quickActions", name: "QuickAction"},
-	metapath{path: "remoteSiteSettings", name: "RemoteSiteSetting"},
-	metapath{path: "reports", name: "Report", hasFolder: true},
-	metapath{path: "reportTypes", name: "ReportType"},
-	metapath{path: "roles", name: "Role"},
-	metapath{path: "scontrols", name: "Scontrol"},
-	metapath{path: "settings", name: "Settings"},
-	metapath{path: "sharingRules", name: "SharingRules"},
-	metapath{path: "staticresources", name: "StaticResource"},
-	metapath{path: "tabs", name: "CustomTab"},
-	metapath{path: "triggers", name: "ApexTrigger"},
-	metapath{path: "workflows", name: "Workflow"},
-}
-
 type PackageBuilder struct {
 	IsPush     bool
 	Metadata   map[string]MetaType
@@ -112,12 +61,7 @@ func (pb PackageBuilder) PackageXml() []byte {
 		p.Types = append(p.Types, metaType)
 	}
 
-	byteXml, _ := xml.MarshalIndent(p, "", "    ")
-	byteXml = append([]byte(xml.Header), byteXml...)
-	//if err := ioutil.WriteFile("mypackage.xml", byteXml, 0644); err != nil {
-	//util.ErrorAndExit(err.Error())
-	//}
-	return byteXml
+	return p.Xml()
 }
 
 // Returns the full ForceMetadataFiles container
@@ -137,12 +81,32 @@ func (pb *PackageBuilder) AddFile(fpath string) (fname string, err error) {
 		return
 	}
 
+	if _, isSource := sourceProjectRoot(fpath); isSource {
+		return pb.addSourceFile(fpath)
+	}
+
+	if dt, componentDir, ok := decomposedComponentDir(fpath); ok {
+		fname = filepath.Base(componentDir)
+		err = pb.addDecomposedComponent(dt, componentDir)
+		return
+	}
+
 	isDestructiveChanges, err := regexp.MatchString("destructiveChanges(Pre|Post)?"+regexp.QuoteMeta(".")+"xml", fpath)
 	if err != nil {
 		return
 	}
 
 	metaName, fname := getMetaTypeFromPath(fpath)
+	// The -meta.xml file of a type that has them stands for its component,
+	// which is pushed along with it.
+	if component := strings.TrimSuffix(fpath, "-meta.xml"); component != fpath {
+		if mp, ok := metapathForName(metaName); ok && mp.metaFile {
+			if _, statErr := os.Stat(component); statErr == nil {
+				fpath = component
+				metaName, fname = getMetaTypeFromPath(fpath)
+			}
+		}
+	}
 	if !isDestructiveChanges && !strings.HasSuffix(fpath, "-meta.xml") {
 		pb.AddMetaToPackage(metaName, fname)
 	}
@@ -201,6 +165,53 @@ func (pb *PackageBuilder) addFileToWorkingDir(metaName string, fpath string) (er
 	return
 }
 
+// decomposedComponentDir finds the component directory (e.g. objects/Account)
+// that a file in the decomposed source format belongs to.  A directory is
+// only taken for one if it has the component's own file in it, such as
+// objects/Account/Account.object-meta.xml, so that a project that happens to
+// be under a directory called objects or profiles isn't mistaken for one.
+func decomposedComponentDir(fpath string) (dt decomposedType, componentDir string, ok bool) {
+	dir := fpath
+	for i := 0; i < 3; i++ {
+		parent := filepath.Dir(dir)
+		for _, dt = range decomposedTypes {
+			if filepath.Base(parent) != dt.path {
+				continue
+			}
+			componentFile := filepath.Join(dir, filepath.Base(dir)+"."+dt.suffix+"-meta.xml")
+			if info, err := os.Stat(componentFile); err == nil && !info.IsDir() {
+				return dt, dir, true
+			}
+		}
+		dir = parent
+	}
+	return
+}
+
+// addDecomposedComponent adds a decomposed component to the package.  The
+// whole component is always pushed, since it has to be recomposed into a
+// single file before it can be deployed.
+func (pb *PackageBuilder) addDecomposedComponent(dt decomposedType, componentDir string) (err error) {
+	pb.AddMetaToPackage(dt.name, filepath.Base(componentDir))
+	if !pb.IsPush {
+		return
+	}
+
+	srcDir := filepath.Dir(filepath.Dir(componentDir))
+	return filepath.Walk(componentDir, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() == ".DS_Store" {
+			return err
+		}
+		frel, _ := filepath.Rel(srcDir, fpath)
+		fdata, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return err
+		}
+		pb.Files[frel] = fdata
+		return nil
+	})
+}
+
 func (pb *PackageBuilder) addDestructiveChanges(fpath string) (err error) {
 	fdata, err := ioutil.ReadFile(fpath)
 	if err != nil {
@@ -251,17 +262,14 @@ func getMetaTypeFromPath(fpath string) (metaName string, name string) {
 
 	// Get the metadata type and name for the file
 	metaName, fileName := getMetaForPath(fpath)
-	name = strings.TrimSuffix(fileName, filepath.Ext(fileName))
-	//name = strings.TrimSuffix(name, filepath.Ext(name))
+	name = memberName(metaName, fileName)
 	return
 }
 
 // Gets partial path based on a meta type name
 func getPathForMeta(metaname string) string {
-	for _, mp := range metapaths {
-		if strings.EqualFold(mp.name, metaname) {
-			return mp.path
-		}
+	if mp, ok := metapathForName(metaname); ok {
+		return mp.path
 	}
 
 	// Unknown, so use metaname
@@ -3,10 +3,12 @@ package salesforce_test
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	"github.com/joist-engineering/force/salesforce"
 
 	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
 )
 
@@ -76,6 +78,20 @@ var _ = Describe("Packagebuilder", func() {
 			})
 		})
 
+		Context("when adding the meta.xml file of a class", func() {
+			It("should add the class with it", func() {
+				os.MkdirAll(tempDir+"/src/classes", 0755)
+				ioutil.WriteFile(tempDir+"/src/classes/Test.cls", []byte("class Test {}"), 0644)
+				ioutil.WriteFile(tempDir+"/src/classes/Test.cls-meta.xml", []byte(`<?xml version="1.0" encoding="UTF-8"?>`), 0644)
+
+				_, err := pb.AddFile(tempDir + "/src/classes/Test.cls-meta.xml")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pb.Files).To(HaveKey("classes/Test.cls"))
+				Expect(pb.Files).To(HaveKey("classes/Test.cls-meta.xml"))
+				Expect(pb.Metadata["ApexClass"].Members).To(Equal([]string{"Test"}))
+			})
+		})
+
 		Context("when adding a CustomMetadata file", func() {
 			var customMetadataPath string
 
@@ -135,5 +151,108 @@ var _ = Describe("Packagebuilder", func() {
 				Expect(pb.Metadata).To(BeEmpty())
 			})
 		})
+
+		Context("when adding a file of a decomposed object", func() {
+			var fieldPath string
+
+			BeforeEach(func() {
+				os.MkdirAll(tempDir+"/src/objects/Account/fields", 0755)
+				fieldPath = tempDir + "/src/objects/Account/fields/Region__c.field-meta.xml"
+				ioutil.WriteFile(tempDir+"/src/objects/Account/Account.object-meta.xml", []byte(`<CustomObject/>`), 0644)
+				ioutil.WriteFile(fieldPath, []byte(`<CustomField/>`), 0644)
+			})
+
+			It("should add the whole object to package", func() {
+				_, err := pb.AddFile(fieldPath)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pb.Files).To(HaveKey("objects/Account/Account.object-meta.xml"))
+				Expect(pb.Files).To(HaveKey("objects/Account/fields/Region__c.field-meta.xml"))
+			})
+			It("should add the object to the package.xml", func() {
+				pb.AddFile(fieldPath)
+				Expect(pb.Metadata).To(HaveKey("CustomObject"))
+				Expect(pb.Metadata["CustomObject"].Members).To(Equal([]string{"Account"}))
+			})
+		})
+
+		Context("when adding a file of a project under a directory called profiles", func() {
+			It("should not take the project for a decomposed profile", func() {
+				classPath := tempDir + "/profiles/myproj/classes/MyClass.cls"
+				os.MkdirAll(filepath.Dir(classPath), 0755)
+				ioutil.WriteFile(classPath, []byte(`public class MyClass {}`), 0644)
+
+				_, err := pb.AddFile(classPath)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pb.Metadata).ToNot(HaveKey("Profile"))
+				Expect(pb.Metadata).To(HaveKey("ApexClass"))
+			})
+		})
+
+		DescribeTable("when adding files of other metadata types",
+			func(fpath string, metaName string, member string) {
+				fullPath := filepath.Join(tempDir, "src", fpath)
+				os.MkdirAll(filepath.Dir(fullPath), 0755)
+				ioutil.WriteFile(fullPath, []byte(`<?xml version="1.0" encoding="UTF-8"?>`), 0644)
+
+				_, err := pb.AddFile(fullPath)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(pb.Files).To(HaveKey(fpath))
+				Expect(pb.Metadata).To(HaveKey(metaName))
+				Expect(pb.Metadata[metaName].Members).To(Equal([]string{member}))
+			},
+			Entry("lightning web component", "lwc/hello/hello.js", "LightningComponentBundle", "hello"),
+			Entry("custom metadata with a dotted name", "customMetadata/Region.West.Coast.md", "CustomMetadata", "Region.West.Coast"),
+			Entry("platform event channel", "platformEventChannels/Changes.platformEventChannel", "PlatformEventChannel", "Changes"),
+			Entry("named credential", "namedCredentials/Billing.namedCredential", "NamedCredential", "Billing"),
+			Entry("custom notification type", "customNotificationTypes/Escalation.notiftype", "CustomNotificationType", "Escalation"),
+			Entry("custom notification type in the API directory", "notificationtypes/Escalation.notiftype", "CustomNotificationType", "Escalation"),
+			Entry("duplicate rule", "duplicateRules/Account.Standard_Rule.duplicateRule", "DuplicateRule", "Account.Standard_Rule"),
+			Entry("matching rules", "matchingRules/Account.matchingRule", "MatchingRules", "Account"),
+			Entry("document", "documents/Shared/logo.png", "Document", "Shared/logo.png"),
+		)
+
+		Context("when using the metadata types described by the org", func() {
+			var restoreMetapaths func()
+
+			BeforeEach(func() {
+				restoreMetapaths = salesforce.SaveMetapaths()
+				salesforce.UseDescribedMetapaths(salesforce.MetadataDescribeResult{
+					MetadataObjects: []salesforce.DescribeMetadataObject{
+						{DirectoryName: "widgets", XmlName: "Widget", Suffix: "widget"},
+						{DirectoryName: "widgetBundles", XmlName: "WidgetBundle"},
+					},
+				})
+			})
+
+			AfterEach(func() {
+				restoreMetapaths()
+			})
+
+			It("should recognize new types", func() {
+				widgetPath := filepath.Join(tempDir, "src", "widgets", "Spinner.widget")
+				os.MkdirAll(filepath.Dir(widgetPath), 0755)
+				ioutil.WriteFile(widgetPath, []byte(`<Widget/>`), 0644)
+
+				pb.AddFile(widgetPath)
+				Expect(pb.Metadata["Widget"].Members).To(Equal([]string{"Spinner"}))
+			})
+			It("should treat types without a suffix as bundles", func() {
+				bundlePath := filepath.Join(tempDir, "src", "widgetBundles", "spinner", "spinner.js")
+				os.MkdirAll(filepath.Dir(bundlePath), 0755)
+				ioutil.WriteFile(bundlePath, []byte(`spin()`), 0644)
+
+				pb.AddFile(bundlePath)
+				Expect(pb.Files).To(HaveKey("widgetBundles/spinner/spinner.js"))
+				Expect(pb.Metadata["WidgetBundle"].Members).To(Equal([]string{"spinner"}))
+			})
+			It("should keep the types it does not describe", func() {
+				classPath := filepath.Join(tempDir, "src", "classes", "Test.cls")
+				os.MkdirAll(filepath.Dir(classPath), 0755)
+				ioutil.WriteFile(classPath, []byte(`class Test {}`), 0644)
+
+				pb.AddFile(classPath)
+				Expect(pb.Metadata["ApexClass"].Members).To(Equal([]string{"Test"}))
+			})
+		})
 	})
 })
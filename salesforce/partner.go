@@ -83,6 +83,9 @@ func (partner *ForcePartner) ExecuteAnonymous(apex string) (output string, err e
 	return
 }
 
+// debuggingHeader asks for the debug log of a call.
+const debuggingHeader = "<apex:DebuggingHeader><apex:debugLevel>DEBUGONLY</apex:debugLevel></apex:DebuggingHeader>"
+
 func (partner *ForcePartner) SoapExecuteCore(action, query string) (response []byte, err error) {
 	login, err := partner.Force.Get(partner.Force.Credentials.Id)
 	if err != nil {
@@ -91,10 +94,7 @@ func (partner *ForcePartner) SoapExecuteCore(action, query string) (response []b
 	version := partner.Force.Credentials.ApiVersionNumber()
 	url := strings.Replace(login["urls"].(map[string]interface{})["partner"].(string), "{version}", version, 1)
 	//url = strings.Replace(url, "/u/", "/s/", 1) // seems dirty
-	soap := NewSoap(url, "urn:partner.soap.sforce.com", partner.Force.Credentials.AccessToken)
-	soap.Header = "<apex:DebuggingHeader><apex:debugLevel>DEBUGONLY</apex:debugLevel></apex:DebuggingHeader>"
-	response, err = soap.Execute(action, query)
-	return
+	return partner.Force.soapExecute(url, "urn:partner.soap.sforce.com", debuggingHeader, action, query)
 }
 
 func (partner *ForcePartner) RunTests(tests []string, namespace string) (output TestCoverage, err error) {
@@ -130,8 +130,5 @@ func (partner *ForcePartner) soapExecute(action, query string) (response []byte,
 	version := partner.Force.Credentials.ApiVersionNumber()
 	url := strings.Replace(login["urls"].(map[string]interface{})["partner"].(string), "{version}", version, 1)
 	url = strings.Replace(url, "/u/", "/s/", 1) // seems dirty
-	soap := NewSoap(url, "http://soap.sforce.com/2006/08/apex", partner.Force.Credentials.AccessToken)
-	soap.Header = "<apex:DebuggingHeader><apex:debugLevel>DEBUGONLY</apex:debugLevel></apex:DebuggingHeader>"
-	response, err = soap.Execute(action, query)
-	return
+	return partner.Force.soapExecute(url, "http://soap.sforce.com/2006/08/apex", debuggingHeader, action, query)
 }
@@ -0,0 +1,113 @@
+package salesforce
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+)
+
+// QueryIterator reads the results of a query a page at a time, so that
+// queries with millions of records don't have to fit in memory.
+//
+//	it := force.NewQueryIterator(ctx, "SELECT Id, Name FROM Account", false)
+//	for it.NextPage() {
+//		for _, record := range it.Records() {
+//			...
+//		}
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type QueryIterator struct {
+	force   *Force
+	ctx     context.Context
+	nextUrl string
+	page    ForceQueryResult
+	err     error
+}
+
+// NewQueryIterator starts a query.  Nothing is sent until the first call to
+// NextPage, and cancelling ctx stops the query between or during pages.
+func (f *Force) NewQueryIterator(ctx context.Context, query string, isTooling bool) *QueryIterator {
+	return f.newQueryIterator(ctx, f.queryUrl("query", query, isTooling))
+}
+
+// NewQueryAllIterator starts a query that includes records that have been
+// deleted or archived, as QueryAll does.
+func (f *Force) NewQueryAllIterator(ctx context.Context, query string) *QueryIterator {
+	return f.newQueryIterator(ctx, f.queryUrl("queryAll", query, false))
+}
+
+// ResumeQuery carries on with a query from the nextRecordsUrl of one of its
+// pages, as long as Salesforce hasn't yet expired the query's cursor.  An
+// empty nextRecordsUrl, from the last page, has no more pages.
+func (f *Force) ResumeQuery(ctx context.Context, nextRecordsUrl string) *QueryIterator {
+	if nextRecordsUrl == "" {
+		return f.newQueryIterator(ctx, "")
+	}
+	return f.newQueryIterator(ctx, f.Credentials.InstanceUrl+nextRecordsUrl)
+}
+
+func (f *Force) newQueryIterator(ctx context.Context, firstUrl string) *QueryIterator {
+	return &QueryIterator{force: f, ctx: ctx, nextUrl: firstUrl}
+}
+
+func (f *Force) queryUrl(resource, query string, isTooling bool) string {
+	if isTooling {
+		resource = "tooling/" + resource
+	}
+	return fmt.Sprintf("%s/services/data/%s/%s?q=%s", f.Credentials.InstanceUrl, f.Credentials.ApiVersion, resource, url.QueryEscape(query))
+}
+
+// NextPage fetches the next page of records.  It returns false when there are
+// no more pages, or a page couldn't be fetched, which Err then reports.
+func (it *QueryIterator) NextPage() bool {
+	if it.err != nil || it.nextUrl == "" {
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		return false
+	}
+	body, err := it.force.httpGetContext(it.ctx, it.nextUrl)
+	if err != nil {
+		it.err = err
+		return false
+	}
+	var page ForceQueryResult
+	if err = json.Unmarshal(body, &page); err != nil {
+		it.err = fmt.Errorf("unable to read query results: %s", err.Error())
+		return false
+	}
+	it.page = page
+	it.nextUrl = ""
+	if !page.Done && page.NextRecordsUrl != "" {
+		it.nextUrl = it.force.Credentials.InstanceUrl + page.NextRecordsUrl
+	}
+	return true
+}
+
+// Records returns the records of the current page.
+func (it *QueryIterator) Records() []ForceRecord {
+	return it.page.Records
+}
+
+// TotalSize returns the number of records the query matched, once the first
+// page has been fetched.
+func (it *QueryIterator) TotalSize() int {
+	return it.page.TotalSize
+}
+
+// NextRecordsUrl returns where the page after the current one is, to be given
+// to ResumeQuery, or "" if the current page is the last.
+func (it *QueryIterator) NextRecordsUrl() string {
+	if it.page.Done {
+		return ""
+	}
+	return it.page.NextRecordsUrl
+}
+
+// Err returns the error that stopped the query, if any.
+func (it *QueryIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,150 @@
+package salesforce_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("QueryIterator", func() {
+	var (
+		failPage  int32
+		requested int32
+	)
+
+	page := func(w http.ResponseWriter, n int, next string) {
+		atomic.AddInt32(&requested, 1)
+		if int32(n) == atomic.LoadInt32(&failPage) {
+			w.WriteHeader(400)
+			w.Write([]byte(`[{"message":"invalid query locator","errorCode":"INVALID_QUERY_LOCATOR"}]`))
+			return
+		}
+		done := next == ""
+		fmt.Fprintf(w, `{"totalSize":5,"done":%t,"nextRecordsUrl":"%s","records":[{"Name":"%d-a"},{"Name":"%d-b"}]}`, done, next, n, n)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services/data/v45.0/query", func(w http.ResponseWriter, r *http.Request) {
+		page(w, 1, "/services/data/v45.0/query/01g-2000")
+	})
+	mux.HandleFunc("/services/data/v45.0/query/01g-2000", func(w http.ResponseWriter, r *http.Request) {
+		page(w, 2, "/services/data/v45.0/query/01g-4000")
+	})
+	mux.HandleFunc("/services/data/v45.0/query/01g-4000", func(w http.ResponseWriter, r *http.Request) {
+		page(w, 3, "")
+	})
+	mux.HandleFunc("/services/data/v45.0/queryAll", func(w http.ResponseWriter, r *http.Request) {
+		page(w, 4, "/services/data/v45.0/queryAll/01g-2000")
+	})
+	mux.HandleFunc("/services/data/v45.0/queryAll/01g-2000", func(w http.ResponseWriter, r *http.Request) {
+		page(w, 5, "")
+	})
+	org := serveOrg(mux)
+
+	BeforeEach(func() {
+		failPage = 0
+		requested = 0
+	})
+
+	names := func(records []salesforce.ForceRecord) (names []string) {
+		for _, record := range records {
+			names = append(names, record["Name"].(string))
+		}
+		return
+	}
+
+	It("should read the records a page at a time", func() {
+		it := org.force.NewQueryIterator(context.Background(), "SELECT Name FROM Account", false)
+		var pages [][]string
+		for it.NextPage() {
+			pages = append(pages, names(it.Records()))
+		}
+		Expect(it.Err()).ToNot(HaveOccurred())
+		Expect(pages).To(Equal([][]string{{"1-a", "1-b"}, {"2-a", "2-b"}, {"3-a", "3-b"}}))
+		Expect(it.TotalSize()).To(Equal(5))
+		Expect(it.NextRecordsUrl()).To(Equal(""))
+	})
+
+	It("should stop at a page that fails", func() {
+		failPage = 2
+		it := org.force.NewQueryIterator(context.Background(), "SELECT Name FROM Account", false)
+		Expect(it.NextPage()).To(BeTrue())
+		Expect(it.NextRecordsUrl()).To(Equal("/services/data/v45.0/query/01g-2000"))
+		Expect(it.NextPage()).To(BeFalse())
+		Expect(it.Err()).To(MatchError("invalid query locator"))
+		Expect(it.NextPage()).To(BeFalse())
+	})
+
+	It("should report errors on later pages from Query", func() {
+		failPage = 3
+		_, err := org.force.Query("SELECT Name FROM Account", false)
+		Expect(err).To(MatchError("invalid query locator"))
+	})
+
+	It("should stop when cancelled", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		it := org.force.NewQueryIterator(ctx, "SELECT Name FROM Account", false)
+		Expect(it.NextPage()).To(BeTrue())
+		cancel()
+		Expect(it.NextPage()).To(BeFalse())
+		Expect(it.Err()).To(Equal(context.Canceled))
+		Expect(atomic.LoadInt32(&requested)).To(BeEquivalentTo(1))
+	})
+
+	It("should resume from a page", func() {
+		it := org.force.ResumeQuery(context.Background(), "/services/data/v45.0/query/01g-4000")
+		Expect(it.NextPage()).To(BeTrue())
+		Expect(names(it.Records())).To(Equal([]string{"3-a", "3-b"}))
+		Expect(it.NextPage()).To(BeFalse())
+		Expect(it.Err()).ToNot(HaveOccurred())
+	})
+
+	It("should include deleted records with QueryAll", func() {
+		result, err := org.force.QueryAll("SELECT Name FROM Account")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(names(result.Records)).To(Equal([]string{"4-a", "4-b", "5-a", "5-b"}))
+		Expect(result.Done).To(BeTrue())
+	})
+
+})
+
+var _ = Describe("ExplainQuery", func() {
+	It("should read the plans of a recorded explanation", func() {
+		fixture, err := ioutil.ReadFile("testdata/explain.json")
+		Expect(err).ToNot(HaveOccurred())
+		var explained string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/services/data/v45.0/query"))
+			explained = r.URL.Query().Get("explain")
+			w.Write(fixture)
+		}))
+		defer server.Close()
+		force := newTestForce(server.URL)
+
+		explanation, err := force.ExplainQuery("SELECT Id FROM Account WHERE Name = 'Acme' AND Industry = 'Energy'")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(explained).To(Equal("SELECT Id FROM Account WHERE Name = 'Acme' AND Industry = 'Energy'"))
+		Expect(explanation.Plans).To(HaveLen(2))
+		Expect(explanation.Plans[0]).To(Equal(salesforce.ForceQueryPlan{
+			Cardinality:          12,
+			Fields:               []string{"Name"},
+			LeadingOperationType: "Index",
+			Notes:                []salesforce.ForceQueryPlanNote{},
+			RelativeCost:         0.0005,
+			SobjectCardinality:   24873,
+			SobjectType:          "Account",
+		}))
+		Expect(explanation.Plans[1].Notes[0]).To(Equal(salesforce.ForceQueryPlanNote{
+			Description:   "Not considering filter for optimization because unindexed",
+			Fields:        []string{"IsDeleted"},
+			TableEnumOrId: "Account",
+		}))
+	})
+})
@@ -0,0 +1,142 @@
+package salesforce
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy says how often, and how long to wait before, requests that fail
+// for transient reasons are sent again.
+//
+// Idempotent requests, such as GETs and SOAP status polls, are retried when
+// Salesforce is unavailable or over its request limits, and when the
+// connection fails.  Other requests are only retried when the connection
+// failed before anything was sent, so they are never applied twice.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent.  One or less turns
+	// retrying off.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, which doubles for each
+	// retry after it, up to MaxDelay.  Each wait is randomly shortened by up
+	// to half so that clients don't retry in step.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// transientErrorCodes are Salesforce error codes for failures that are worth
+// retrying.
+var transientErrorCodes = []string{
+	"REQUEST_LIMIT_EXCEEDED",
+	"SERVER_UNAVAILABLE",
+}
+
+// Do sends the request built by newRequest, which is called again for each
+// retry so that its body can be sent again.
+func (p RetryPolicy) Do(client *http.Client, idempotent bool, newRequest func() (*http.Request, error)) (res *http.Response, err error) {
+	for attempt := 1; ; attempt++ {
+		var req *http.Request
+		if req, err = newRequest(); err != nil {
+			return
+		}
+		res, err = client.Do(req)
+		if attempt >= p.MaxAttempts {
+			return
+		}
+
+		var wait time.Duration
+		if err != nil {
+			if errors.Is(err, context.Canceled) || !(idempotent || isNotSent(err)) {
+				return
+			}
+		} else {
+			if !idempotent || !isTransientResponse(res) {
+				return
+			}
+			wait = retryAfter(res)
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+		if backoff := p.backoff(attempt); backoff > wait {
+			wait = backoff
+		}
+		select {
+		case <-time.After(wait):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+	}
+}
+
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << uint(attempt-1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	if delay <= 1 {
+		return delay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// isNotSent reports whether a request failed before any of it was sent, while
+// looking up or connecting to the server.
+func isNotSent(err error) bool {
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Op == "dial" || opErr.Op == "proxyconnect"
+	}
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr)
+}
+
+// isTransientResponse reports whether a response is a failure worth retrying.
+// The body is left to be read again.
+func isTransientResponse(res *http.Response) bool {
+	switch res.StatusCode {
+	case 429, 502, 503, 504:
+		return true
+	case 400, 403, 500:
+		body, err := ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		res.Body = ioutil.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return true
+		}
+		for _, code := range transientErrorCodes {
+			if bytes.Contains(body, []byte(code)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// retryAfter reads the Retry-After header, in seconds or as a date.
+func retryAfter(res *http.Response) time.Duration {
+	value := strings.TrimSpace(res.Header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
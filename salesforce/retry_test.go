@@ -0,0 +1,143 @@
+package salesforce_test
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retrying", func() {
+	var (
+		server   *httptest.Server
+		requests int32
+		failures int32
+		failWith func(w http.ResponseWriter)
+		policy   salesforce.RetryPolicy
+	)
+
+	BeforeEach(func() {
+		requests = 0
+		failures = 2
+		failWith = func(w http.ResponseWriter) {
+			w.WriteHeader(503)
+		}
+		policy = salesforce.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&requests, 1) <= atomic.LoadInt32(&failures) {
+				failWith(w)
+				return
+			}
+			w.Write([]byte(`{"totalSize":0,"done":true,"records":[]}`))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newForce := func(url string) *salesforce.Force {
+		force := newTestForce(url)
+		force.Retry = policy
+		return force
+	}
+
+	It("should retry GETs while Salesforce is unavailable", func() {
+		_, err := newForce(server.URL).Query("SELECT Id FROM Account", false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(3))
+	})
+
+	It("should retry when over the request limit", func() {
+		failWith = func(w http.ResponseWriter) {
+			w.WriteHeader(403)
+			w.Write([]byte(`[{"message":"TotalRequests Limit exceeded.","errorCode":"REQUEST_LIMIT_EXCEEDED"}]`))
+		}
+		_, err := newForce(server.URL).Query("SELECT Id FROM Account", false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(3))
+	})
+
+	It("should not retry other errors", func() {
+		failWith = func(w http.ResponseWriter) {
+			w.WriteHeader(400)
+			w.Write([]byte(`[{"message":"unexpected token: FORM","errorCode":"MALFORMED_QUERY"}]`))
+		}
+		_, err := newForce(server.URL).Query("SELECT Id FORM Account", false)
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(1))
+	})
+
+	It("should wait as long as Retry-After says", func() {
+		failures = 1
+		failWith = func(w http.ResponseWriter) {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(429)
+		}
+		start := time.Now()
+		_, err := newForce(server.URL).Query("SELECT Id FROM Account", false)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically(">=", time.Second))
+	})
+
+	It("should give up after the most attempts", func() {
+		failures = 10
+		res, err := policy.Do(http.DefaultClient, true, func() (*http.Request, error) {
+			return http.NewRequest("GET", server.URL, nil)
+		})
+		Expect(err).ToNot(HaveOccurred())
+		res.Body.Close()
+		Expect(res.StatusCode).To(Equal(503))
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(4))
+	})
+
+	It("should not retry a POST that Salesforce received", func() {
+		failWith = func(w http.ResponseWriter) {
+			w.WriteHeader(503)
+			w.Write([]byte(`[{"message":"Server unavailable","errorCode":"SERVER_UNAVAILABLE"}]`))
+		}
+		_, err, _ := newForce(server.URL).CreateRecord("Account", map[string]string{"Name": "Joist"})
+		Expect(err).To(HaveOccurred())
+		Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(1))
+	})
+
+	It("should retry a POST that could not connect", func() {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+		closed := "http://" + listener.Addr().String()
+		listener.Close()
+
+		attempts := 0
+		_, err = policy.Do(http.DefaultClient, false, func() (*http.Request, error) {
+			attempts++
+			return http.NewRequest("POST", closed, nil)
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(attempts).To(Equal(4))
+	})
+
+	Describe("SOAP calls", func() {
+		execute := func(action string) error {
+			soap := salesforce.NewSoap(server.URL, "http://soap.sforce.com/2006/04/metadata", "token")
+			soap.Retry = policy
+			_, err := soap.Execute(action, "<id>0Af000000000001</id>")
+			return err
+		}
+
+		It("should retry status polls", func() {
+			Expect(execute("checkStatus")).To(Succeed())
+			Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(3))
+		})
+
+		It("should not retry deploys", func() {
+			execute("deploy")
+			Expect(atomic.LoadInt32(&requests)).To(BeEquivalentTo(1))
+		})
+	})
+})
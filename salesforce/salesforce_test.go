@@ -1,6 +1,11 @@
 package salesforce_test
 
 import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/joist-engineering/force/salesforce"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -11,3 +16,37 @@ func TestForce(t *testing.T) {
 	RegisterFailHandler(Fail)
 	RunSpecs(t, "Salesforce Module Suite")
 }
+
+// testOrg is an httptest server standing in for an org, and a Force logged
+// in to it.
+type testOrg struct {
+	server *httptest.Server
+	force  *salesforce.Force
+}
+
+// newTestForce returns a Force logged in to the org at url that doesn't
+// retry failed requests.
+func newTestForce(url string) *salesforce.Force {
+	force := salesforce.NewForce(salesforce.ForceCredentials{
+		AccessToken: "token",
+		InstanceUrl: url,
+		Id:          url + "/id/00D/005",
+		ApiVersion:  "v45.0",
+	})
+	force.Retry = salesforce.RetryPolicy{}
+	return force
+}
+
+// serveOrg serves handler as an org for each spec of the enclosing
+// container, closing the server after the spec.
+func serveOrg(handler http.Handler) *testOrg {
+	org := &testOrg{}
+	BeforeEach(func() {
+		org.server = httptest.NewServer(handler)
+		org.force = newTestForce(org.server.URL)
+	})
+	AfterEach(func() {
+		org.server.Close()
+	})
+	return org
+}
@@ -0,0 +1,114 @@
+package salesforce
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// ForceTokenLogin uses an existing session id or access token for an
+// instance.  The token is checked by looking up the identity of its user.
+func ForceTokenLogin(instanceUrl string, token string) (creds ForceCredentials, err error) {
+	instanceUrl, err = normalizeInstanceUrl(instanceUrl)
+	if err != nil {
+		return
+	}
+	creds = ForceCredentials{
+		AccessToken:   token,
+		InstanceUrl:   instanceUrl,
+		IsCustomEP:    true,
+		ApiVersion:    DefaultApiVersion,
+		ForceEndpoint: EndpointCustom,
+	}
+	userinfo, err := NewForce(creds).Get(instanceUrl + "/services/oauth2/userinfo")
+	if err != nil {
+		return
+	}
+	id, _ := userinfo["sub"].(string)
+	if id == "" {
+		err = errors.New("unable to find the identity of the token's user")
+		return
+	}
+	creds.Id = id
+	return
+}
+
+// ForceAuthUrlLogin logs in with a Salesforce DX auth URL, of the form
+// force://<client id>:<client secret>:<refresh token>@<instance>, exchanging
+// its refresh token for an access token.
+func ForceAuthUrlLogin(authUrl string) (creds ForceCredentials, err error) {
+	clientId, clientSecret, refreshToken, instanceUrl, err := ParseAuthUrl(authUrl)
+	if err != nil {
+		return
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {clientId},
+	}
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	token, err := requestToken(SharedHTTPClient(), instanceUrl, form)
+	if err != nil {
+		return
+	}
+	if token.InstanceUrl != "" {
+		instanceUrl = token.InstanceUrl
+	}
+	creds = ForceCredentials{
+		AccessToken:   token.AccessToken,
+		Id:            token.Id,
+		InstanceUrl:   instanceUrl,
+		IssuedAt:      token.IssuedAt,
+		Scope:         token.Scope,
+		IsCustomEP:    true,
+		ApiVersion:    DefaultApiVersion,
+		ForceEndpoint: EndpointCustom,
+		RefreshToken:  refreshToken,
+		ClientId:      clientId,
+		ClientSecret:  clientSecret,
+	}
+	return
+}
+
+// ParseAuthUrl splits a Salesforce DX auth URL into its parts.  The client
+// secret is often empty.  The instance may include a scheme, and defaults to
+// https.
+func ParseAuthUrl(authUrl string) (clientId string, clientSecret string, refreshToken string, instanceUrl string, err error) {
+	authUrl = strings.TrimSpace(authUrl)
+	if !strings.HasPrefix(authUrl, "force://") {
+		err = errors.New("auth URL must start with force://")
+		return
+	}
+	rest := strings.TrimPrefix(authUrl, "force://")
+	at := strings.LastIndex(rest, "@")
+	if at < 0 {
+		err = errors.New("auth URL has no instance")
+		return
+	}
+	parts := strings.SplitN(rest[:at], ":", 3)
+	if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+		err = errors.New("auth URL must be of the form force://<client id>:<client secret>:<refresh token>@<instance>")
+		return
+	}
+	clientId, clientSecret, refreshToken = parts[0], parts[1], parts[2]
+	instanceUrl, err = normalizeInstanceUrl(rest[at+1:])
+	return
+}
+
+// normalizeInstanceUrl reduces an instance to its scheme and host, adding
+// https if there is no scheme.
+func normalizeInstanceUrl(instance string) (string, error) {
+	if !strings.Contains(instance, "://") {
+		instance = "https://" + instance
+	}
+	u, err := url.Parse(instance)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", errors.New("no host in instance URL: " + instance)
+	}
+	return u.Scheme + "://" + u.Host, nil
+}
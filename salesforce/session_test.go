@@ -0,0 +1,112 @@
+package salesforce_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Session", func() {
+	var (
+		server *httptest.Server
+		form   chan map[string]string
+	)
+
+	BeforeEach(func() {
+		form = make(chan map[string]string, 1)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/services/oauth2/userinfo", func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer 00D!session" {
+				w.WriteHeader(401)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"sub":     "https://login.salesforce.com/id/00D000000000001/005000000000001",
+				"user_id": "005000000000001",
+			})
+		})
+		mux.HandleFunc("/services/oauth2/token", func(w http.ResponseWriter, r *http.Request) {
+			r.ParseForm()
+			form <- map[string]string{
+				"grant_type":    r.PostForm.Get("grant_type"),
+				"refresh_token": r.PostForm.Get("refresh_token"),
+				"client_id":     r.PostForm.Get("client_id"),
+				"client_secret": r.PostForm.Get("client_secret"),
+			}
+			json.NewEncoder(w).Encode(map[string]string{
+				"access_token": "00D!access",
+				"id":           "https://login.salesforce.com/id/00D000000000001/005000000000001",
+			})
+		})
+		server = httptest.NewServer(mux)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("ForceTokenLogin", func() {
+		It("should look up the identity of the token's user", func() {
+			creds, err := salesforce.ForceTokenLogin(server.URL+"/lightning/page/home", "00D!session")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(creds.AccessToken).To(Equal("00D!session"))
+			Expect(creds.InstanceUrl).To(Equal(server.URL))
+			Expect(creds.Id).To(Equal("https://login.salesforce.com/id/00D000000000001/005000000000001"))
+		})
+
+		It("should reject an invalid token", func() {
+			_, err := salesforce.ForceTokenLogin(server.URL, "expired")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("ForceAuthUrlLogin", func() {
+		It("should exchange the refresh token", func() {
+			creds, err := salesforce.ForceAuthUrlLogin("force://PlatformCLI:secret:5Aep861!x.y@" + server.URL)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(<-form).To(Equal(map[string]string{
+				"grant_type":    "refresh_token",
+				"refresh_token": "5Aep861!x.y",
+				"client_id":     "PlatformCLI",
+				"client_secret": "secret",
+			}))
+			Expect(creds.AccessToken).To(Equal("00D!access"))
+			Expect(creds.InstanceUrl).To(Equal(server.URL))
+			Expect(creds.Id).To(Equal("https://login.salesforce.com/id/00D000000000001/005000000000001"))
+			Expect(creds.RefreshToken).To(Equal("5Aep861!x.y"))
+			Expect(creds.ClientId).To(Equal("PlatformCLI"))
+		})
+	})
+
+	Describe("ParseAuthUrl", func() {
+		DescribeTable("valid auth urls",
+			func(authUrl, clientId, clientSecret, refreshToken, instanceUrl string) {
+				id, secret, refresh, instance, err := salesforce.ParseAuthUrl(authUrl)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(id).To(Equal(clientId))
+				Expect(secret).To(Equal(clientSecret))
+				Expect(refresh).To(Equal(refreshToken))
+				Expect(instance).To(Equal(instanceUrl))
+			},
+			Entry("without a secret", "force://PlatformCLI::5Aep861@example.my.salesforce.com", "PlatformCLI", "", "5Aep861", "https://example.my.salesforce.com"),
+			Entry("with a secret", "force://3MVG9:ABC123:5Aep861@example.my.salesforce.com", "3MVG9", "ABC123", "5Aep861", "https://example.my.salesforce.com"),
+			Entry("with a scheme", "force://PlatformCLI::5Aep861@https://example.my.salesforce.com/", "PlatformCLI", "", "5Aep861", "https://example.my.salesforce.com"),
+		)
+
+		DescribeTable("invalid auth urls",
+			func(authUrl string) {
+				_, _, _, _, err := salesforce.ParseAuthUrl(authUrl)
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("wrong scheme", "https://PlatformCLI::5Aep861@example.my.salesforce.com"),
+			Entry("no instance", "force://PlatformCLI::5Aep861"),
+			Entry("no refresh token", "force://PlatformCLI:@example.my.salesforce.com"),
+		)
+	})
+})
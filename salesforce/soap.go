@@ -1,27 +1,40 @@
 package salesforce
 
 import (
+	"bytes"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"strings"
 )
 
-type SoapError struct {
-	FaultCode   string `xml:"Body>Fault>faultcode"`
-	FaultString string `xml:"Body>Fault>faultstring"`
-}
-
 type Soap struct {
 	AccessToken string
 	Endpoint    string
 	Header      string
 	Namespace   string
+	Client      *http.Client
+	Retry       RetryPolicy
+}
+
+// idempotentActions are the SOAP calls that only read, and so can be retried
+// however they failed.
+var idempotentActions = map[string]bool{
+	"checkDeployStatus":   true,
+	"checkRetrieveStatus": true,
+	"checkStatus":         true,
+	"describeMetadata":    true,
+	"describeValueType":   true,
+	"listMetadata":        true,
+	"readMetadata":        true,
 }
 
 func NewSoap(endpoint, namespace, accessToken string) (s *Soap) {
 	s = new(Soap)
+	s.Client = SharedHTTPClient()
+	s.Retry = DefaultRetryPolicy
 	s.AccessToken = accessToken
 	s.Namespace = namespace
 	s.Endpoint = endpoint
@@ -48,21 +61,17 @@ func (s *Soap) ExecuteLogin(username, password string) (response []byte, err err
 	req.Header.Add("Content-Type", "text/xml")
 	req.Header.Add("SOAPACtion", "login")
 
-	res, err := doRequest(req)
+	res, err := s.Client.Do(req)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
 	defer res.Body.Close()
-	if res.StatusCode == 401 {
-		err = errors.New("authorization expired, please run `force login`")
-		return
-	}
 	response, err = ioutil.ReadAll(res.Body)
 	if err != nil {
 		return
 	}
-	err = processError(response)
+	err = processError(res.StatusCode, response)
 	return
 
 }
@@ -96,7 +105,7 @@ func (s *Soap) ExecuteLogin(username, password string) (response []byte, err err
 	}
 	defer res.Body.Close()
 	if res.StatusCode == 401 {
-		err = errors.New("authorization expired, please run `force login`")
+		err = ErrAuthorizationExpired
 		return
 	}
 	response, err = ioutil.ReadAll(res.Body)
@@ -109,6 +118,19 @@ func (s *Soap) ExecuteLogin(username, password string) (response []byte, err err
 }*/
 
 func (s *Soap) Execute(action, query string) (response []byte, err error) {
+	newRequest := s.request(action, query)
+	res, err := s.Retry.Do(s.Client, idempotentActions[action], func() (*http.Request, error) {
+		return newRequest(s.AccessToken)
+	})
+	if err != nil {
+		return
+	}
+	return readSoapResponse(res)
+}
+
+// request returns a function that builds the request for a call with an
+// access token, so that it can be built again with a new one.
+func (s *Soap) request(action, query string) func(token string) (*http.Request, error) {
 	soap := `
 		<env:Envelope xmlns:xsd="http://www.w3.org/2001/XMLSchema"
 		xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"
@@ -128,37 +150,66 @@ func (s *Soap) Execute(action, query string) (response []byte, err error) {
 			</env:Body>
 		</env:Envelope>
 	`
-	rbody := fmt.Sprintf(soap, s.Namespace,
-		s.AccessToken, s.Header, action, s.Namespace, query, action)
-	//fmt.Println(rbody)
-	req, err := httpRequest("POST", s.Endpoint, strings.NewReader(rbody))
-	if err != nil {
-		return
-	}
-	req.Header.Add("Content-Type", "text/xml")
-	req.Header.Add("SOAPACtion", action)
-	res, err := doRequest(req)
-	if err != nil {
+	return func(token string) (req *http.Request, err error) {
+		rbody := fmt.Sprintf(soap, s.Namespace,
+			token, s.Header, action, s.Namespace, query, action)
+		req, err = httpRequest("POST", s.Endpoint, strings.NewReader(rbody))
+		if err != nil {
+			return
+		}
+		req.Header.Add("Content-Type", "text/xml")
+		req.Header.Add("SOAPACtion", action)
 		return
 	}
+}
+
+func readSoapResponse(res *http.Response) (response []byte, err error) {
 	defer res.Body.Close()
-	if res.StatusCode == 401 {
-		err = errors.New("authorization expired, please run `force login`")
-		return
-	}
 	response, err = ioutil.ReadAll(res.Body)
 	if err != nil {
 		return
 	}
-	err = processError(response)
+	err = processError(res.StatusCode, response)
 	return
 }
 
-func processError(body []byte) (err error) {
-	var soapError SoapError
-	xml.Unmarshal(body, &soapError)
-	if soapError.FaultCode != "" {
-		return errors.New(soapError.FaultString)
+// soapExecute makes a SOAP call with the Force's access token, through httpDo,
+// so that an expired session is refreshed and the call made once more just as
+// for REST requests.
+func (f *Force) soapExecute(endpoint, namespace, header, action, query string) (response []byte, err error) {
+	soap := &Soap{Endpoint: endpoint, Namespace: namespace, Header: header}
+	res, err := f.httpDoUnless(idempotentActions[action], soap.request(action, query), soapSessionExpired)
+	if err != nil {
+		return
+	}
+	return readSoapResponse(res)
+}
+
+// soapSessionExpired reports whether a SOAP call was refused for an expired
+// session, which SOAP gives as an INVALID_SESSION_ID fault rather than a 401.
+// The body is left to be read again.
+func soapSessionExpired(res *http.Response) bool {
+	if res.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+	if res.StatusCode/100 == 2 {
+		return false
+	}
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return err == nil && errors.Is(newAPIError(res.StatusCode, body), ErrAuthorizationExpired)
+}
+
+// processError returns the fault in a SOAP response, or an *APIError for any
+// other failed response.
+func processError(statusCode int, body []byte) (err error) {
+	var fault struct {
+		FaultCode string `xml:"Body>Fault>faultcode"`
+	}
+	xml.Unmarshal(body, &fault)
+	if fault.FaultCode != "" || statusCode/100 != 2 {
+		return newAPIError(statusCode, body)
 	}
 	return
 }
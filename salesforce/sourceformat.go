@@ -0,0 +1,329 @@
+package salesforce
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The Salesforce DX source format differs from the Metadata API format in a
+// few ways:
+//
+//   - every file sits somewhere below a package directory such as
+//     `force-app/main/default`, rather than at the root of the package.
+//   - types without a separate content file (layouts, profiles, ...) are
+//     stored as `<Name>.<suffix>-meta.xml` rather than `<Name>.<suffix>`.
+//   - folders are described by `<Folder>.<type>Folder-meta.xml`.
+//   - static resources are stored unzipped, and documents have a
+//     `.document-meta.xml` rather than one named after their content file.
+//   - objects are decomposed, in the same layout as DecomposeMetadata.
+//
+// ConvertSourceToMetadata takes care of all of these.
+
+// SourceProjectFile is the file marking the root of a source format project.
+const SourceProjectFile = "sfdx-project.json"
+
+// isSourceToolingFile reports whether a file in a source format project is
+// only used by local tooling, and so never deployed.
+func isSourceToolingFile(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__tests__" {
+			return true
+		}
+	}
+	return path.Base(name) == "jsconfig.json"
+}
+
+// relocateSourceFile strips the package directory prefix from a file in the
+// source format, leaving its path relative to the metadata type directory's
+// parent, e.g. `main/default/classes/Foo.cls` becomes `classes/Foo.cls`.
+func relocateSourceFile(name string) (relocated string, ok bool) {
+	parts := strings.Split(filepath.ToSlash(name), "/")
+	for i, part := range parts[:len(parts)-1] {
+		if _, known := metapathForDir(part); known {
+			return strings.Join(parts[i:], "/"), true
+		}
+	}
+	return
+}
+
+// ConvertSourceToMetadata converts a set of files in the source format, keyed
+// by path relative to a package directory, into the Metadata API format.  A
+// package.xml listing every component is generated unless one is present.
+func ConvertSourceToMetadata(files ForceMetadataFiles, apiVersion string) (converted ForceMetadataFiles, err error) {
+	converted, err = convertSourceFiles(files)
+	if err != nil {
+		return
+	}
+	if _, present := converted["package.xml"]; !present {
+		pb := NewFetchBuilder(apiVersion)
+		for name := range converted {
+			pb.addPackageMember(name)
+		}
+		converted["package.xml"] = pb.PackageXml()
+	}
+	return
+}
+
+func convertSourceFiles(files ForceMetadataFiles) (converted ForceMetadataFiles, err error) {
+	relocated := make(ForceMetadataFiles)
+	for name, data := range files {
+		name = filepath.ToSlash(name)
+		if name == "package.xml" || strings.HasPrefix(name, "destructiveChanges") {
+			relocated[name] = data
+			continue
+		}
+		if isSourceToolingFile(name) {
+			continue
+		}
+		if name, ok := relocateSourceFile(name); ok {
+			relocated[name] = data
+		}
+	}
+
+	recomposed, err := RecomposeMetadata(relocated)
+	if err != nil {
+		return
+	}
+
+	converted = make(ForceMetadataFiles)
+	resources := make(map[string]ForceMetadataFiles)
+	for name, data := range recomposed {
+		parts := strings.Split(name, "/")
+		mp, _ := metapathForDir(parts[0])
+		switch {
+		case mp.name == "StaticResource" && len(parts) > 1:
+			// gather up the meta file and the content of each static
+			// resource, to be put back together below.
+			resource := strings.SplitN(parts[1], ".", 2)[0]
+			if resources[resource] == nil {
+				resources[resource] = make(ForceMetadataFiles)
+			}
+			resources[resource][strings.Join(parts[1:], "/")] = data
+		case mp.name == "Document" && strings.HasSuffix(name, ".document-meta.xml"):
+			documentName := strings.TrimSuffix(name, ".document-meta.xml")
+			renamed := false
+			for other := range recomposed {
+				if other != name && strings.HasPrefix(other, documentName+".") && !strings.HasSuffix(other, "-meta.xml") {
+					converted[other+"-meta.xml"] = data
+					renamed = true
+				}
+			}
+			if !renamed {
+				err = fmt.Errorf("%s has no matching document", name)
+				return
+			}
+		case mp.hasFolder && len(parts) == 2 && strings.HasSuffix(name, "Folder-meta.xml"):
+			folder := strings.SplitN(parts[1], ".", 2)[0]
+			converted[path.Join(parts[0], folder+"-meta.xml")] = data
+		case strings.HasSuffix(name, "-meta.xml") && !mp.onlyFolder:
+			if _, hasContent := recomposed[strings.TrimSuffix(name, "-meta.xml")]; hasContent {
+				converted[name] = data
+			} else {
+				converted[strings.TrimSuffix(name, "-meta.xml")] = data
+			}
+		default:
+			converted[name] = data
+		}
+	}
+
+	for resource, resourceFiles := range resources {
+		var data []byte
+		if data, err = zipStaticResource(resource, resourceFiles); err != nil {
+			return
+		}
+		converted[path.Join("staticresources", resource+".resource")] = data
+		if meta, present := resourceFiles[resource+".resource-meta.xml"]; present {
+			converted[path.Join("staticresources", resource+".resource-meta.xml")] = meta
+		}
+	}
+	return
+}
+
+// zipStaticResource builds the `.resource` file for a static resource in the
+// source format, which is either stored as a single file with its original
+// extension, or unzipped into a directory of the same name.
+func zipStaticResource(resource string, files ForceMetadataFiles) (data []byte, err error) {
+	var bundled []string
+	for name, content := range files {
+		switch {
+		case name == resource+".resource-meta.xml":
+		case strings.HasPrefix(name, resource+"/"):
+			bundled = append(bundled, name)
+		default:
+			if data != nil {
+				err = fmt.Errorf("static resource %s has more than one content file", resource)
+				return
+			}
+			data = content
+		}
+	}
+	if len(bundled) == 0 {
+		if data == nil {
+			err = fmt.Errorf("static resource %s has no content", resource)
+		}
+		return
+	}
+	if data != nil {
+		err = fmt.Errorf("static resource %s has both a content file and a directory", resource)
+		return
+	}
+
+	sort.Strings(bundled)
+	zipfile := new(bytes.Buffer)
+	zipper := zip.NewWriter(zipfile)
+	for _, name := range bundled {
+		fl, cerr := zipper.Create(strings.TrimPrefix(name, resource+"/"))
+		if cerr != nil {
+			return nil, cerr
+		}
+		if _, err = fl.Write(files[name]); err != nil {
+			return
+		}
+	}
+	if err = zipper.Close(); err != nil {
+		return
+	}
+	data = zipfile.Bytes()
+	return
+}
+
+// addPackageMember adds the component a file in the Metadata API format
+// belongs to, keyed by path relative to the package root, to the package.xml.
+func (pb *PackageBuilder) addPackageMember(name string) {
+	if metaName, member, ok := packageMemberForFile(name); ok {
+		pb.AddMetaToPackage(metaName, member)
+	}
+}
+
+// packageMemberForFile returns the package.xml type and member a file in the
+// Metadata API format, keyed by path relative to the package root, belongs
+// to.
+func packageMemberForFile(name string) (metaName string, member string, ok bool) {
+	name = filepath.ToSlash(name)
+	if !strings.Contains(name, "/") || strings.HasPrefix(name, "destructiveChanges") {
+		return
+	}
+	parts := strings.Split(name, "/")
+	if strings.HasSuffix(name, "-meta.xml") {
+		// folders only have a meta file, named after the folder itself.
+		if mp, known := metapathForDir(parts[0]); known && mp.hasFolder && !mp.onlyFolder && len(parts) == 2 {
+			return mp.name, strings.TrimSuffix(parts[1], "-meta.xml"), true
+		}
+		name = strings.TrimSuffix(name, "-meta.xml")
+	}
+	metaName, objectName := getMetaForPath(name)
+	return metaName, memberName(metaName, objectName), true
+}
+
+// sourceProjectRoot finds the source format project a file belongs to, if
+// any.
+func sourceProjectRoot(fpath string) (root string, ok bool) {
+	for dir := filepath.Dir(fpath); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, err := os.Stat(filepath.Join(dir, SourceProjectFile)); err == nil {
+			return dir, true
+		}
+	}
+	return
+}
+
+// sourceComponentFiles finds all of the files making up the component a file
+// in the source format belongs to, keyed by path relative to the metadata
+// type directory's parent.
+func sourceComponentFiles(fpath string) (name string, files ForceMetadataFiles, err error) {
+	typeDir := ""
+	var mp metapath
+	for dir := filepath.Dir(fpath); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		var ok bool
+		if mp, ok = metapathForDir(filepath.Base(dir)); ok {
+			typeDir = dir
+			break
+		}
+	}
+	if typeDir == "" {
+		err = fmt.Errorf("%s is not in a known metadata directory", fpath)
+		return
+	}
+
+	rel, _ := filepath.Rel(typeDir, fpath)
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	var paths []string
+	componentDir := filepath.Join(typeDir, parts[0])
+	_, _, isDecomposed := decomposedComponentForPath(mp.path + "/" + parts[0] + "/")
+	info, serr := os.Stat(componentDir)
+	switch {
+	case serr == nil && info.IsDir() && (mp.onlyFolder || isDecomposed):
+		// bundles and decomposed objects are deployed as a whole.
+		name = parts[0]
+		paths = append(paths, componentDir)
+	case mp.hasFolder && len(parts) == 1 && strings.HasSuffix(fpath, "Folder-meta.xml"):
+		name = strings.SplitN(parts[0], ".", 2)[0]
+		paths = append(paths, fpath)
+	default:
+		// everything else is made up of the files named after the
+		// component, along with the unzipped content of static resources.
+		dir := filepath.Dir(fpath)
+		name = strings.TrimSuffix(filepath.Base(fpath), "-meta.xml")
+		name = strings.TrimSuffix(name, filepath.Ext(name))
+		if mp.name == "StaticResource" {
+			dir = typeDir
+			name = strings.SplitN(parts[0], ".", 2)[0]
+		}
+		var entries []os.FileInfo
+		if entries, err = ioutil.ReadDir(dir); err != nil {
+			return
+		}
+		for _, entry := range entries {
+			if entry.Name() == name || strings.HasPrefix(entry.Name(), name+".") {
+				paths = append(paths, filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	files = make(ForceMetadataFiles)
+	srcDir := filepath.Dir(typeDir)
+	for _, p := range paths {
+		err = filepath.Walk(p, func(fpath string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			frel, _ := filepath.Rel(srcDir, fpath)
+			data, err := ioutil.ReadFile(fpath)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(frel)] = data
+			return nil
+		})
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// addSourceFile adds the component a file in a source format project belongs
+// to, converted to the Metadata API format.
+func (pb *PackageBuilder) addSourceFile(fpath string) (fname string, err error) {
+	fname, files, err := sourceComponentFiles(fpath)
+	if err != nil {
+		return
+	}
+	converted, err := convertSourceFiles(files)
+	if err != nil {
+		return
+	}
+	for name, data := range converted {
+		pb.addPackageMember(name)
+		if pb.IsPush {
+			pb.Files[name] = data
+		}
+	}
+	return
+}
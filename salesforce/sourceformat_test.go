@@ -0,0 +1,176 @@
+package salesforce_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/joist-engineering/force/salesforce"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Sourceformat", func() {
+	Describe("ConvertSourceToMetadata", func() {
+		var (
+			files     salesforce.ForceMetadataFiles
+			converted salesforce.ForceMetadataFiles
+			pkg       salesforce.Package
+		)
+
+		BeforeEach(func() {
+			files = salesforce.ForceMetadataFiles{
+				"main/default/classes/Foo.cls":                                 []byte("public class Foo {}"),
+				"main/default/classes/Foo.cls-meta.xml":                        []byte("<ApexClass/>"),
+				"main/default/layouts/Account-Account Layout.layout-meta.xml":  []byte("<Layout/>"),
+				"main/default/customMetadata/Region.West.md-meta.xml":          []byte("<CustomMetadata/>"),
+				"main/default/reports/Sales.reportFolder-meta.xml":             []byte("<ReportFolder/>"),
+				"main/default/reports/Sales/Pipeline.report-meta.xml":          []byte("<Report/>"),
+				"main/default/lwc/hello/hello.js":                              []byte("export default class Hello {}"),
+				"main/default/lwc/hello/hello.js-meta.xml":                     []byte("<LightningComponentBundle/>"),
+				"main/default/lwc/hello/__tests__/hello.test.js":               []byte("test()"),
+				"main/default/lwc/jsconfig.json":                               []byte("{}"),
+				"main/default/staticresources/app.resource-meta.xml":           []byte("<StaticResource/>"),
+				"main/default/staticresources/app/js/app.js":                   []byte("app()"),
+				"main/default/staticresources/logo.png":                        []byte("PNG"),
+				"main/default/staticresources/logo.resource-meta.xml":          []byte("<StaticResource/>"),
+				"main/default/documents/Shared.documentFolder-meta.xml":        []byte("<DocumentFolder/>"),
+				"main/default/documents/Shared/terms.pdf":                      []byte("PDF"),
+				"main/default/documents/Shared/terms.document-meta.xml":        []byte("<Document/>"),
+				"main/default/objects/Account/Account.object-meta.xml":         []byte(accountResidual),
+				"main/default/objects/Account/fields/Region__c.field-meta.xml": []byte(regionField),
+				"README.md": []byte("# Hi"),
+			}
+			var err error
+			converted, err = salesforce.ConvertSourceToMetadata(files, "v45.0")
+			Expect(err).ToNot(HaveOccurred())
+			pkg = salesforce.Package{}
+			Expect(xml.Unmarshal(converted["package.xml"], &pkg)).To(Succeed())
+		})
+
+		members := func(typeName string) []string {
+			for _, metaType := range pkg.Types {
+				if metaType.Name == typeName {
+					return metaType.Members
+				}
+			}
+			return nil
+		}
+
+		It("should strip the package directory", func() {
+			Expect(converted).To(HaveKey("classes/Foo.cls"))
+			Expect(converted).To(HaveKey("classes/Foo.cls-meta.xml"))
+			Expect(members("ApexClass")).To(Equal([]string{"Foo"}))
+		})
+
+		It("should drop files not deployed to Salesforce", func() {
+			Expect(converted).ToNot(HaveKey("README.md"))
+			Expect(converted).ToNot(HaveKey("lwc/hello/__tests__/hello.test.js"))
+			Expect(converted).ToNot(HaveKey("lwc/jsconfig.json"))
+		})
+
+		It("should rename files that only have metadata", func() {
+			Expect(converted).To(HaveKey("layouts/Account-Account Layout.layout"))
+			Expect(members("Layout")).To(Equal([]string{"Account-Account Layout"}))
+			Expect(converted).To(HaveKey("customMetadata/Region.West.md"))
+			Expect(members("CustomMetadata")).To(Equal([]string{"Region.West"}))
+		})
+
+		It("should rename folders", func() {
+			Expect(converted).To(HaveKey("reports/Sales-meta.xml"))
+			Expect(converted).To(HaveKey("reports/Sales/Pipeline.report"))
+			Expect(members("Report")).To(Equal([]string{"Sales", "Sales/Pipeline"}))
+		})
+
+		It("should keep lightning web component bundles together", func() {
+			Expect(converted).To(HaveKey("lwc/hello/hello.js"))
+			Expect(converted).To(HaveKey("lwc/hello/hello.js-meta.xml"))
+			Expect(members("LightningComponentBundle")).To(Equal([]string{"hello"}))
+		})
+
+		It("should zip up unpacked static resources", func() {
+			Expect(converted["staticresources/app.resource-meta.xml"]).To(Equal([]byte("<StaticResource/>")))
+			reader, err := zip.NewReader(bytes.NewReader(converted["staticresources/app.resource"]), int64(len(converted["staticresources/app.resource"])))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reader.File).To(HaveLen(1))
+			Expect(reader.File[0].Name).To(Equal("js/app.js"))
+			Expect(converted["staticresources/logo.resource"]).To(Equal([]byte("PNG")))
+			Expect(members("StaticResource")).To(Equal([]string{"app", "logo"}))
+		})
+
+		It("should name document metadata after the document", func() {
+			Expect(converted).To(HaveKey("documents/Shared-meta.xml"))
+			Expect(converted).To(HaveKey("documents/Shared/terms.pdf"))
+			Expect(converted).To(HaveKey("documents/Shared/terms.pdf-meta.xml"))
+			Expect(converted).ToNot(HaveKey("documents/Shared/terms.document-meta.xml"))
+		})
+
+		It("should recompose decomposed objects", func() {
+			Expect(converted).To(HaveKey("objects/Account.object"))
+			Expect(string(converted["objects/Account.object"])).To(ContainSubstring("<fullName>Region__c</fullName>"))
+			Expect(members("CustomObject")).To(Equal([]string{"Account"}))
+		})
+
+		It("should use the given API version", func() {
+			Expect(pkg.Version).To(Equal("45.0"))
+		})
+	})
+
+	Describe("PackageBuilder.AddFile", func() {
+		var (
+			pb      salesforce.PackageBuilder
+			tempDir string
+			srcDir  string
+		)
+
+		BeforeEach(func() {
+			pb = salesforce.NewPushBuilder("v45.0")
+			tempDir, _ = ioutil.TempDir("", "sourceformat-test")
+			srcDir = filepath.Join(tempDir, "force-app", "main", "default")
+			ioutil.WriteFile(filepath.Join(tempDir, "sfdx-project.json"), []byte(`{"packageDirectories": [{"path": "force-app"}]}`), 0644)
+			os.MkdirAll(filepath.Join(srcDir, "layouts"), 0755)
+			os.MkdirAll(filepath.Join(srcDir, "lwc", "hello"), 0755)
+			ioutil.WriteFile(filepath.Join(srcDir, "layouts", "Account-Account Layout.layout-meta.xml"), []byte("<Layout/>"), 0644)
+			ioutil.WriteFile(filepath.Join(srcDir, "lwc", "hello", "hello.js"), []byte("export default class Hello {}"), 0644)
+			ioutil.WriteFile(filepath.Join(srcDir, "lwc", "hello", "hello.html"), []byte("<template></template>"), 0644)
+			ioutil.WriteFile(filepath.Join(srcDir, "lwc", "hello", "hello.js-meta.xml"), []byte("<LightningComponentBundle/>"), 0644)
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(tempDir)
+		})
+
+		It("should convert files that only have metadata", func() {
+			_, err := pb.AddFile(filepath.Join(srcDir, "layouts", "Account-Account Layout.layout-meta.xml"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pb.Files).To(HaveKey("layouts/Account-Account Layout.layout"))
+			Expect(pb.Metadata["Layout"].Members).To(Equal([]string{"Account-Account Layout"}))
+		})
+
+		It("should push the whole bundle of a lightning web component", func() {
+			_, err := pb.AddFile(filepath.Join(srcDir, "lwc", "hello", "hello.html"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pb.Files).To(HaveKey("lwc/hello/hello.js"))
+			Expect(pb.Files).To(HaveKey("lwc/hello/hello.html"))
+			Expect(pb.Files).To(HaveKey("lwc/hello/hello.js-meta.xml"))
+			Expect(pb.Metadata["LightningComponentBundle"].Members).To(Equal([]string{"hello"}))
+		})
+	})
+})
+
+const accountResidual = `<?xml version="1.0" encoding="UTF-8"?>
+<CustomObject xmlns="http://soap.sforce.com/2006/04/metadata">
+    <sharingModel>ReadWrite</sharingModel>
+</CustomObject>
+`
+
+const regionField = `<?xml version="1.0" encoding="UTF-8"?>
+<CustomField xmlns="http://soap.sforce.com/2006/04/metadata">
+    <fullName>Region__c</fullName>
+    <type>Text</type>
+</CustomField>
+`
@@ -266,7 +266,7 @@ func runSecurity(cmd *Command, args []string) {
 	// Step 1: retrieve the desired metadata
 	files, err := force.Metadata.Retrieve(query, salesforce.ForceRetrieveOptions{})
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	// Step 2: go through the metadata and construct a list of Profile (profiles) and a CustomObject (theObject)
@@ -360,7 +360,7 @@ func runSecurity(cmd *Command, args []string) {
 
 	// Last step: write the file on disk and display it inside a Web browser
 	if err := ioutil.WriteFile(filepath.Join(root, "security.html"), []byte(HTMLoutput), 0644); err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	util.Open(filepath.Join(root, "security.html"))
@@ -88,7 +88,7 @@ func runSobjectCreate(args []string) {
 	}
 	force, _ := ActiveForce()
 	if err := force.Metadata.CreateCustomObject(args[0]); err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Println("Custom object created")
 
@@ -104,7 +104,7 @@ func runSobjectDelete(args []string) {
 	}
 	force, _ := ActiveForce()
 	if err := force.Metadata.DeleteCustomObject(args[0]); err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Println("Custom object deleted")
 }
@@ -121,7 +121,7 @@ func runSobjectImport(args []string) {
 	var query salesforce.ForceQueryResult
 	json.Unmarshal(data, &query)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 
 	var soapMsg = ""
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// soqlField is a column of a SELECT: a field, a relationship field such as
+// Account.Name, an aggregate, or a subquery.
+type soqlField struct {
+	// Name is the key of the column in the query's records, such as
+	// Account.Name, an aggregate's alias or expr0, or a subquery's
+	// relationship name.
+	Name string
+	// Children are the fields a subquery selects.
+	Children []soqlField
+	// Aggregate is set for aggregate functions, such as COUNT(Id).
+	Aggregate bool
+}
+
+// fieldFunctions are the SOQL functions whose column is named after the field
+// they are given, rather than after an alias or exprN.
+var fieldFunctions = map[string]bool{
+	"tolabel":         true,
+	"format":          true,
+	"convertcurrency": true,
+}
+
+// parseSoqlFields returns the fields a query selects, in order.  It returns
+// nil if it can't tell, such as for FIELDS(ALL) or TYPEOF.
+func parseSoqlFields(soql string) (fields []soqlField) {
+	selectList, from := splitSelect(soql)
+	if selectList == "" || from == "" {
+		return nil
+	}
+	expressions := 0
+	for _, item := range splitTopLevel(selectList, ',') {
+		item = strings.TrimSpace(item)
+		words := strings.Fields(item)
+		switch {
+		case len(words) == 0 || strings.EqualFold(words[0], "TYPEOF"):
+			return nil
+		case strings.HasPrefix(item, "("):
+			inner := strings.TrimSuffix(strings.TrimPrefix(item, "("), ")")
+			children := parseSoqlFields(inner)
+			_, relationship := splitSelect(inner)
+			if children == nil || relationship == "" {
+				return nil
+			}
+			fields = append(fields, soqlField{Name: relationship, Children: children})
+		case strings.Contains(item, "("):
+			open, close := strings.Index(item, "("), strings.LastIndex(item, ")")
+			if close < open {
+				return nil
+			}
+			function := strings.ToLower(strings.TrimSpace(item[:open]))
+			argument := strings.TrimSpace(item[open+1 : close])
+			alias := strings.TrimSpace(item[close+1:])
+			switch {
+			case function == "fields":
+				return nil
+			case fieldFunctions[function] && alias == "":
+				fields = append(fields, soqlField{Name: argument})
+			case fieldFunctions[function]:
+				fields = append(fields, soqlField{Name: alias})
+			case alias != "":
+				fields = append(fields, soqlField{Name: alias, Aggregate: true})
+			default:
+				fields = append(fields, soqlField{Name: fmt.Sprintf("expr%d", expressions), Aggregate: true})
+				expressions++
+			}
+		default:
+			fields = append(fields, soqlField{Name: words[len(words)-1]})
+		}
+	}
+	return
+}
+
+// splitSelect splits a query into its select list and the object it is FROM.
+func splitSelect(soql string) (selectList, from string) {
+	soql = strings.TrimSpace(soql)
+	if len(soql) < 6 || !strings.EqualFold(soql[:6], "SELECT") {
+		return
+	}
+	i := findSoqlKeyword(soql, 6, "FROM")
+	if i < 0 {
+		return
+	}
+	selectList = soql[6:i]
+	if rest := strings.Fields(soql[i+4:]); len(rest) > 0 {
+		from = rest[0]
+	}
+	return
+}
+
+// findSoqlKeyword returns where a keyword is in soql, after start and outside
+// of parentheses and quotes, or -1 if it isn't.
+func findSoqlKeyword(soql string, start int, keyword string) int {
+	depth := 0
+	quoted := false
+	for i := start; i < len(soql); i++ {
+		switch c := soql[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && isSoqlKeyword(soql, i, keyword):
+			return i
+		}
+	}
+	return -1
+}
+
+// isSoqlKeyword reports whether the keyword is at i in soql as a word of its
+// own.
+func isSoqlKeyword(soql string, i int, keyword string) bool {
+	end := i + len(keyword)
+	if end > len(soql) || !strings.EqualFold(soql[i:end], keyword) {
+		return false
+	}
+	before := i == 0 || unicode.IsSpace(rune(soql[i-1])) || soql[i-1] == ')'
+	after := end == len(soql) || unicode.IsSpace(rune(soql[end])) || soql[end] == '('
+	return before && after
+}
+
+// splitTopLevel splits s at each sep that isn't in parentheses or quotes.
+func splitTopLevel(s string, sep byte) (parts []string) {
+	depth := 0
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == sep && depth == 0:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
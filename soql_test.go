@@ -0,0 +1,75 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bmizerany/assert"
+)
+
+func TestParseSoqlFields(t *testing.T) {
+	fields := parseSoqlFields("select Id, Name, Account.Name, toLabel(StageName), (SELECT LastName, Email FROM Contacts WHERE Email != null) FROM Opportunity WHERE Name = 'a, b FROM c'")
+	assert.Equal(t, []soqlField{
+		{Name: "Id"},
+		{Name: "Name"},
+		{Name: "Account.Name"},
+		{Name: "StageName"},
+		{Name: "Contacts", Children: []soqlField{{Name: "LastName"}, {Name: "Email"}}},
+	}, fields)
+
+	fields = parseSoqlFields("SELECT LeadSource, COUNT(Id) total, MAX(Amount), MIN(Amount) FROM Opportunity GROUP BY LeadSource")
+	assert.Equal(t, []soqlField{
+		{Name: "LeadSource"},
+		{Name: "total", Aggregate: true},
+		{Name: "expr0", Aggregate: true},
+		{Name: "expr1", Aggregate: true},
+	}, fields)
+
+	assert.Equal(t, []soqlField(nil), parseSoqlFields("SELECT FIELDS(STANDARD) FROM Account"))
+	assert.Equal(t, []soqlField(nil), parseSoqlFields("SELECT TYPEOF What WHEN Account THEN Phone END FROM Event"))
+	assert.Equal(t, []soqlField(nil), parseSoqlFields("not a query"))
+}
+
+func TestBindSoqlParams(t *testing.T) {
+	params := soqlParams{
+		"name":           `O'Brien \ Sons`,
+		"start":          "2026-01-01",
+		"since":          "2026-01-01T09:00:00Z",
+		"ids":            "001A, 001B",
+		"min:number":     "1000",
+		"active:boolean": "TRUE",
+		"code:string":    "2026-01-01",
+	}
+	bind := func(soql string) string {
+		bound, err := bindSoqlParams(soql, params)
+		assert.Equal(t, nil, err)
+		return bound
+	}
+
+	assert.Equal(t, `SELECT Id FROM Account WHERE Name = 'O\'Brien \\ Sons'`, bind("SELECT Id FROM Account WHERE Name = :name"))
+	assert.Equal(t, "SELECT Id FROM Opportunity WHERE CloseDate >= 2026-01-01 AND CreatedDate > 2026-01-01T09:00:00Z",
+		bind("SELECT Id FROM Opportunity WHERE CloseDate >= :start AND CreatedDate > :since"))
+	assert.Equal(t, "SELECT Id FROM Account WHERE Id IN ('001A', '001B') OR ParentId NOT IN ('001A', '001B')",
+		bind("SELECT Id FROM Account WHERE Id IN :ids OR ParentId NOT IN (:ids)"))
+	assert.Equal(t, "SELECT Id FROM Opportunity WHERE Amount > 1000 AND IsWon = true AND Code__c = '2026-01-01'",
+		bind("SELECT Id FROM Opportunity WHERE Amount > :min AND IsWon = :active AND Code__c = :code"))
+
+	// Colons that aren't bindings are left alone.
+	assert.Equal(t, "SELECT Id FROM Task WHERE Subject = 'Call :name' AND CreatedDate = LAST_N_DAYS:30",
+		bind("SELECT Id FROM Task WHERE Subject = 'Call :name' AND CreatedDate = LAST_N_DAYS:30"))
+
+	_, err := bindSoqlParams("SELECT Id FROM Account WHERE OwnerId = :ownerId", params)
+	assert.Equal(t, "no value for :ownerId, pass -p ownerId=<value>", err.Error())
+	_, err = bindSoqlParams("SELECT Id FROM Account WHERE CreatedDate > :start", soqlParams{"start:date": "yesterday"})
+	assert.Equal(t, "parameter start is not a date, such as 2026-01-01: yesterday", err.Error())
+	_, err = bindSoqlParams("SELECT Id FROM Account", soqlParams{"start:day": "1"})
+	assert.NotEqual(t, nil, err)
+}
+
+func TestSoqlParamsOverride(t *testing.T) {
+	params := soqlParams{"ownerId": "005A", "start:date": "2026-01-01"}
+	var flags soqlParams
+	assert.Equal(t, nil, flags.Set("start=2026-02-01"))
+	assert.NotEqual(t, nil, flags.Set("start"))
+	params.Override(flags)
+	assert.Equal(t, soqlParams{"ownerId": "005A", "start": "2026-02-01"}, params)
+}
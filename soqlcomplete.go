@@ -0,0 +1,211 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// soqlKeywords are completed along with field names.
+var soqlKeywords = []string{
+	"SELECT", "FROM", "WHERE", "AND", "OR", "NOT", "IN", "LIKE", "INCLUDES",
+	"EXCLUDES", "NULL", "TRUE", "FALSE", "GROUP", "BY", "ROLLUP", "CUBE",
+	"HAVING", "ORDER", "ASC", "DESC", "NULLS", "FIRST", "LAST", "LIMIT",
+	"OFFSET", "WITH", "FOR", "VIEW", "REFERENCE", "UPDATE", "TYPEOF", "WHEN",
+	"THEN", "ELSE", "END", "USING", "SCOPE", "FIELDS", "COUNT",
+	"COUNT_DISTINCT", "SUM", "AVG", "MIN", "MAX", "TOLABEL", "FORMAT",
+	"CONVERTCURRENCY",
+}
+
+// soqlCompleter completes the names of sObjects, fields and relationships
+// from an org's describes, as well as SOQL keywords and the shell's
+// commands.  It works on plain strings, so it doesn't need a terminal.
+type soqlCompleter struct {
+	describes *describeCache
+	// statement is what has been entered of a statement on earlier lines.
+	statement string
+}
+
+// Complete completes the word before pos, in runes, in line, as a
+// liner.WordCompleter.  It returns the line around the word and the words it
+// could be.
+func (c *soqlCompleter) Complete(line string, pos int) (head string, completions []string, tail string) {
+	runes := []rune(line)
+	if pos > len(runes) {
+		pos = len(runes)
+	}
+	before, tail := string(runes[:pos]), string(runes[pos:])
+	start := strings.LastIndexFunc(before, func(r rune) bool { return !isSoqlNameRune(r) }) + 1
+	head, word := before[:start], before[start:]
+
+	var candidates []string
+	if strings.TrimSpace(c.statement) == "" && strings.HasPrefix(strings.TrimSpace(before), ":") {
+		candidates = c.commandCandidates(before)
+		if words := strings.Fields(before); len(words) == 1 && !strings.HasSuffix(before, " ") {
+			head, word = before[:strings.Index(before, ":")], words[0]
+		}
+	} else {
+		statement := c.statement + "\n" + head
+		candidates = c.soqlCandidates(statement, c.statement+"\n"+line, word)
+	}
+
+	for _, candidate := range candidates {
+		if len(candidate) >= len(word) && strings.EqualFold(candidate[:len(word)], word) {
+			completions = append(completions, matchCase(candidate, word))
+		}
+	}
+	sort.Strings(completions)
+	return
+}
+
+func isSoqlNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+}
+
+// matchCase lower cases keywords being typed in lower case.
+func matchCase(candidate, word string) string {
+	if word != "" && word == strings.ToLower(word) && candidate == strings.ToUpper(candidate) {
+		return strings.ToLower(candidate)
+	}
+	return candidate
+}
+
+func (c *soqlCompleter) commandCandidates(before string) []string {
+	words := strings.Fields(before)
+	if len(words) == 1 && !strings.HasSuffix(before, " ") {
+		var names []string
+		for _, command := range soqlShellCommands {
+			names = append(names, command.name)
+		}
+		return names
+	}
+	switch words[0] {
+	case ":format":
+		return recordFormats
+	case ":tooling", ":all":
+		return []string{"on", "off"}
+	}
+	return nil
+}
+
+// soqlCandidates returns what the word at the end of statement could be, given
+// the whole of the statement entered so far.
+func (c *soqlCompleter) soqlCandidates(statement, full, word string) (candidates []string) {
+	words := strings.Fields(statement)
+	afterFrom := len(words) > 0 && strings.EqualFold(words[len(words)-1], "FROM")
+	sobject, outer := c.scopeSobjects(full, len(statement))
+
+	if afterFrom {
+		candidates, _ = c.describes.SobjectNames()
+		if outer != "" {
+			if describe, err := c.describes.Describe(outer); err == nil {
+				for _, child := range describe.ChildRelationships {
+					if child.RelationshipName != "" {
+						candidates = append(candidates, child.RelationshipName)
+					}
+				}
+			}
+		}
+		return
+	}
+	if sobject == "" {
+		return soqlKeywords
+	}
+
+	// Follow the relationships before the last dot, as in Owner.Manager.Na
+	path := strings.Split(word, ".")
+	prefix := ""
+	for _, relationship := range path[:len(path)-1] {
+		describe, err := c.describes.Describe(sobject)
+		if err != nil {
+			return
+		}
+		field, ok := describe.Relationship(relationship)
+		if !ok || len(field.ReferenceTo) == 0 {
+			return
+		}
+		sobject = field.ReferenceTo[0]
+		prefix += relationship + "."
+	}
+	describe, err := c.describes.Describe(sobject)
+	if err != nil {
+		return
+	}
+	for _, field := range describe.Fields {
+		candidates = append(candidates, prefix+field.Name)
+		if field.RelationshipName != "" {
+			candidates = append(candidates, prefix+field.RelationshipName)
+		}
+	}
+	if prefix == "" {
+		candidates = append(candidates, soqlKeywords...)
+	}
+	return
+}
+
+// scopeSobjects returns the sObject that the part of a statement at pos
+// selects from, and for a subquery, the sObject of the query it is in.
+func (c *soqlCompleter) scopeSobjects(statement string, pos int) (sobject, outer string) {
+	open, close := soqlScope(statement, pos)
+	scope := statement[open+1 : close]
+	from := findSoqlKeyword(scope, 0, "FROM")
+	if open >= 0 {
+		outer, _ = c.scopeSobjects(statement, open)
+		if from < 0 {
+			// Parentheses that aren't a subquery, as in COUNT(Id)
+			return outer, ""
+		}
+	}
+	if from < 0 {
+		return "", outer
+	}
+	name := ""
+	if words := strings.Fields(strings.TrimRight(scope[from+4:], "; \t\n")); len(words) > 0 {
+		name = words[0]
+	}
+	if outer != "" {
+		if describe, err := c.describes.Describe(outer); err == nil {
+			if child, ok := describe.ChildRelationship(name); ok {
+				return child.ChildSObject, outer
+			}
+		}
+	}
+	return name, outer
+}
+
+// soqlScope returns where the parentheses around pos in a statement are, or
+// -1 and the end of the statement if pos isn't in any.
+func soqlScope(statement string, pos int) (open, close int) {
+	var opened []int
+	quoted := false
+	for i := 0; i < pos && i < len(statement); i++ {
+		switch c := statement[i]; {
+		case c == '\\' && quoted:
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case quoted:
+		case c == '(':
+			opened = append(opened, i)
+		case c == ')' && len(opened) > 0:
+			opened = opened[:len(opened)-1]
+		}
+	}
+	if len(opened) == 0 {
+		return -1, len(statement)
+	}
+	open = opened[len(opened)-1]
+	depth := 0
+	for close = pos; close < len(statement); close++ {
+		switch statement[close] {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+	}
+	return
+}
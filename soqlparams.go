@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// soqlParams are the values for the :name bindings of a query, given as
+// name=value, or name:type=value to say what kind of literal the value is.
+type soqlParams map[string]string
+
+func (p *soqlParams) String() string {
+	var params []string
+	for name, value := range *p {
+		params = append(params, name+"="+value)
+	}
+	sort.Strings(params)
+	return strings.Join(params, " ")
+}
+
+func (p *soqlParams) Set(param string) error {
+	i := strings.Index(param, "=")
+	if i <= 0 {
+		return fmt.Errorf("parameters must be name=value, not %s", param)
+	}
+	if *p == nil {
+		*p = make(soqlParams)
+	}
+	(*p)[param[:i]] = param[i+1:]
+	return nil
+}
+
+// Override sets the parameters of other, replacing any of the same name,
+// whatever their type.
+func (p soqlParams) Override(other soqlParams) {
+	for key, value := range other {
+		name, _ := splitSoqlParam(key)
+		for existing := range p {
+			if existingName, _ := splitSoqlParam(existing); existingName == name {
+				delete(p, existing)
+			}
+		}
+		p[key] = value
+	}
+}
+
+// splitSoqlParam splits a parameter's name from its type, if it has one.
+func splitSoqlParam(key string) (name, kind string) {
+	if i := strings.Index(key, ":"); i >= 0 {
+		return key[:i], key[i+1:]
+	}
+	return key, ""
+}
+
+// soqlParamTypes are the types that can be given for a parameter.
+var soqlParamTypes = []string{"string", "date", "datetime", "number", "boolean"}
+
+// soqlLiteralEscaper escapes the characters SOQL needs escaped in strings.
+var soqlLiteralEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	`'`, `\'`,
+	`"`, `\"`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\t", `\t`,
+	"\b", `\b`,
+	"\f", `\f`,
+)
+
+// bindSoqlParams replaces the :name bindings in a query with the values of
+// params, as SOQL literals.  Values that are dates, such as 2026-01-01, or
+// datetimes, such as 2026-01-01T09:00:00Z, are written as such, and anything
+// else as a string, unless a type is given with name:type.  After IN,
+// INCLUDES or EXCLUDES, a value is a comma separated list.
+func bindSoqlParams(soql string, params soqlParams) (bound string, err error) {
+	values := make(map[string]string)
+	types := make(map[string]string)
+	for key, value := range params {
+		name, kind := splitSoqlParam(key)
+		if kind != "" && !StringSliceContains(soqlParamTypes, kind) {
+			return "", fmt.Errorf("unknown type %s for parameter %s, use one of %s", kind, name, strings.Join(soqlParamTypes, ", "))
+		}
+		values[name], types[name] = value, kind
+	}
+
+	var out strings.Builder
+	quoted := false
+	for i := 0; i < len(soql); i++ {
+		c := soql[i]
+		switch {
+		case c == '\\' && quoted && i+1 < len(soql):
+			out.WriteByte(c)
+			i++
+			c = soql[i]
+		case c == '\'':
+			quoted = !quoted
+		case c == ':' && !quoted && isSoqlBinding(soql, i):
+			end := i + 1
+			for end < len(soql) && isSoqlIdentifierByte(soql[end]) {
+				end++
+			}
+			name := soql[i+1 : end]
+			value, ok := values[name]
+			if !ok {
+				return "", fmt.Errorf("no value for :%s, pass -p %s=<value>", name, name)
+			}
+			var literal string
+			if list, parenthesized := isSoqlListContext(soql[:i]); list {
+				literal, err = soqlListLiteral(name, value, types[name])
+				if !parenthesized {
+					literal = "(" + literal + ")"
+				}
+			} else {
+				literal, err = soqlLiteral(name, value, types[name])
+			}
+			if err != nil {
+				return
+			}
+			out.WriteString(literal)
+			i = end - 1
+			continue
+		}
+		out.WriteByte(c)
+	}
+	return out.String(), nil
+}
+
+// isSoqlBinding reports whether the colon at i starts a binding, rather than
+// being part of a date literal such as LAST_N_DAYS:30.
+func isSoqlBinding(soql string, i int) bool {
+	if i+1 >= len(soql) || !(unicode.IsLetter(rune(soql[i+1])) || soql[i+1] == '_') {
+		return false
+	}
+	return i == 0 || !isSoqlIdentifierByte(soql[i-1])
+}
+
+func isSoqlIdentifierByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// isSoqlListContext reports whether a binding after before takes a list, and
+// whether it is already in parentheses, as in IN (:ids).
+func isSoqlListContext(before string) (list, parenthesized bool) {
+	before = strings.TrimRightFunc(before, unicode.IsSpace)
+	if strings.HasSuffix(before, "(") {
+		before, parenthesized = strings.TrimSuffix(before, "("), true
+	}
+	words := strings.Fields(before)
+	if len(words) == 0 {
+		return false, false
+	}
+	last := strings.ToUpper(words[len(words)-1])
+	list = last == "IN" || last == "INCLUDES" || last == "EXCLUDES"
+	return list, list && parenthesized
+}
+
+func soqlListLiteral(name, value, kind string) (string, error) {
+	var literals []string
+	for _, item := range strings.Split(value, ",") {
+		literal, err := soqlLiteral(name, strings.TrimSpace(item), kind)
+		if err != nil {
+			return "", err
+		}
+		literals = append(literals, literal)
+	}
+	return strings.Join(literals, ", "), nil
+}
+
+func soqlLiteral(name, value, kind string) (string, error) {
+	if kind == "" {
+		switch {
+		case isSoqlDate(value):
+			kind = "date"
+		case isSoqlDateTime(value):
+			kind = "datetime"
+		default:
+			kind = "string"
+		}
+	}
+	switch kind {
+	case "date":
+		if !isSoqlDate(value) {
+			return "", fmt.Errorf("parameter %s is not a date, such as 2026-01-01: %s", name, value)
+		}
+	case "datetime":
+		if !isSoqlDateTime(value) {
+			return "", fmt.Errorf("parameter %s is not a datetime, such as 2026-01-01T09:00:00Z: %s", name, value)
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("parameter %s is not a number: %s", name, value)
+		}
+	case "boolean":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("parameter %s is not true or false: %s", name, value)
+		}
+		value = strconv.FormatBool(b)
+	default:
+		value = "'" + soqlLiteralEscaper.Replace(value) + "'"
+	}
+	return value, nil
+}
+
+func isSoqlDate(value string) bool {
+	_, err := time.Parse("2006-01-02", value)
+	return err == nil
+}
+
+// isSoqlDateTime reports whether a value is a datetime the way SOQL writes
+// them, with Z or an offset such as +01:00.
+func isSoqlDateTime(value string) bool {
+	_, err := time.Parse(time.RFC3339, value)
+	return err == nil && strings.Contains(value, "T")
+}
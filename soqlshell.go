@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/joist-engineering/force/salesforce"
+	"github.com/joist-engineering/force/util"
+	"github.com/peterh/liner"
+)
+
+var cmdSoql = &Command{
+	Run:   runSoql,
+	Usage: "soql [-format <format>] [-tooling]",
+	Short: "Run SOQL statements interactively",
+	Long: `
+Run SOQL statements interactively
+
+Statements can span lines, and run when a line ends with a semicolon, or
+at a blank line.  Tab completes the names of sObjects, fields and
+relationships, which are described once per org and cached.  History is kept
+between sessions.
+
+Options:
+  -format <format>   console (the default), csv, tsv, json, json-pretty,
+                     ndjson, markdown or html
+  -tooling, -t       Query the Tooling API
+
+Commands:
+  :format <format>   Change the output format
+  :tooling on|off    Query the Tooling API, or not
+  :all on|off        Include deleted and archived records, or not
+  :refresh           Describe the org's sObjects again, after changing them
+  :help              List the commands
+  :quit              Leave, as does Ctrl-D
+
+Examples:
+
+  force soql
+
+  force soql -format csv
+`,
+}
+
+var (
+	soqlFormat  string
+	soqlTooling bool
+)
+
+func init() {
+	cmdSoql.Flag.StringVar(&soqlFormat, "format", "console", "output format")
+	cmdSoql.Flag.BoolVar(&soqlTooling, "tooling", false, "query the Tooling API")
+	cmdSoql.Flag.BoolVar(&soqlTooling, "t", false, "query the Tooling API")
+	soqlShellCommands = []soqlShellCommand{
+		{":format", "<format>", "change the output format", (*soqlShell).setFormat},
+		{":tooling", "on|off", "query the Tooling API, or not", (*soqlShell).setTooling},
+		{":all", "on|off", "include deleted and archived records, or not", (*soqlShell).setAll},
+		{":refresh", "", "describe the org's sObjects again", (*soqlShell).refresh},
+		{":help", "", "list the commands", (*soqlShell).help},
+		{":quit", "", "leave", (*soqlShell).exit},
+		{":exit", "", "leave", (*soqlShell).exit},
+	}
+}
+
+// soqlShellCommand is a command of the shell, such as :format csv.
+type soqlShellCommand struct {
+	name  string
+	args  string
+	short string
+	run   func(s *soqlShell, args []string) error
+}
+
+var soqlShellCommands []soqlShellCommand
+
+const (
+	soqlPrompt             = "soql> "
+	soqlContinuationPrompt = "  ... "
+)
+
+// soqlShell runs the statements and commands it is given a line at a time.
+type soqlShell struct {
+	force     *salesforce.Force
+	completer *soqlCompleter
+	out       io.Writer
+	format    string
+	tooling   bool
+	all       bool
+	// lines are the lines entered so far of a statement.
+	lines []string
+	done  bool
+}
+
+func runSoql(cmd *Command, args []string) {
+	if !StringSliceContains(recordFormats, soqlFormat) {
+		util.ErrorAndExit(fmt.Sprintf("Format %s not supported, use one of %s", soqlFormat, strings.Join(recordFormats, ", ")))
+	}
+	force, _ := ActiveForce()
+	shell := &soqlShell{
+		force:     force,
+		completer: &soqlCompleter{describes: newDescribeCache(force)},
+		out:       os.Stdout,
+		format:    soqlFormat,
+		tooling:   soqlTooling,
+	}
+
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetWordCompleter(shell.completer.Complete)
+	if history, err := util.Config.Load("soql", "history"); err == nil {
+		line.ReadHistory(strings.NewReader(history))
+	}
+
+	for !shell.done {
+		input, err := line.Prompt(shell.Prompt())
+		switch {
+		case err == liner.ErrPromptAborted:
+			shell.Reset()
+			continue
+		case err == io.EOF:
+			fmt.Println()
+			shell.done = true
+			input = ""
+		case err != nil:
+			exitWithError(err)
+		}
+		if entry := shell.Input(input); entry != "" {
+			line.AppendHistory(entry)
+		}
+	}
+
+	var history strings.Builder
+	line.WriteHistory(&history)
+	util.Config.Save("soql", "history", history.String())
+}
+
+// Prompt returns the prompt for the next line.
+func (s *soqlShell) Prompt() string {
+	if len(s.lines) > 0 {
+		return soqlContinuationPrompt
+	}
+	return soqlPrompt
+}
+
+// Reset drops what has been entered of a statement.
+func (s *soqlShell) Reset() {
+	s.lines = nil
+	s.completer.statement = ""
+}
+
+// Input takes a line.  It runs a command, or a statement once the line ends
+// it, and returns what it ran, to be added to the history.
+func (s *soqlShell) Input(line string) (entry string) {
+	trimmed := strings.TrimSpace(line)
+	if len(s.lines) == 0 && strings.HasPrefix(trimmed, ":") {
+		if err := s.command(strings.Fields(trimmed)); err != nil {
+			printError(err)
+		}
+		return trimmed
+	}
+	if trimmed != "" {
+		s.lines = append(s.lines, trimmed)
+	}
+	if len(s.lines) == 0 || (trimmed != "" && !strings.HasSuffix(trimmed, ";") && !s.done) {
+		s.completer.statement = strings.Join(s.lines, "\n")
+		return ""
+	}
+	soql := strings.TrimSpace(strings.TrimRight(strings.Join(s.lines, " "), "; "))
+	entry = strings.Join(s.lines, " ")
+	s.Reset()
+	if soql == "" {
+		return
+	}
+	if err := s.query(soql); err != nil {
+		printError(err)
+	}
+	return
+}
+
+func (s *soqlShell) query(soql string) error {
+	out, err := newRecordWriter(s.out, s.format, parseSoqlFields(soql), false)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return writeQuery(queryIterator(ctx, s.force, soql, s.tooling, s.all), out)
+}
+
+func (s *soqlShell) command(words []string) error {
+	for _, command := range soqlShellCommands {
+		if command.name == words[0] {
+			return command.run(s, words[1:])
+		}
+	}
+	return fmt.Errorf("unknown command %s, see :help", words[0])
+}
+
+func (s *soqlShell) setFormat(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(s.out, "format is %s\n", s.format)
+		return nil
+	}
+	if !StringSliceContains(recordFormats, args[0]) {
+		return fmt.Errorf("Format %s not supported, use one of %s", args[0], strings.Join(recordFormats, ", "))
+	}
+	s.format = args[0]
+	return nil
+}
+
+func (s *soqlShell) setTooling(args []string) (err error) {
+	if s.tooling, err = onOff(":tooling", s.tooling, args, s.out); err == nil && s.tooling {
+		s.all = false
+	}
+	return
+}
+
+func (s *soqlShell) setAll(args []string) (err error) {
+	if s.all, err = onOff(":all", s.all, args, s.out); err == nil && s.all {
+		s.tooling = false
+	}
+	return
+}
+
+// onOff parses the argument of a command that turns a setting on or off, or
+// without one, prints whether it is.
+func onOff(name string, value bool, args []string, out io.Writer) (bool, error) {
+	if len(args) == 0 {
+		state := "off"
+		if value {
+			state = "on"
+		}
+		fmt.Fprintf(out, "%s is %s\n", name, state)
+		return value, nil
+	}
+	switch strings.ToLower(args[0]) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return value, fmt.Errorf("%s must be on or off", name)
+}
+
+func (s *soqlShell) refresh(args []string) error {
+	s.completer.describes.Refresh()
+	return nil
+}
+
+func (s *soqlShell) help(args []string) error {
+	for _, command := range soqlShellCommands {
+		fmt.Fprintf(s.out, "  %-18s %s\n", strings.TrimSpace(command.name+" "+command.args), command.short)
+	}
+	return nil
+}
+
+func (s *soqlShell) exit(args []string) error {
+	s.done = true
+	return nil
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bmizerany/assert"
+	"github.com/joist-engineering/force/salesforce"
+)
+
+// fakeDescriber describes a few sObjects, counting the calls made to it.
+type fakeDescriber struct {
+	calls int
+}
+
+var fakeDescribes = map[string]string{
+	"Account": `{"name":"Account","fields":[
+		{"name":"Id","type":"id"},
+		{"name":"Name","type":"string"},
+		{"name":"OwnerId","type":"reference","relationshipName":"Owner","referenceTo":["User"]},
+		{"name":"IsActive__c","type":"boolean"},
+		{"name":"NumberOfEmployees","type":"int"},
+		{"name":"AnnualRevenue","type":"currency"},
+		{"name":"Founded__c","type":"date"},
+		{"name":"LastReviewed__c","type":"datetime"},
+		{"name":"Regions__c","type":"multipicklist"}],
+		"childRelationships":[{"childSObject":"Contact","relationshipName":"Contacts","field":"AccountId"}]}`,
+	"Contact": `{"name":"Contact","fields":[
+		{"name":"Id","type":"id"},
+		{"name":"LastName","type":"string"},
+		{"name":"AccountId","type":"reference","relationshipName":"Account","referenceTo":["Account"]}]}`,
+	"User": `{"name":"User","fields":[
+		{"name":"Id","type":"id"},
+		{"name":"Name","type":"string"},
+		{"name":"ManagerId","type":"reference","relationshipName":"Manager","referenceTo":["User"]}]}`,
+}
+
+func (f *fakeDescriber) ListSobjects() (sobjects []salesforce.ForceSobject, err error) {
+	f.calls++
+	for _, name := range []string{"Account", "Contact", "User"} {
+		sobjects = append(sobjects, salesforce.ForceSobject{"name": name})
+	}
+	return
+}
+
+func (f *fakeDescriber) GetSobject(name string) (sobject salesforce.ForceSobject, err error) {
+	f.calls++
+	for key, describe := range fakeDescribes {
+		if strings.EqualFold(key, name) {
+			err = json.Unmarshal([]byte(describe), &sobject)
+			return
+		}
+	}
+	return nil, &salesforce.APIError{StatusCode: 404, ErrorCode: "NOT_FOUND"}
+}
+
+func newFakeDescribeCache(force describer) *describeCache {
+	return &describeCache{force: force, name: "describe/00D000000000001", describes: map[string]sobjectDescribe{}}
+}
+
+func TestDescribeCache(t *testing.T) {
+	defer withTempHome(t)()
+	force := &fakeDescriber{}
+
+	cache := newFakeDescribeCache(force)
+	names, err := cache.SobjectNames()
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{"Account", "Contact", "User"}, names)
+	describe, err := cache.Describe("account")
+	assert.Equal(t, nil, err)
+	field, ok := describe.Relationship("owner")
+	assert.T(t, ok)
+	assert.Equal(t, "OwnerId", field.Name)
+	cache.Describe("Account")
+	assert.Equal(t, 2, force.calls)
+
+	// Another session with the same org uses what was cached.
+	cache = newFakeDescribeCache(force)
+	cache.SobjectNames()
+	cache.Describe("Account")
+	assert.Equal(t, 2, force.calls)
+
+	cache.Refresh()
+	cache.Describe("Account")
+	assert.Equal(t, 3, force.calls)
+}
+
+func TestOrgKey(t *testing.T) {
+	assert.Equal(t, "00D000000000001", orgKey(salesforce.ForceCredentials{Id: "https://login.salesforce.com/id/00D000000000001/005000000000001"}))
+	assert.Equal(t, "na1.salesforce.com", orgKey(salesforce.ForceCredentials{InstanceUrl: "https://na1.salesforce.com"}))
+}
+
+func TestSoqlCompletion(t *testing.T) {
+	defer withTempHome(t)()
+	completer := &soqlCompleter{describes: newFakeDescribeCache(&fakeDescriber{})}
+
+	complete := func(statement, line string) (string, []string, string) {
+		completer.statement = statement
+		pos := strings.Index(line, "|")
+		line = strings.Replace(line, "|", "", 1)
+		return completer.Complete(line, len([]rune(line[:pos])))
+	}
+
+	head, completions, tail := complete("", "SELECT Id FROM Acc|")
+	assert.Equal(t, "SELECT Id FROM ", head)
+	assert.Equal(t, []string{"Account"}, completions)
+	assert.Equal(t, "", tail)
+
+	head, completions, tail = complete("", "SELECT Na| FROM Account")
+	assert.Equal(t, "SELECT ", head)
+	assert.Equal(t, []string{"Name"}, completions)
+	assert.Equal(t, " FROM Account", tail)
+
+	_, completions, _ = complete("", "SELECT Owner| FROM Account")
+	assert.Equal(t, []string{"Owner", "OwnerId"}, completions)
+
+	_, completions, _ = complete("", "SELECT Owner.Manager.Na| FROM Account")
+	assert.Equal(t, []string{"Owner.Manager.Name"}, completions)
+
+	_, completions, _ = complete("", "SELECT Id, (SELECT La| FROM Contacts) FROM Account")
+	assert.Equal(t, []string{"LAST", "LastName"}, completions)
+
+	_, completions, _ = complete("", "SELECT Id, (SELECT Id FROM Con|) FROM Account")
+	assert.Equal(t, []string{"Contact", "Contacts"}, completions)
+
+	_, completions, _ = complete("SELECT Id\nFROM Contact", "WHERE Acc|")
+	assert.Equal(t, []string{"Account", "AccountId"}, completions)
+
+	_, completions, _ = complete("", "select Id from Account ord|")
+	assert.Equal(t, []string{"order"}, completions)
+
+	head, completions, _ = complete("", ":fo|")
+	assert.Equal(t, "", head)
+	assert.Equal(t, []string{":format"}, completions)
+
+	head, completions, _ = complete("", ":format j|")
+	assert.Equal(t, ":format ", head)
+	assert.Equal(t, []string{"json", "json-pretty"}, completions)
+}
+
+func TestSoqlShell(t *testing.T) {
+	force, stop := serveOrg(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"totalSize":1,"done":true,"records":[{"attributes":{"type":"Account"},"Id":"001A","Name":"Acme"}]}`)
+	}))
+	defer stop()
+	var out strings.Builder
+	shell := &soqlShell{
+		force:     force,
+		completer: &soqlCompleter{},
+		out:       &out,
+		format:    "console",
+	}
+
+	assert.Equal(t, ":format csv", shell.Input(" :format csv"))
+	assert.Equal(t, "csv", shell.format)
+	assert.Equal(t, "", shell.Input("SELECT Id, Name"))
+	assert.Equal(t, soqlContinuationPrompt, shell.Prompt())
+	assert.Equal(t, "SELECT Id, Name", shell.completer.statement)
+	assert.Equal(t, "SELECT Id, Name FROM Account;", shell.Input("FROM Account;"))
+	assert.Equal(t, soqlPrompt, shell.Prompt())
+	assert.Equal(t, "Id,Name\n001A,Acme\n", out.String())
+
+	out.Reset()
+	shell.Input("SELECT Name FROM Account")
+	assert.Equal(t, "", out.String())
+	shell.Input("")
+	assert.Equal(t, "Name\nAcme\n", out.String())
+
+	shell.Input(":all on")
+	assert.T(t, shell.all)
+	shell.Input(":tooling on")
+	assert.T(t, shell.tooling && !shell.all)
+	shell.Input(":quit")
+	assert.T(t, shell.done)
+}
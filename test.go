@@ -57,7 +57,7 @@ func runTests(cmd *Command, args []string) {
 	output, err := RunTests(force.Partner, args, *namespaceTestFlag)
 	success := false
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	if verboselogging {
 		fmt.Println(output.Log)
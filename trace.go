@@ -54,7 +54,7 @@ func runQueryTrace() {
 	force, _ := ActiveForce()
 	result, err := force.QueryTraceFlags()
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	DisplayForceRecordsf(result.Records, "json-pretty")
 }
@@ -63,7 +63,7 @@ func runStartTrace(userId ...string) {
 	force, _ := ActiveForce()
 	_, err, _ := force.StartTrace(userId...)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Printf("Tracing Enabled\n")
 }
@@ -72,7 +72,7 @@ func runDeleteTrace(id string) {
 	force, _ := ActiveForce()
 	err := force.DeleteToolingRecord("TraceFlag", id)
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	}
 	fmt.Printf("Trace Flag deleted\n")
 }
@@ -29,7 +29,7 @@ func runUpdate(cmd *Command, args []string) {
 	if len(args) == 1 {
 		err := d.FullUpdate(args[0])
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		} else {
 			fmt.Printf("updated to %s\n", args[0])
 		}
@@ -39,7 +39,7 @@ func runUpdate(cmd *Command, args []string) {
 		}
 		to, err := d.Update()
 		if err != nil {
-			util.ErrorAndExit(err.Error())
+			exitWithError(err)
 		} else {
 			fmt.Printf("updated to %s\n", to)
 		}
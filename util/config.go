@@ -4,4 +4,4 @@ import "github.com/devangel/config"
 
 //"fmt"
 
-var Config = config.NewConfig("force")
+var Config = NewConfigStore(config.NewConfig("force"))
@@ -0,0 +1,130 @@
+package util
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// encryptedPrefix marks a value encrypted by the encrypted store.
+const encryptedPrefix = "encrypted:v1:"
+
+const (
+	saltSize         = 16
+	keyIterations    = 100000
+	encryptionKeyLen = 32
+)
+
+// encryptedStore keeps values in files, encrypted with AES-256-GCM using a
+// key derived from a passphrase.
+type encryptedStore struct {
+	files      Store
+	passphrase string
+}
+
+func (e *encryptedStore) List(name string) (keys []string, err error) {
+	return e.files.List(name)
+}
+
+func (e *encryptedStore) Save(name, key, value string) (err error) {
+	sealed, err := encrypt(e.passphrase, []byte(value))
+	if err != nil {
+		return
+	}
+	return e.files.Save(name, key, sealed)
+}
+
+// Load decrypts a value.  Values that haven't been encrypted yet are returned
+// as they are.
+func (e *encryptedStore) Load(name, key string) (value string, err error) {
+	value, err = e.files.Load(name, key)
+	if err != nil || !strings.HasPrefix(value, encryptedPrefix) {
+		return
+	}
+	plain, err := decrypt(e.passphrase, value)
+	if err != nil {
+		return "", fmt.Errorf("unable to decrypt %s, is %s right? (%s)", key, PassphraseVariable, err.Error())
+	}
+	return string(plain), nil
+}
+
+func (e *encryptedStore) Delete(name, key string) (err error) {
+	return e.files.Delete(name, key)
+}
+
+func encrypt(passphrase string, plain []byte) (sealed string, err error) {
+	salt := make([]byte, saltSize)
+	if _, err = rand.Read(salt); err != nil {
+		return
+	}
+	aead, err := newCipher(passphrase, salt)
+	if err != nil {
+		return
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return
+	}
+	data := append(salt, nonce...)
+	data = aead.Seal(data, nonce, plain, nil)
+	return encryptedPrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+func decrypt(passphrase string, sealed string) (plain []byte, err error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, encryptedPrefix))
+	if err != nil {
+		return
+	}
+	if len(data) < saltSize {
+		return nil, errors.New("value is too short")
+	}
+	aead, err := newCipher(passphrase, data[:saltSize])
+	if err != nil {
+		return
+	}
+	data = data[saltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, errors.New("value is too short")
+	}
+	return aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+}
+
+func newCipher(passphrase string, salt []byte) (aead cipher.AEAD, err error) {
+	block, err := aes.NewCipher(pbkdf2([]byte(passphrase), salt, keyIterations, encryptionKeyLen))
+	if err != nil {
+		return
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2 derives a key from a passphrase with PBKDF2-HMAC-SHA256 (RFC 8018).
+func pbkdf2(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	var key []byte
+	for block := uint32(1); len(key) < keyLen; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		var counter [4]byte
+		binary.BigEndian.PutUint32(counter[:], block)
+		prf.Write(counter[:])
+		u := prf.Sum(nil)
+		t := append([]byte(nil), u...)
+		for i := 1; i < iterations; i++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for j := range t {
+				t[j] ^= u[j]
+			}
+		}
+		key = append(key, t...)
+	}
+	return key[:keyLen]
+}
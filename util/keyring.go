@@ -0,0 +1,98 @@
+package util
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Keyring holds secrets in an operating system keyring.
+type Keyring interface {
+	Set(name, key, secret string) (err error)
+	Get(name, key string) (secret string, err error)
+	Delete(name, key string) (err error)
+}
+
+// keyringMarker is saved in place of a value that is kept in the keyring.
+const keyringMarker = "(stored in the keyring)"
+
+// keyringStore keeps values in a keyring, with a marker file for each so that
+// they can still be listed.
+type keyringStore struct {
+	files   Store
+	keyring Keyring
+}
+
+func (k *keyringStore) List(name string) (keys []string, err error) {
+	return k.files.List(name)
+}
+
+func (k *keyringStore) Save(name, key, value string) (err error) {
+	if err = k.keyring.Set(name, key, value); err != nil {
+		return
+	}
+	return k.files.Save(name, key, keyringMarker)
+}
+
+// Load reads a value from the keyring.  Values that haven't been moved into
+// the keyring yet are read from their file.
+func (k *keyringStore) Load(name, key string) (value string, err error) {
+	value, err = k.files.Load(name, key)
+	if err != nil || value != keyringMarker {
+		return
+	}
+	return k.keyring.Get(name, key)
+}
+
+func (k *keyringStore) Delete(name, key string) (err error) {
+	if value, _ := k.files.Load(name, key); value == keyringMarker {
+		if err = k.keyring.Delete(name, key); err != nil {
+			return
+		}
+	}
+	return k.files.Delete(name, key)
+}
+
+// SecretServiceKeyring uses the freedesktop.org Secret Service, as provided by
+// GNOME Keyring and KWallet, through the secret-tool command from libsecret.
+type SecretServiceKeyring struct{}
+
+func (SecretServiceKeyring) Set(name, key, secret string) (err error) {
+	cmd := exec.Command("secret-tool", "store", "--label", fmt.Sprintf("force %s %s", name, key), "service", "force", "name", name, "key", key)
+	cmd.Stdin = strings.NewReader(secret)
+	return runSecretTool(cmd)
+}
+
+func (SecretServiceKeyring) Get(name, key string) (secret string, err error) {
+	cmd := exec.Command("secret-tool", "lookup", "service", "force", "name", name, "key", key)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err = runSecretTool(cmd); err != nil {
+		return
+	}
+	if out.Len() == 0 {
+		err = fmt.Errorf("%s is not in the keyring", key)
+		return
+	}
+	secret = out.String()
+	return
+}
+
+func (SecretServiceKeyring) Delete(name, key string) (err error) {
+	return runSecretTool(exec.Command("secret-tool", "clear", "service", "force", "name", name, "key", key))
+}
+
+func runSecretTool(cmd *exec.Cmd) (err error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err = cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok && stderr.Len() > 0 {
+			err = errors.New("secret-tool: " + strings.TrimSpace(stderr.String()))
+		} else if _, lookErr := exec.LookPath("secret-tool"); lookErr != nil {
+			err = errors.New("the keyring needs secret-tool, from libsecret, to be installed")
+		}
+	}
+	return
+}
@@ -0,0 +1,153 @@
+package util
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Store keeps string values by key, grouped under a name.
+type Store interface {
+	List(name string) (keys []string, err error)
+	Save(name, key, value string) (err error)
+	Load(name, key string) (value string, err error)
+	Delete(name, key string) (err error)
+}
+
+// The credential stores that logins can be kept in.
+const (
+	PlaintextStore = "plaintext"
+	EncryptedStore = "encrypted"
+	KeyringStore   = "keyring"
+)
+
+// CredentialStores lists the names of the credential stores.
+var CredentialStores = []string{PlaintextStore, EncryptedStore, KeyringStore}
+
+// PassphraseVariable is the environment variable holding the passphrase for
+// the encrypted credential store.
+const PassphraseVariable = "FORCE_CONFIG_PASSPHRASE"
+
+const (
+	credentialsName    = "accounts"
+	settingsName       = "settings"
+	credentialStoreKey = "credentialstore"
+)
+
+// ConfigStore keeps logins in the chosen credential store, and everything
+// else in plain files.  Every credential store indexes its logins with a file
+// per login, so listing them never needs a passphrase or the keyring.
+type ConfigStore struct {
+	Files   Store
+	Keyring Keyring
+}
+
+// NewConfigStore creates a ConfigStore over files, using the Secret Service
+// for the keyring.
+func NewConfigStore(files Store) *ConfigStore {
+	return &ConfigStore{Files: files, Keyring: SecretServiceKeyring{}}
+}
+
+func (c *ConfigStore) List(name string) (keys []string, err error) {
+	return c.Files.List(name)
+}
+
+func (c *ConfigStore) Save(name, key, value string) (err error) {
+	store, err := c.storeFor(name)
+	if err != nil {
+		return
+	}
+	return store.Save(name, key, value)
+}
+
+func (c *ConfigStore) Load(name, key string) (value string, err error) {
+	store, err := c.storeFor(name)
+	if err != nil {
+		return
+	}
+	return store.Load(name, key)
+}
+
+func (c *ConfigStore) Delete(name, key string) (err error) {
+	store, err := c.storeFor(name)
+	if err != nil {
+		return
+	}
+	return store.Delete(name, key)
+}
+
+func (c *ConfigStore) storeFor(name string) (Store, error) {
+	if name == credentialsName {
+		return c.CredentialStore(c.CredentialStoreName())
+	}
+	return c.Files, nil
+}
+
+// CredentialStoreName returns the name of the credential store logins are
+// kept in, which is the plaintext store unless they have been migrated.
+func (c *ConfigStore) CredentialStoreName() string {
+	name, err := c.Files.Load(settingsName, credentialStoreKey)
+	if err != nil || strings.TrimSpace(name) == "" {
+		return PlaintextStore
+	}
+	return strings.TrimSpace(name)
+}
+
+// CredentialStore returns the credential store with the given name.
+func (c *ConfigStore) CredentialStore(name string) (Store, error) {
+	switch name {
+	case PlaintextStore:
+		return c.Files, nil
+	case EncryptedStore:
+		passphrase := os.Getenv(PassphraseVariable)
+		if passphrase == "" {
+			return nil, fmt.Errorf("logins are encrypted, please set %s", PassphraseVariable)
+		}
+		return &encryptedStore{files: c.Files, passphrase: passphrase}, nil
+	case KeyringStore:
+		return &keyringStore{files: c.Files, keyring: c.Keyring}, nil
+	}
+	return nil, fmt.Errorf("unknown credential store %q, must be one of %s", name, strings.Join(CredentialStores, ", "))
+}
+
+// MigrateCredentials moves every login into the named credential store, and
+// keeps them there from then on.  Nothing is moved unless every login can be
+// read first.
+func (c *ConfigStore) MigrateCredentials(to string) (migrated []string, err error) {
+	fromName := c.CredentialStoreName()
+	from, err := c.CredentialStore(fromName)
+	if err != nil {
+		return
+	}
+	target, err := c.CredentialStore(to)
+	if err != nil {
+		return
+	}
+
+	keys, _ := c.Files.List(credentialsName)
+	values := make(map[string]string)
+	for _, key := range keys {
+		if strings.HasPrefix(key, ".") {
+			continue
+		}
+		if values[key], err = from.Load(credentialsName, key); err != nil {
+			return nil, fmt.Errorf("unable to read %s: %s", key, err.Error())
+		}
+	}
+
+	for _, key := range keys {
+		value, ok := values[key]
+		if !ok {
+			continue
+		}
+		if err = target.Save(credentialsName, key, value); err != nil {
+			return
+		}
+		if fromName == KeyringStore && to != KeyringStore {
+			c.Keyring.Delete(credentialsName, key)
+		}
+		migrated = append(migrated, key)
+	}
+	err = c.Files.Save(settingsName, credentialStoreKey, to)
+	return
+}
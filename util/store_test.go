@@ -0,0 +1,191 @@
+package util_test
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/joist-engineering/force/util"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// memoryStore stands in for the config files.
+type memoryStore map[string]string
+
+func (m memoryStore) List(name string) (keys []string, err error) {
+	for path := range m {
+		if strings.HasPrefix(path, name+"/") {
+			keys = append(keys, strings.TrimPrefix(path, name+"/"))
+		}
+	}
+	sort.Strings(keys)
+	return
+}
+
+func (m memoryStore) Save(name, key, value string) (err error) {
+	m[name+"/"+key] = value
+	return
+}
+
+func (m memoryStore) Load(name, key string) (value string, err error) {
+	value, ok := m[name+"/"+key]
+	if !ok {
+		err = errors.New("not found")
+	}
+	return
+}
+
+func (m memoryStore) Delete(name, key string) (err error) {
+	delete(m, name+"/"+key)
+	return
+}
+
+// memoryKeyring stands in for the Secret Service.
+type memoryKeyring map[string]string
+
+func (m memoryKeyring) Set(name, key, secret string) (err error) {
+	m[name+"/"+key] = secret
+	return
+}
+
+func (m memoryKeyring) Get(name, key string) (secret string, err error) {
+	secret, ok := m[name+"/"+key]
+	if !ok {
+		err = errors.New("not in the keyring")
+	}
+	return
+}
+
+func (m memoryKeyring) Delete(name, key string) (err error) {
+	delete(m, name+"/"+key)
+	return
+}
+
+var _ = Describe("ConfigStore", func() {
+	const creds = `{"AccessToken":"00D!secret"}`
+
+	var (
+		files   memoryStore
+		keyring memoryKeyring
+		store   *util.ConfigStore
+	)
+
+	BeforeEach(func() {
+		files = memoryStore{}
+		keyring = memoryKeyring{}
+		store = &util.ConfigStore{Files: files, Keyring: keyring}
+		os.Setenv(util.PassphraseVariable, "correct horse battery staple")
+	})
+
+	AfterEach(func() {
+		os.Unsetenv(util.PassphraseVariable)
+	})
+
+	It("should keep logins in plain files by default", func() {
+		Expect(store.CredentialStoreName()).To(Equal(util.PlaintextStore))
+		Expect(store.Save("accounts", "user@example.com", creds)).To(Succeed())
+		Expect(files["accounts/user@example.com"]).To(Equal(creds))
+	})
+
+	It("should keep other settings in plain files", func() {
+		files["settings/credentialstore"] = util.EncryptedStore
+		Expect(store.Save("current", "account", "user@example.com")).To(Succeed())
+		Expect(files["current/account"]).To(Equal("user@example.com"))
+	})
+
+	Context("with the encrypted store", func() {
+		BeforeEach(func() {
+			files["settings/credentialstore"] = util.EncryptedStore
+		})
+
+		It("should encrypt logins", func() {
+			Expect(store.Save("accounts", "user@example.com", creds)).To(Succeed())
+			Expect(files["accounts/user@example.com"]).To(HavePrefix("encrypted:v1:"))
+			Expect(files["accounts/user@example.com"]).ToNot(ContainSubstring("secret"))
+			Expect(store.Load("accounts", "user@example.com")).To(Equal(creds))
+		})
+
+		It("should still list logins", func() {
+			Expect(store.Save("accounts", "user@example.com", creds)).To(Succeed())
+			Expect(store.List("accounts")).To(Equal([]string{"user@example.com"}))
+		})
+
+		It("should need the passphrase", func() {
+			os.Unsetenv(util.PassphraseVariable)
+			err := store.Save("accounts", "user@example.com", creds)
+			Expect(err).To(MatchError(ContainSubstring(util.PassphraseVariable)))
+		})
+
+		It("should fail to decrypt with the wrong passphrase", func() {
+			Expect(store.Save("accounts", "user@example.com", creds)).To(Succeed())
+			os.Setenv(util.PassphraseVariable, "wrong")
+			_, err := store.Load("accounts", "user@example.com")
+			Expect(err).To(MatchError(ContainSubstring("unable to decrypt user@example.com")))
+		})
+	})
+
+	Context("with the keyring store", func() {
+		BeforeEach(func() {
+			files["settings/credentialstore"] = util.KeyringStore
+		})
+
+		It("should keep logins in the keyring", func() {
+			Expect(store.Save("accounts", "user@example.com", creds)).To(Succeed())
+			Expect(keyring["accounts/user@example.com"]).To(Equal(creds))
+			Expect(files["accounts/user@example.com"]).ToNot(ContainSubstring("secret"))
+			Expect(store.Load("accounts", "user@example.com")).To(Equal(creds))
+			Expect(store.List("accounts")).To(Equal([]string{"user@example.com"}))
+		})
+
+		It("should remove logins from the keyring", func() {
+			Expect(store.Save("accounts", "user@example.com", creds)).To(Succeed())
+			Expect(store.Delete("accounts", "user@example.com")).To(Succeed())
+			Expect(keyring).To(BeEmpty())
+			Expect(files).ToNot(HaveKey("accounts/user@example.com"))
+		})
+	})
+
+	Describe("MigrateCredentials", func() {
+		BeforeEach(func() {
+			files["accounts/a@example.com"] = creds
+			files["accounts/b@example.com"] = creds
+		})
+
+		It("should move logins into the keyring", func() {
+			migrated, err := store.MigrateCredentials(util.KeyringStore)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(migrated).To(Equal([]string{"a@example.com", "b@example.com"}))
+			Expect(store.CredentialStoreName()).To(Equal(util.KeyringStore))
+			Expect(keyring).To(HaveLen(2))
+			Expect(store.Load("accounts", "b@example.com")).To(Equal(creds))
+		})
+
+		It("should move logins between the keyring and encrypted files", func() {
+			_, err := store.MigrateCredentials(util.KeyringStore)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = store.MigrateCredentials(util.EncryptedStore)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keyring).To(BeEmpty())
+			Expect(files["accounts/a@example.com"]).To(HavePrefix("encrypted:v1:"))
+			Expect(store.Load("accounts", "a@example.com")).To(Equal(creds))
+		})
+
+		It("should move nothing if a login can't be read", func() {
+			_, err := store.MigrateCredentials(util.EncryptedStore)
+			Expect(err).ToNot(HaveOccurred())
+			os.Setenv(util.PassphraseVariable, "wrong")
+			_, err = store.MigrateCredentials(util.PlaintextStore)
+			Expect(err).To(HaveOccurred())
+			Expect(store.CredentialStoreName()).To(Equal(util.EncryptedStore))
+			Expect(files["accounts/a@example.com"]).To(HavePrefix("encrypted:v1:"))
+		})
+
+		It("should reject unknown stores", func() {
+			_, err := store.MigrateCredentials("cloud")
+			Expect(err).To(MatchError(ContainSubstring("unknown credential store")))
+		})
+	})
+})
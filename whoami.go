@@ -1,9 +1,5 @@
 package main
 
-import (
-	"github.com/joist-engineering/force/util"
-)
-
 var cmdWhoami = &Command{
 	Run:   runWhoami,
 	Usage: "whoami",
@@ -21,7 +17,7 @@ func runWhoami(cmd *Command, args []string) {
 	force, _ := ActiveForce()
 	me, err := force.Whoami()
 	if err != nil {
-		util.ErrorAndExit(err.Error())
+		exitWithError(err)
 	} else if len(args) == 0 {
 		DisplayForceRecord(me)
 	}